@@ -80,6 +80,16 @@ fi
 egrep -q "^[#]?wsrep_sst_donor" "$CFG" || sed '/^\[mysqld\]/a wsrep_sst_donor=\n' ${CFG} 1<> ${CFG}
 egrep -q "^[#]?wsrep_node_incoming_address" "$CFG" || sed '/^\[mysqld\]/a wsrep_node_incoming_address=\n' ${CFG} 1<> ${CFG}
 egrep -q "^[#]?wsrep_provider_options" "$CFG" || sed '/^\[mysqld\]/a wsrep_provider_options="pc.weight=10"\n' ${CFG} 1<> ${CFG}
+# merge the auto-tuned provider options into the options of the image
+AUTO_PROVIDER_OPTIONS=/etc/my.cnf.d/wsrep-provider-options
+if [ -s "$AUTO_PROVIDER_OPTIONS" ]; then
+    PROVIDER_OPTIONS=$(egrep "^wsrep_provider_options" "$CFG" | tail -1 | cut -d'=' -f2- | tr -d '" ')
+    PROVIDER_OPTIONS=$(printf '%s;%s' "$PROVIDER_OPTIONS" "$(cat $AUTO_PROVIDER_OPTIONS)" | tr ';' '\n' \
+        | awk -F'=' 'NF { if (!($1 in opts)) keys[++n] = $1; opts[$1] = $0 } END { for (i = 1; i <= n; i++) printf "%s%s", (i > 1 ? ";" : ""), opts[keys[i]] }')
+    # the line can get shorter, so the config is truncated on write
+    NODE_CFG=$(sed -r "s|^wsrep_provider_options.*$|wsrep_provider_options=\"${PROVIDER_OPTIONS}\"|" ${CFG})
+    printf '%s\n' "$NODE_CFG" > ${CFG}
+fi
 sed -r "s|^[#]?server_id=.*$|server_id=1${SERVER_ID}|" ${CFG} 1<> ${CFG}
 sed -r "s|^[#]?coredumper$|coredumper|" ${CFG} 1<> ${CFG}
 sed -r "s|^[#]?wsrep_node_address=.*$|wsrep_node_address=${NODE_IP}|" ${CFG} 1<> ${CFG}
@@ -480,6 +480,7 @@ if [ "$1" = 'mysqld' -a -z "$wantHelp" ]; then
 			)"
 			wsrep_start_position_opt="--wsrep_start_position=$start_pos"
 			seqno=$(echo "$start_pos" | awk -F':' '{print $NF}' || :)
+			uuid=$(echo "$start_pos" | awk -F':' '{print $1}' || :)
 		else
 			# The server prints "..skipping position recovery.." if started without wsrep.
 			if grep 'skipping position recovery' "$wsrep_verbose_logfile"; then
@@ -503,7 +504,10 @@ if [ "$1" = 'mysqld' -a -z "$wantHelp" ]; then
 			set -o xtrace
 			echo "Recovery is in progress, please wait...."
 			sed -i 's/wsrep_cluster_address=.*/wsrep_cluster_address=gcomm:\/\//g' /etc/mysql/node.cnf
-			rm -f /tmp/recovery-case
+			rm -f /tmp/recovery-case /tmp/recovery-state.json
+			if [ -n "$recovery_agent_pid" ]; then
+				kill "$recovery_agent_pid" || :
+			fi
 			if [ -s "$grastate_loc" ]; then
 				sed -i 's/safe_to_bootstrap: 0/safe_to_bootstrap: 1/g' "$grastate_loc"
 			fi
@@ -515,10 +519,22 @@ if [ "$1" = 'mysqld' -a -z "$wantHelp" ]; then
 		if [[ -z "$is_primary_exists" && -f "$grastate_loc" && $safe_to_bootstrap != 1 ]] || [[ -z "$is_primary_exists" && -f "${DATADIR}/gvwstate.dat" ]]; then
 			trap "{ node_recovery \"\$@\" ; }" USR1
 			touch /tmp/recovery-case
-			if [[ -z ${seqno} ]]; then
+			# seqno is written to the JSON state as a number
+			if ! [[ ${seqno} =~ ^-?[0-9]+$ ]]; then
 				seqno="-1"
 			fi
 
+			# the operator reads the recovery state through the recovery agent
+			# and asks the agent to start the bootstrap of the chosen node
+			cat >/tmp/recovery-state.json <<-EOF
+				{"node":"$NODE_NAME","uuid":"${uuid}","seqno":${seqno},"safeToBootstrap":$([ "$safe_to_bootstrap" = 1 ] && echo true || echo false)}
+			EOF
+			recovery_agent_pid=""
+			if [ -x /var/lib/mysql/recovery-agent ]; then
+				/var/lib/mysql/recovery-agent -state=/tmp/recovery-state.json &
+				recovery_agent_pid=$!
+			fi
+
 			set +o xtrace
 			sleep 3
 
@@ -534,7 +550,10 @@ if [ "$1" = 'mysqld' -a -z "$wantHelp" ]; then
 			for (( ; ; )) do
 				is_primary_exists=$(get_primary)
 				if [ -n "$is_primary_exists" ]; then
-					rm -f /tmp/recovery-case
+					rm -f /tmp/recovery-case /tmp/recovery-state.json
+					if [ -n "$recovery_agent_pid" ]; then
+						kill "$recovery_agent_pid" || :
+					fi
 					exit 0
 				fi
 			done
@@ -0,0 +1,81 @@
+// A small HTTP server started by the pxc entrypoint while the node is waiting
+// for the full cluster crash recovery. It publishes the recovered position of
+// the node and lets the operator start the bootstrap without exec'ing into pods.
+// It's served over TLS with the internal certificate of the cluster if it's mounted.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"syscall"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/recovery"
+)
+
+var (
+	listen    = flag.String("listen", fmt.Sprintf(":%d", recovery.AgentPort), "Address to listen on.")
+	stateFile = flag.String("state", "/tmp/recovery-state.json", "File with the recovery state written by the entrypoint.")
+	certFile  = flag.String("cert", "/etc/mysql/ssl-internal/tls.crt", "TLS certificate, the agent is served over plain HTTP if it doesn't exist.")
+	keyFile   = flag.String("key", "/etc/mysql/ssl-internal/tls.key", "TLS key.")
+)
+
+func main() {
+	flag.Parse()
+
+	http.HandleFunc(recovery.StatePath, handleState)
+	http.HandleFunc(recovery.BootstrapPath, handleBootstrap)
+
+	if _, err := os.Stat(*certFile); err == nil {
+		log.Printf("recovery agent is listening on %s with TLS", *listen)
+		log.Fatal(http.ListenAndServeTLS(*listen, *certFile, *keyFile, nil))
+	}
+
+	log.Printf("recovery agent is listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
+
+func handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := ioutil.ReadFile(*stateFile)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !recovery.Authorized(r, os.Getenv(recovery.TokenEnv)) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// the entrypoint is PID 1 and starts the bootstrap on USR1
+	err := syscall.Kill(1, syscall.SIGUSR1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("bootstrap was requested by %s", r.RemoteAddr)
+	w.WriteHeader(http.StatusAccepted)
+}
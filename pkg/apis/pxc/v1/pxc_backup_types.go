@@ -49,6 +49,13 @@ const (
 	BackupSucceeded                = "Succeeded"
 )
 
+// Reasons of the events emitted for the PerconaXtraDBClusterBackup objects
+const (
+	EventBackupStarted   = "BackupStarted"
+	EventBackupSucceeded = "BackupSucceeded"
+	EventBackupFailed    = "BackupFailed"
+)
+
 // OwnerRef returns OwnerReference to object
 func (cr *PerconaXtraDBClusterBackup) OwnerRef(scheme *runtime.Scheme) (metav1.OwnerReference, error) {
 	gvk, err := apiutil.GVKForObject(cr, scheme)
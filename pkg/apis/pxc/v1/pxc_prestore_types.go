@@ -63,6 +63,13 @@ const (
 	RestoreSucceeded                     = "Succeeded"
 )
 
+// Reasons of the events emitted for the PerconaXtraDBClusterRestore objects
+const (
+	EventRestoreStarted   = "RestoreStarted"
+	EventRestoreSucceeded = "RestoreSucceeded"
+	EventRestoreFailed    = "RestoreFailed"
+)
+
 func (cr *PerconaXtraDBClusterRestore) CheckNsetDefaults() error {
 	if cr.Spec.PXCCluster == "" {
 		return errors.New("pxcCluster can't be empty")
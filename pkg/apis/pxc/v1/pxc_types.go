@@ -3,7 +3,9 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-ini/ini"
 	"github.com/go-logr/logr"
@@ -12,8 +14,10 @@ import (
 
 	v "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -24,6 +28,7 @@ type PerconaXtraDBClusterSpec struct {
 	CRVersion                 string                               `json:"crVersion,omitempty"`
 	Pause                     bool                                 `json:"pause,omitempty"`
 	SecretsName               string                               `json:"secretsName,omitempty"`
+	SecretsProvider           *SecretsProviderSpec                 `json:"secretsProvider,omitempty"`
 	VaultSecretName           string                               `json:"vaultSecretName,omitempty"`
 	SSLSecretName             string                               `json:"sslSecretName,omitempty"`
 	SSLInternalSecretName     string                               `json:"sslInternalSecretName,omitempty"`
@@ -33,6 +38,7 @@ type PerconaXtraDBClusterSpec struct {
 	ProxySQL                  *PodSpec                             `json:"proxysql,omitempty"`
 	HAProxy                   *PodSpec                             `json:"haproxy,omitempty"`
 	PMM                       *PMMSpec                             `json:"pmm,omitempty"`
+	Metrics                   *MetricsSpec                         `json:"metrics,omitempty"`
 	LogCollector              *LogCollectorSpec                    `json:"logcollector,omitempty"`
 	Backup                    *PXCScheduledBackup                  `json:"backup,omitempty"`
 	UpdateStrategy            appsv1.StatefulSetUpdateStrategyType `json:"updateStrategy,omitempty"`
@@ -40,28 +46,330 @@ type PerconaXtraDBClusterSpec struct {
 	AllowUnsafeConfig         bool                                 `json:"allowUnsafeConfigurations,omitempty"`
 	InitImage                 string                               `json:"initImage,omitempty"`
 	EnableCRValidationWebhook *bool                                `json:"enableCRValidationWebhook,omitempty"`
+	MaintenanceWindows        []MaintenanceWindow                  `json:"maintenanceWindows,omitempty"`
 }
 
 type PXCSpec struct {
-	AutoRecovery *bool `json:"autoRecovery,omitempty"`
+	AutoRecovery       *bool                   `json:"autoRecovery,omitempty"`
+	AutoTune           *AutoTuneSpec           `json:"autoTune,omitempty"`
+	StorageAutoscaling *StorageAutoscalingSpec `json:"storageAutoscaling,omitempty"`
 	*PodSpec
 }
 
+// StorageAutoscalingSpec grows the PXC volumes when the datadir is filling up
+type StorageAutoscalingSpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// ThresholdPercent is the datadir usage the volumes are grown at, 80 by default
+	ThresholdPercent int `json:"thresholdPercent,omitempty"`
+	// GrowthPercent is the step of the growth, 20 by default
+	GrowthPercent int `json:"growthPercent,omitempty"`
+	// MaxSize is the size the volumes aren't grown beyond
+	MaxSize string `json:"maxSize"`
+	// CheckInterval is the interval of the usage checks, 60 seconds by default
+	CheckInterval int64 `json:"checkIntervalSec,omitempty"`
+}
+
+func (s *StorageAutoscalingSpec) setDefaults() {
+	if s.ThresholdPercent == 0 {
+		s.ThresholdPercent = 80
+	}
+	if s.GrowthPercent == 0 {
+		s.GrowthPercent = 20
+	}
+	if s.CheckInterval <= 0 {
+		s.CheckInterval = 60
+	}
+}
+
+// validate checks the spec with the defaults applied
+func (s StorageAutoscalingSpec) validate(volume *VolumeSpec) error {
+	s.setDefaults()
+	if volume == nil || volume.PersistentVolumeClaim == nil {
+		return errors.New("volumes can be grown only with persistentVolumeClaim")
+	}
+	if s.ThresholdPercent < 1 || s.ThresholdPercent > 99 {
+		return errors.New("thresholdPercent should be between 1 and 99")
+	}
+	if s.GrowthPercent < 1 || s.GrowthPercent > 100 {
+		return errors.New("growthPercent should be between 1 and 100")
+	}
+	maxSize, err := resource.ParseQuantity(s.MaxSize)
+	if err != nil {
+		return errors.Wrap(err, "maxSize")
+	}
+	if size, ok := volume.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]; ok && maxSize.Cmp(size) < 0 {
+		return errors.Errorf("maxSize %s is less than the volume size %s", s.MaxSize, size.String())
+	}
+	return nil
+}
+
+type AutoTuneProfile string
+
+const (
+	AutoTuneProfileOLTP      AutoTuneProfile = "oltp"
+	AutoTuneProfileAnalytics AutoTuneProfile = "analytics"
+	AutoTuneProfileDev       AutoTuneProfile = "dev"
+)
+
+// AutoTuneSpec gives the mysqld auto-tuning the hints the resources don't provide
+type AutoTuneSpec struct {
+	// Profile is the workload profile: oltp (default), analytics or dev
+	Profile AutoTuneProfile `json:"profile,omitempty"`
+	// IOPS the data volume provides
+	IOPS int64 `json:"iops,omitempty"`
+	// StorageType is ssd or hdd. It's guessed from the storage class name if not set.
+	StorageType string `json:"storageType,omitempty"`
+}
+
 type TLSSpec struct {
 	SANs       []string                `json:"SANs,omitempty"`
 	IssuerConf *cmmeta.ObjectReference `json:"issuerConf,omitempty"`
 }
 
+// SecretsProviderSpec defines an external storage of the system users credentials.
+// If it isn't set, the credentials are taken from the secret defined by SecretsName
+type SecretsProviderSpec struct {
+	Vault *VaultKVSpec `json:"vault,omitempty"`
+}
+
+type VaultKVSpec struct {
+	Address string `json:"address"`
+	// Mount is the path the KV secrets engine is mounted at, "secret" by default
+	Mount string `json:"mount,omitempty"`
+	// Path is the path of the secret with the system users credentials inside the mount
+	Path      string `json:"path"`
+	KVVersion int    `json:"kvVersion,omitempty"`
+	// Role and AuthPath are used for the Kubernetes auth method
+	Role     string `json:"role,omitempty"`
+	AuthPath string `json:"authPath,omitempty"`
+	// TokenSecretName is a secret with the vault token under the `token` key.
+	// It is used instead of the Kubernetes auth method if set
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+	// CASecretName is a secret with the vault CA certificate under the `ca.crt` key
+	CASecretName    string `json:"caSecretName,omitempty"`
+	RefreshInterval int64  `json:"refreshIntervalSec,omitempty"`
+}
+
+const (
+	defaultVaultMount           = "secret"
+	defaultVaultAuthPath        = "kubernetes"
+	defaultVaultKVVersion       = 2
+	defaultVaultRefreshInterval = 300
+)
+
 type UpgradeOptions struct {
-	VersionServiceEndpoint string `json:"versionServiceEndpoint,omitempty"`
-	Apply                  string `json:"apply,omitempty"`
-	Schedule               string `json:"schedule,omitempty"`
+	VersionServiceEndpoint string      `json:"versionServiceEndpoint,omitempty"`
+	Apply                  string      `json:"apply,omitempty"`
+	Schedule               string      `json:"schedule,omitempty"`
+	Canary                 *CanarySpec `json:"canary,omitempty"`
+	// Rollback makes SmartUpdate revert the failed upgrade
+	// to the last known-good state
+	Rollback bool `json:"rollback,omitempty"`
+	// VersionCatalog is the name of the config map with the version matrix
+	// used instead of the version service
+	VersionCatalog string `json:"versionCatalog,omitempty"`
+	// PlanOnly makes the operator report the upgrade plan in the status and
+	// wait for UpgradePlanApprovalAnnotation before changing the images
+	PlanOnly bool `json:"planOnly,omitempty"`
+	// MajorUpgrade configures the upgrade to the next major version of PXC
+	MajorUpgrade *MajorUpgradeSpec `json:"majorUpgrade,omitempty"`
+}
+
+// MajorUpgradeSpec enables the guided upgrade to the next major version of
+// PXC (5.7 to 8.0). The new image is rolled out only after the pre-flight
+// checks passed and the backup is made.
+type MajorUpgradeSpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// BackupStorageName is the storage of the backup made before the upgrade.
+	// If it's empty, the upgrade waits for a backup made by the user.
+	BackupStorageName string `json:"backupStorageName,omitempty"`
+	// PreflightImage is the image with MySQL Shell to run the upgrade checker.
+	// PXC images have no MySQL Shell, so a dedicated image is used by default.
+	PreflightImage string `json:"preflightImage,omitempty"`
+}
+
+type MajorUpgradeStage string
+
+const (
+	MajorUpgradeStagePreflight   MajorUpgradeStage = "Preflight"
+	MajorUpgradeStageBlocked     MajorUpgradeStage = "Blocked"
+	MajorUpgradeStageBackup      MajorUpgradeStage = "Backup"
+	MajorUpgradeStageNodes       MajorUpgradeStage = "UpgradingNodes"
+	MajorUpgradeStagePostUpgrade MajorUpgradeStage = "PostUpgrade"
+	MajorUpgradeStageCompleted   MajorUpgradeStage = "Completed"
+)
+
+type NodeUpgradeStage string
+
+const (
+	NodeUpgradeStagePending   NodeUpgradeStage = "Pending"
+	NodeUpgradeStageUpgrading NodeUpgradeStage = "Upgrading"
+	NodeUpgradeStageUpgraded  NodeUpgradeStage = "Upgraded"
+)
+
+// MajorUpgradeStatus is the progress of the major upgrade
+type MajorUpgradeStatus struct {
+	From               string              `json:"from,omitempty"`
+	To                 string              `json:"to,omitempty"`
+	Image              string              `json:"image,omitempty"`
+	Stage              MajorUpgradeStage   `json:"stage,omitempty"`
+	Message            string              `json:"message,omitempty"`
+	StartedAt          *metav1.Time        `json:"startedAt,omitempty"`
+	Backup             string              `json:"backup,omitempty"`
+	Nodes              []NodeUpgradeStatus `json:"nodes,omitempty"`
+	ObservedGeneration int64               `json:"observedGeneration,omitempty"`
+}
+
+// NodeUpgradeStatus is the upgrade stage of the PXC pod
+type NodeUpgradeStatus struct {
+	Pod   string           `json:"pod"`
+	Stage NodeUpgradeStage `json:"stage"`
+}
+
+// UpgradePlanApprovalAnnotation on the cluster approves the upgrade plan.
+// Its value is the plan ID.
+const UpgradePlanApprovalAnnotation = "percona.com/approve-upgrade-plan"
+
+// UpgradePlan is the upgrade to the versions returned by the version service
+// that waits for the approval
+type UpgradePlan struct {
+	ID         string             `json:"id,omitempty"`
+	Components []ComponentUpgrade `json:"components,omitempty"`
+	PlannedAt  *metav1.Time       `json:"plannedAt,omitempty"`
+}
+
+// ComponentUpgrade is the planned upgrade of the cluster component
+type ComponentUpgrade struct {
+	Name        string `json:"name"`
+	FromVersion string `json:"fromVersion,omitempty"`
+	ToVersion   string `json:"toVersion,omitempty"`
+	FromImage   string `json:"fromImage,omitempty"`
+	ToImage     string `json:"toImage,omitempty"`
+}
+
+// CanaryApprovalAnnotation on the cluster approves the SmartUpdate to
+// continue after the canary stage. Its value is the statefulset revision.
+const CanaryApprovalAnnotation = "percona.com/canary-approved"
+
+// CanarySpec configures the canary stage of SmartUpdate. One secondary pod
+// is updated first and the rest of pods are updated only if it passes the checks.
+type CanarySpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// SoakSeconds is how long the canary pod has to stay healthy
+	SoakSeconds int32 `json:"soakSeconds,omitempty"`
+	// MaxErrorLogLines is the number of [ERROR] lines allowed in the log
+	// of the canary pod since it was updated
+	MaxErrorLogLines *int32 `json:"maxErrorLogLines,omitempty"`
+	// FailureThreshold is the number of consecutive failed checks
+	// after which the canary is failed
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// Checks are the SQL queries run on the canary pod
+	Checks []CanaryCheck `json:"checks,omitempty"`
+	// RequireApproval makes SmartUpdate wait for CanaryApprovalAnnotation
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+func (c *CanarySpec) setDefaults() {
+	if c.MaxErrorLogLines == nil {
+		maxErrorLogLines := int32(10)
+		c.MaxErrorLogLines = &maxErrorLogLines
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 3
+	}
+}
+
+// CanaryCheck is the SQL query that passes if it returns 1 in the first row
+type CanaryCheck struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+type CanaryState string
+
+const (
+	CanaryStateSoaking         CanaryState = "Soaking"
+	CanaryStateWaitingApproval CanaryState = "WaitingApproval"
+	CanaryStatePassed          CanaryState = "Passed"
+	CanaryStateFailed          CanaryState = "Failed"
+)
+
+// CanaryStatus is the state of the canary stage of the statefulset revision
+type CanaryStatus struct {
+	Revision  string       `json:"revision,omitempty"`
+	Pod       string       `json:"pod,omitempty"`
+	State     CanaryState  `json:"state,omitempty"`
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	// Failures is the number of consecutive failed checks
+	Failures int32 `json:"failures,omitempty"`
 }
 
 const (
 	SmartUpdateStatefulSetStrategyType appsv1.StatefulSetUpdateStrategyType = "SmartUpdate"
 )
 
+// MaintenanceOverrideAnnotation set to "true" on the cluster applies
+// the deferred changes without waiting for the maintenance window
+const MaintenanceOverrideAnnotation = "percona.com/ignore-maintenance-windows"
+
+// MaintenanceWindow is the time when the changes that restart pods
+// (new images, configuration, TLS certificates, etc.) can be rolled out
+type MaintenanceWindow struct {
+	// Schedule is the cron expression of the window start
+	Schedule string `json:"schedule"`
+	// Duration is the length of the window, e.g. 2h
+	Duration string `json:"duration"`
+	// TimeZone of the schedule, UTC by default
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+func (w *MaintenanceWindow) parse() (cron.Schedule, time.Duration, error) {
+	schedule := w.Schedule
+	if len(w.TimeZone) > 0 {
+		schedule = "CRON_TZ=" + w.TimeZone + " " + schedule
+	}
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "parse schedule")
+	}
+
+	d, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "parse duration")
+	}
+	if d <= 0 {
+		return nil, 0, errors.New("duration should be positive")
+	}
+
+	return sched, d, nil
+}
+
+// MaintenanceWindowOpen returns true if t is inside any of the maintenance
+// windows or the windows aren't set. Otherwise it also returns the start
+// of the next window.
+func (s *PerconaXtraDBClusterSpec) MaintenanceWindowOpen(t time.Time) (bool, time.Time, error) {
+	var next time.Time
+	if len(s.MaintenanceWindows) == 0 {
+		return true, next, nil
+	}
+
+	for i := range s.MaintenanceWindows {
+		sched, d, err := s.MaintenanceWindows[i].parse()
+		if err != nil {
+			return false, next, errors.Wrapf(err, "maintenance window %d", i)
+		}
+		if start := sched.Next(t.Add(-d)); !start.After(t) {
+			return true, time.Time{}, nil
+		}
+		if n := sched.Next(t); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+
+	return false, next, nil
+}
+
 type PXCScheduledBackup struct {
 	Image              string                        `json:"image,omitempty"`
 	ImagePullSecrets   []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
@@ -95,7 +403,9 @@ const (
 	AppStateError   AppState = "error"
 )
 
-// PerconaXtraDBClusterStatus defines the observed state of PerconaXtraDBCluster
+// PerconaXtraDBClusterStatus defines the observed state of PerconaXtraDBCluster.
+// Messages is deprecated in favour of Conditions, it is still filled with
+// the current app errors for the tools that read it.
 type PerconaXtraDBClusterStatus struct {
 	PXC                AppStatus          `json:"pxc,omitempty"`
 	ProxySQL           AppStatus          `json:"proxysql,omitempty"`
@@ -108,6 +418,127 @@ type PerconaXtraDBClusterStatus struct {
 	Status             AppState           `json:"state,omitempty"`
 	Conditions         []ClusterCondition `json:"conditions,omitempty"`
 	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	// AutoTuneParams are the mysqld variables computed by the auto-tuning
+	AutoTuneParams map[string]string `json:"autoTuneParams,omitempty"`
+	// ConfigChanges is the result of the last change of PXC.Configuration
+	ConfigChanges *ConfigChangesStatus `json:"configChanges,omitempty"`
+	// Canary is the state of the canary stage of the last SmartUpdate
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// LastKnownGood is the last state the cluster was ready with.
+	// A failed upgrade is rolled back to it.
+	LastKnownGood *KnownGoodState `json:"lastKnownGood,omitempty"`
+	// FailedUpgrade holds the images of the upgrade rolled back to LastKnownGood.
+	// The version service doesn't upgrade the cluster to them again until
+	// FailedUpgrade is removed from the status by the user.
+	FailedUpgrade *KnownGoodState `json:"failedUpgrade,omitempty"`
+	// Maintenance shows the changes waiting for the maintenance window
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+	// UpgradePlan is the upgrade waiting for the approval
+	UpgradePlan *UpgradePlan `json:"upgradePlan,omitempty"`
+	// MajorUpgrade is the progress of the last major upgrade of PXC
+	MajorUpgrade *MajorUpgradeStatus `json:"majorUpgrade,omitempty"`
+	// QueryRules are the hit counters of the ProxySQL query rules
+	QueryRules []QueryRuleStatus `json:"queryRules,omitempty"`
+	// Topology is the node and the zone of each scheduled PXC pod
+	Topology []PodTopology `json:"topology,omitempty"`
+	// VolumeExpansion is the progress of the last expansion of the data volumes
+	VolumeExpansion []VolumeExpansionStatus `json:"volumeExpansion,omitempty"`
+	// StorageAutoscaling is the datadir usage and the size the PXC volumes are grown to
+	StorageAutoscaling *StorageAutoscalingStatus `json:"storageAutoscaling,omitempty"`
+}
+
+// StorageAutoscalingStatus is the result of the last check of the datadir usage
+type StorageAutoscalingStatus struct {
+	// Size overrides the size of the PXC volumes if it's bigger
+	Size       string            `json:"size,omitempty"`
+	LastCheck  *metav1.Time      `json:"lastCheck,omitempty"`
+	LastGrowth *metav1.Time      `json:"lastGrowth,omitempty"`
+	Pods       []PodStorageUsage `json:"pods,omitempty"`
+	// GrownFromKiB is the biggest datadir filesystem before the last growth.
+	// The volumes aren't grown again until all filesystems are bigger.
+	GrownFromKiB int64 `json:"grownFromKiB,omitempty"`
+}
+
+// PodStorageUsage is the datadir usage of the PXC pod
+type PodStorageUsage struct {
+	Pod         string `json:"pod"`
+	UsedPercent int    `json:"usedPercent"`
+	CapacityKiB int64  `json:"capacityKiB,omitempty"`
+	// Error is set if the usage of the pod can't be read
+	Error string `json:"error,omitempty"`
+}
+
+type VolumeExpansionState string
+
+const (
+	VolumeExpansionInProgress VolumeExpansionState = "InProgress"
+	VolumeExpansionCompleted  VolumeExpansionState = "Completed"
+	VolumeExpansionFailed     VolumeExpansionState = "Failed"
+)
+
+// VolumeExpansionStatus is the expansion of the data volumes of the component
+type VolumeExpansionStatus struct {
+	Component string               `json:"component"`
+	From      string               `json:"from,omitempty"`
+	To        string               `json:"to"`
+	State     VolumeExpansionState `json:"state"`
+	Message   string               `json:"message,omitempty"`
+	PVCs      []PVCExpansionStatus `json:"pvcs,omitempty"`
+}
+
+type PVCExpansionState string
+
+const (
+	// PVCResizing waits for the volume to be expanded by the storage provider
+	PVCResizing PVCExpansionState = "Resizing"
+	// PVCFileSystemResizePending waits for kubelet to resize the filesystem
+	PVCFileSystemResizePending PVCExpansionState = "FileSystemResizePending"
+	PVCResized                 PVCExpansionState = "Resized"
+	PVCResizeFailed            PVCExpansionState = "Failed"
+)
+
+// PVCExpansionStatus is the expansion of the single PVC
+type PVCExpansionStatus struct {
+	Name     string            `json:"name"`
+	Capacity string            `json:"capacity,omitempty"`
+	State    PVCExpansionState `json:"state"`
+	Message  string            `json:"message,omitempty"`
+}
+
+// PodTopology is the placement of the pod published to the proxies
+type PodTopology struct {
+	Pod  string `json:"pod"`
+	Node string `json:"node,omitempty"`
+	Zone string `json:"zone,omitempty"`
+}
+
+// MaintenanceStatus holds the rollouts deferred until the maintenance window
+type MaintenanceStatus struct {
+	// NextWindow is the start of the next maintenance window
+	NextWindow *metav1.Time `json:"nextWindow,omitempty"`
+	// PendingActions are the deferred changes prefixed with the component name
+	PendingActions []string `json:"pendingActions,omitempty"`
+}
+
+// KnownGoodState holds the images, the configuration hash and the CR version
+// of the cluster recorded before the upgrade
+type KnownGoodState struct {
+	CRVersion         string `json:"crVersion,omitempty"`
+	PXCImage          string `json:"pxcImage,omitempty"`
+	ProxySQLImage     string `json:"proxysqlImage,omitempty"`
+	HAProxyImage      string `json:"haproxyImage,omitempty"`
+	BackupImage       string `json:"backupImage,omitempty"`
+	PMMImage          string `json:"pmmImage,omitempty"`
+	LogCollectorImage string `json:"logCollectorImage,omitempty"`
+	ConfigHash        string `json:"configHash,omitempty"`
+}
+
+// ConfigChangesStatus reports how the changed options were applied
+type ConfigChangesStatus struct {
+	// AppliedOnline are the dynamic variables set with SET GLOBAL on all pods
+	AppliedOnline []string `json:"appliedOnline,omitempty"`
+	// RestartRequired are the options applied by the rolling restart
+	RestartRequired []string `json:"restartRequired,omitempty"`
 }
 
 type ConditionStatus string
@@ -121,22 +552,95 @@ const (
 type ClusterConditionType string
 
 const (
-	ClusterReady        ClusterConditionType = "Ready"
-	ClusterInit                              = "Initializing"
-	ClusterPXCReady                          = "PXCReady"
-	ClusterProxyReady                        = "ProxySQLReady"
-	ClusterHAProxyReady                      = "HAProxyReady"
-	ClusterError                             = "Error"
+	ClusterReady              ClusterConditionType = "Ready"
+	ClusterPXCReady           ClusterConditionType = "PXCReady"
+	ClusterProxyReady         ClusterConditionType = "ProxyReady"
+	ClusterBackupsHealthy     ClusterConditionType = "BackupsHealthy"
+	ClusterPITRHealthy        ClusterConditionType = "PITRHealthy"
+	ClusterTLSValid           ClusterConditionType = "TLSValid"
+	ClusterUpgradeInProgress  ClusterConditionType = "UpgradeInProgress"
+	ClusterPaused             ClusterConditionType = "Paused"
+	ClusterRestoreInProgress  ClusterConditionType = "RestoreInProgress"
+	ClusterFullCrashRecovery  ClusterConditionType = "FullCrashRecovery"
+	ClusterConfigurationValid ClusterConditionType = "ConfigurationValid"
+	ClusterUpgradeRolledBack  ClusterConditionType = "UpgradeRolledBack"
+	ClusterProxySQLConfigured ClusterConditionType = "ProxySQLConfigured"
+	ClusterTopologyAvailable  ClusterConditionType = "TopologyAvailable"
+	// ClusterReconciled is False if the last reconcile loop failed. The errors
+	// can be transient, so they don't change the Ready condition.
+	ClusterReconciled ClusterConditionType = "Reconciled"
 )
 
+// ClusterError is the cluster state set if the reconcile loop fails.
+// It is also used as a deprecated condition type.
+const ClusterError = "Error"
+
+// Deprecated condition types of the previous operator versions. The operator
+// keeps them up to date alongside the new ones, they will be removed in
+// the future versions.
+const (
+	ClusterInit          ClusterConditionType = "Initializing"
+	ClusterProxySQLReady ClusterConditionType = "ProxySQLReady"
+	ClusterHAProxyReady  ClusterConditionType = "HAProxyReady"
+)
+
+// ClusterCondition is a Kubernetes-style condition. There is only one
+// condition of every type, the history of changes is kept in events.
 type ClusterCondition struct {
 	Status             ConditionStatus      `json:"status,omitempty"`
 	Type               ClusterConditionType `json:"type,omitempty"`
+	ObservedGeneration int64                `json:"observedGeneration,omitempty"`
 	LastTransitionTime metav1.Time          `json:"lastTransitionTime,omitempty"`
 	Reason             string               `json:"reason,omitempty"`
 	Message            string               `json:"message,omitempty"`
 }
 
+// FindCondition returns the condition of the given type or nil if it isn't set
+func (s *PerconaXtraDBClusterStatus) FindCondition(t ClusterConditionType) *ClusterCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the same type.
+// LastTransitionTime is changed only if the condition status was changed.
+// It returns true if the status was changed.
+func (s *PerconaXtraDBClusterStatus) SetCondition(c ClusterCondition) bool {
+	existing := s.FindCondition(c.Type)
+	if existing == nil {
+		if c.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, c)
+		return true
+	}
+
+	changed := existing.Status != c.Status
+	if changed {
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Status = c.Status
+	existing.Reason = c.Reason
+	existing.Message = c.Message
+	existing.ObservedGeneration = c.ObservedGeneration
+
+	return changed
+}
+
+// RemoveCondition deletes the condition of the given type
+func (s *PerconaXtraDBClusterStatus) RemoveCondition(t ClusterConditionType) {
+	conditions := s.Conditions[:0]
+	for _, c := range s.Conditions {
+		if c.Type != t {
+			conditions = append(conditions, c)
+		}
+	}
+	s.Conditions = conditions
+}
+
 type AppStatus struct {
 	Size    int32    `json:"size,omitempty"`
 	Ready   int32    `json:"ready,omitempty"`
@@ -144,6 +648,27 @@ type AppStatus struct {
 	Message string   `json:"message,omitempty"`
 	Version string   `json:"version,omitempty"`
 	Image   string   `json:"image,omitempty"`
+	// Members is the per-node Galera status, it's filled in only for PXC
+	Members []PXCMemberStatus `json:"members,omitempty"`
+}
+
+// PXCMemberStatus is the Galera state of the PXC pod
+type PXCMemberStatus struct {
+	Name string `json:"name"`
+	// State is wsrep_local_state_comment
+	State string `json:"state,omitempty"`
+	// ClusterStatus is wsrep_cluster_status
+	ClusterStatus string `json:"clusterStatus,omitempty"`
+	// Seqno is wsrep_last_committed
+	Seqno int64 `json:"seqno,omitempty"`
+	// FlowControlPaused is wsrep_flow_control_paused, the fraction of time
+	// the replication was paused by the flow control
+	FlowControlPaused string `json:"flowControlPaused,omitempty"`
+	// RecvQueue is wsrep_local_recv_queue
+	RecvQueue int64 `json:"recvQueue,omitempty"`
+	// Writer is true if the node is the current writer in ProxySQL/HAProxy
+	Writer  bool   `json:"writer,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -191,6 +716,21 @@ func (cr *PerconaXtraDBCluster) Validate() error {
 		return errors.Wrap(err, "PXC: validate volume spec")
 	}
 
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		if err := c.PXC.validateScheduling(); err != nil {
+			return errors.Wrap(err, "pxc")
+		}
+	}
+
+	if c.PXC.StorageAutoscaling != nil && c.PXC.StorageAutoscaling.Enabled {
+		if cr.CompareVersionWith("1.8.0") < 0 {
+			return errors.New("pxc.storageAutoscaling is supported starting from crVersion 1.8.0")
+		}
+		if err := c.PXC.StorageAutoscaling.validate(c.PXC.VolumeSpec); err != nil {
+			return errors.Wrap(err, "pxc.storageAutoscaling")
+		}
+	}
+
 	if c.HAProxy != nil && c.HAProxy.Enabled &&
 		c.ProxySQL != nil && c.ProxySQL.Enabled {
 		return errors.New("can't enable both HAProxy and ProxySQL please only select one of them")
@@ -200,6 +740,22 @@ func (cr *PerconaXtraDBCluster) Validate() error {
 		if c.HAProxy.Image == "" {
 			return errors.New("haproxy.Image can't be empty")
 		}
+		if cr.CompareVersionWith("1.8.0") >= 0 {
+			if err := c.HAProxy.validateScheduling(); err != nil {
+				return errors.Wrap(err, "haproxy")
+			}
+		}
+		if c.HAProxy.Backends != nil {
+			if cr.CompareVersionWith("1.8.0") < 0 {
+				return errors.New("haproxy backends are supported starting from crVersion 1.8.0")
+			}
+			if err := c.HAProxy.Backends.validate(cr.Name, c.PXC.Size); err != nil {
+				return errors.Wrap(err, "haproxy.backends")
+			}
+		}
+		if c.HAProxy.ZoneAwareReads && c.HAProxy.Backends == nil {
+			return errors.New("haproxy.zoneAwareReads requires haproxy.backends to be set")
+		}
 	}
 
 	if c.ProxySQL != nil && c.ProxySQL.Enabled {
@@ -213,6 +769,28 @@ func (cr *PerconaXtraDBCluster) Validate() error {
 		if err := c.ProxySQL.VolumeSpec.validate(); err != nil {
 			return errors.Wrap(err, "ProxySQL: validate volume spec")
 		}
+
+		if cr.CompareVersionWith("1.8.0") >= 0 {
+			if err := c.ProxySQL.validateScheduling(); err != nil {
+				return errors.Wrap(err, "proxysql")
+			}
+		}
+
+		if c.ProxySQL.Hostgroups != nil || len(c.ProxySQL.QueryRules) > 0 || c.ProxySQL.ZoneAwareReads {
+			if !cr.ProxySQLManaged() {
+				return errors.New("proxysql hostgroups, queryRules and zoneAwareReads require proxysql.managed and crVersion 1.8.0 or newer")
+			}
+		}
+		hostgroups := ProxySQLHostgroups{}
+		if c.ProxySQL.Hostgroups != nil {
+			if err := c.ProxySQL.Hostgroups.validate(); err != nil {
+				return errors.Wrap(err, "proxysql.hostgroups")
+			}
+			hostgroups = *c.ProxySQL.Hostgroups
+		}
+		if err := validateQueryRules(c.ProxySQL.QueryRules, hostgroups); err != nil {
+			return errors.Wrap(err, "proxysql.queryRules")
+		}
 	}
 
 	if c.Backup != nil {
@@ -241,6 +819,24 @@ func (cr *PerconaXtraDBCluster) Validate() error {
 		}
 	}
 
+	if c.SecretsProvider != nil && c.SecretsProvider.Vault != nil {
+		if err := c.SecretsProvider.Vault.validate(); err != nil {
+			return errors.Wrap(err, "secretsProvider.vault")
+		}
+		if cr.CompareVersionWith("1.6.0") < 0 {
+			return errors.New("secretsProvider is supported starting from crVersion 1.6.0")
+		}
+	}
+
+	if len(c.MaintenanceWindows) > 0 {
+		if cr.CompareVersionWith("1.8.0") < 0 {
+			return errors.New("maintenanceWindows are supported starting from crVersion 1.8.0")
+		}
+		if _, _, err := c.MaintenanceWindowOpen(time.Now()); err != nil {
+			return err
+		}
+	}
+
 	if c.UpdateStrategy == SmartUpdateStatefulSetStrategyType &&
 		(c.ProxySQL == nil || !c.ProxySQL.Enabled) &&
 		(c.HAProxy == nil || !c.HAProxy.Enabled) {
@@ -269,6 +865,7 @@ type PodSpec struct {
 	Affinity                      *PodAffinity                            `json:"affinity,omitempty"`
 	NodeSelector                  map[string]string                       `json:"nodeSelector,omitempty"`
 	Tolerations                   []corev1.Toleration                     `json:"tolerations,omitempty"`
+	TopologySpreadConstraints     []corev1.TopologySpreadConstraint       `json:"topologySpreadConstraints,omitempty"`
 	PriorityClassName             string                                  `json:"priorityClassName,omitempty"`
 	Annotations                   map[string]string                       `json:"annotations,omitempty"`
 	Labels                        map[string]string                       `json:"labels,omitempty"`
@@ -295,6 +892,332 @@ type PodSpec struct {
 	ImagePullPolicy               corev1.PullPolicy                       `json:"imagePullPolicy,omitempty"`
 	Sidecars                      []corev1.Container                      `json:"sidecars,omitempty"`
 	RuntimeClassName              *string                                 `json:"runtimeClassName,omitempty"`
+	// Managed makes the operator configure ProxySQL over the admin interface
+	// instead of the scripts run by the ProxySQL sidecars
+	Managed bool `json:"managed,omitempty"`
+	// Hostgroups and QueryRules are applied to the managed ProxySQL
+	Hostgroups *ProxySQLHostgroups `json:"hostgroups,omitempty"`
+	QueryRules []ProxySQLQueryRule `json:"queryRules,omitempty"`
+	// Backends configures the HAProxy backends rendered by the operator
+	Backends *HAProxyBackendsSpec `json:"backends,omitempty"`
+	// ZoneAwareReads makes the proxy send the reads to the PXC pods
+	// in its own zone first. The writes still go to the single writer.
+	ZoneAwareReads bool `json:"zoneAwareReads,omitempty"`
+}
+
+type HAProxyWriterPolicy string
+
+const (
+	// HAProxyWriterLowestOrdinal sends writes to the ready node with the
+	// lowest ordinal and returns them to it once it's ready again
+	HAProxyWriterLowestOrdinal HAProxyWriterPolicy = "lowest-ordinal"
+	// HAProxyWriterSticky keeps the writer until it fails
+	HAProxyWriterSticky HAProxyWriterPolicy = "sticky"
+	// HAProxyWriterPreferredZone prefers the nodes of PreferredZone
+	HAProxyWriterPreferredZone HAProxyWriterPolicy = "preferred-zone"
+)
+
+// HAProxyBackendsSpec replaces the backends generated by the HAProxy
+// image scripts with the configuration rendered by the operator
+type HAProxyBackendsSpec struct {
+	WriterPolicy  HAProxyWriterPolicy `json:"writerPolicy,omitempty"`
+	PreferredZone string              `json:"preferredZone,omitempty"`
+	// Weights are the weights of the PXC pods in the replicas backend,
+	// the pods that aren't listed have weight 100
+	Weights   map[string]int    `json:"weights,omitempty"`
+	Listeners []HAProxyListener `json:"listeners,omitempty"`
+	// MaxConnections limits the connections of each frontend
+	MaxConnections int `json:"maxConnections,omitempty"`
+	// ServerMaxConnections limits the connections to each PXC pod
+	ServerMaxConnections int `json:"serverMaxConnections,omitempty"`
+}
+
+// HAProxyListener is the extra frontend balancing between the PXC pods.
+// All pods are used if Nodes is empty.
+type HAProxyListener struct {
+	Name           string   `json:"name"`
+	Port           int32    `json:"port"`
+	Nodes          []string `json:"nodes,omitempty"`
+	MaxConnections int      `json:"maxConnections,omitempty"`
+}
+
+// HAProxyBackendsManaged returns true if the HAProxy backends are rendered
+// by the operator instead of the pxc-monit sidecar
+func (cr *PerconaXtraDBCluster) HAProxyBackendsManaged() bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 && cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled &&
+		cr.Spec.HAProxy.Backends != nil
+}
+
+// HAProxyMetricsEnabled returns true if HAProxy serves the metrics. The exporter
+// frontend is rendered in the backends config, so the backends must be managed.
+func (cr *PerconaXtraDBCluster) HAProxyMetricsEnabled() bool {
+	return cr.Spec.Metrics != nil && cr.Spec.Metrics.Enabled && cr.HAProxyBackendsManaged()
+}
+
+// TopologyAware returns true if the proxies need the zones of the PXC pods
+func (cr *PerconaXtraDBCluster) TopologyAware() bool {
+	if cr.HAProxyBackendsManaged() {
+		return cr.Spec.HAProxy.ZoneAwareReads || cr.Spec.HAProxy.Backends.WriterPolicy == HAProxyWriterPreferredZone
+	}
+	return cr.ProxySQLManaged() && cr.Spec.ProxySQL.ZoneAwareReads
+}
+
+// haproxyReservedNames are the port names of the HAProxy container and services
+// and the names of the default frontends
+var haproxyReservedNames = map[string]bool{
+	"mysql": true, "mysql-replicas": true, "proxy-protocol": true, "mysql-admin": true, "metrics": true,
+	"galera": true, "galera-replica": true, "galera-mysqlx": true, "galera-admin": true,
+}
+
+var haproxyListenerName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// haproxyReservedPorts are the ports of the frontends generated for HAProxy
+var haproxyReservedPorts = map[int32]bool{3306: true, 3307: true, 3309: true, 33060: true, 33062: true, 8404: true}
+
+func (b *HAProxyBackendsSpec) validate(clusterName string, size int32) error {
+	switch b.WriterPolicy {
+	case "", HAProxyWriterLowestOrdinal, HAProxyWriterSticky:
+	case HAProxyWriterPreferredZone:
+		if len(b.PreferredZone) == 0 {
+			return errors.New("preferredZone should be set for the preferred-zone writer policy")
+		}
+	default:
+		return errors.Errorf("unknown writer policy %s", b.WriterPolicy)
+	}
+
+	pods := make(map[string]bool, size)
+	for i := int32(0); i < size; i++ {
+		pods[fmt.Sprintf("%s-pxc-%d", clusterName, i)] = true
+	}
+	for pod, w := range b.Weights {
+		if !pods[pod] {
+			return errors.Errorf("weights: unknown pod %s", pod)
+		}
+		if w < 0 || w > 256 {
+			return errors.Errorf("weights: weight of %s should be between 0 and 256", pod)
+		}
+	}
+
+	names := make(map[string]bool, len(b.Listeners))
+	ports := make(map[int32]bool, len(b.Listeners))
+	for _, l := range b.Listeners {
+		// the name is used as the name of the container and the service port
+		if len(l.Name) > 15 || !haproxyListenerName.MatchString(l.Name) || haproxyReservedNames[l.Name] {
+			return errors.Errorf("listeners: name %q should be up to 15 lowercase alphanumeric characters or '-' and not used by the default frontends", l.Name)
+		}
+		if names[l.Name] {
+			return errors.Errorf("listeners: name %s is duplicated", l.Name)
+		}
+		names[l.Name] = true
+		if l.Port <= 0 || l.Port > 65535 || haproxyReservedPorts[l.Port] || ports[l.Port] {
+			return errors.Errorf("listeners: port %d of %s is invalid or used", l.Port, l.Name)
+		}
+		ports[l.Port] = true
+		for _, n := range l.Nodes {
+			if !pods[n] {
+				return errors.Errorf("listeners: unknown pod %s in %s", n, l.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ProxySQLHostgroups configures the Galera hostgroups of ProxySQL
+type ProxySQLHostgroups struct {
+	Writer                int `json:"writer,omitempty"`
+	BackupWriter          int `json:"backupWriter,omitempty"`
+	Reader                int `json:"reader,omitempty"`
+	Offline               int `json:"offline,omitempty"`
+	MaxWriters            int `json:"maxWriters,omitempty"`
+	WriterIsAlsoReader    int `json:"writerIsAlsoReader,omitempty"`
+	MaxTransactionsBehind int `json:"maxTransactionsBehind,omitempty"`
+}
+
+// ProxySQLQueryRule routes the matched queries to the hostgroup.
+// The rules are processed in the order of RuleID.
+type ProxySQLQueryRule struct {
+	RuleID int `json:"ruleId"`
+	// Active is true by default
+	Active       *bool  `json:"active,omitempty"`
+	Username     string `json:"username,omitempty"`
+	SchemaName   string `json:"schemaName,omitempty"`
+	FlagIn       int    `json:"flagIn,omitempty"`
+	MatchDigest  string `json:"matchDigest,omitempty"`
+	MatchPattern string `json:"matchPattern,omitempty"`
+	// NegateMatchPattern matches the queries not matching MatchPattern
+	NegateMatchPattern bool `json:"negateMatchPattern,omitempty"`
+	FlagOut            int  `json:"flagOut,omitempty"`
+	// DestinationHostgroup is one of the configured hostgroups,
+	// 0 keeps the default hostgroup of the user
+	DestinationHostgroup int    `json:"destinationHostgroup,omitempty"`
+	Apply                bool   `json:"apply,omitempty"`
+	Comment              string `json:"comment,omitempty"`
+}
+
+// QueryRuleStatus is the number of the queries matched by the rule
+// on all ProxySQL pods since they were started
+type QueryRuleStatus struct {
+	RuleID int   `json:"ruleId"`
+	Hits   int64 `json:"hits"`
+}
+
+func validateQueryRules(rules []ProxySQLQueryRule, hostgroups ProxySQLHostgroups) error {
+	hostgroups.setDefaults()
+	known := map[int]bool{
+		0:                       true,
+		hostgroups.Writer:       true,
+		hostgroups.BackupWriter: true,
+		hostgroups.Reader:       true,
+		hostgroups.Offline:      true,
+	}
+
+	ids := make(map[int]bool, len(rules))
+	for _, r := range rules {
+		if r.RuleID <= 0 {
+			return errors.Errorf("rule %d: ruleId should be positive", r.RuleID)
+		}
+		if ids[r.RuleID] {
+			return errors.Errorf("rule %d: ruleId is duplicated", r.RuleID)
+		}
+		ids[r.RuleID] = true
+
+		if len(r.MatchDigest) == 0 && len(r.MatchPattern) == 0 && len(r.Username) == 0 &&
+			len(r.SchemaName) == 0 && r.FlagIn == 0 {
+			return errors.Errorf("rule %d: no match criteria, set matchDigest, matchPattern, username, schemaName or flagIn", r.RuleID)
+		}
+		if r.NegateMatchPattern && len(r.MatchPattern) == 0 {
+			return errors.Errorf("rule %d: negateMatchPattern requires matchPattern", r.RuleID)
+		}
+		// the patterns are PCRE by default, so they aren't compiled here,
+		// only the brackets and the escapes are checked
+		for _, re := range []string{r.MatchDigest, r.MatchPattern} {
+			if len(re) > 0 && len(strings.TrimSpace(re)) == 0 {
+				return errors.Errorf("rule %d: the pattern is empty", r.RuleID)
+			}
+			if err := checkPatternSyntax(re); err != nil {
+				return errors.Errorf("rule %d: pattern %q: %v", r.RuleID, re, err)
+			}
+		}
+		if !known[r.DestinationHostgroup] {
+			return errors.Errorf("rule %d: destinationHostgroup %d isn't one of the configured hostgroups", r.RuleID, r.DestinationHostgroup)
+		}
+		if r.FlagIn < 0 || r.FlagOut < 0 {
+			return errors.Errorf("rule %d: flags can't be negative", r.RuleID)
+		}
+	}
+
+	return nil
+}
+
+// checkPatternSyntax checks the groups and the character classes of the
+// pattern are closed and the pattern doesn't end with a lone backslash
+func checkPatternSyntax(re string) error {
+	depth := 0
+	for i := 0; i < len(re); i++ {
+		switch re[i] {
+		case '\\':
+			if i == len(re)-1 {
+				return errors.New("trailing backslash")
+			}
+			i++
+		case '[':
+			end := classEnd(re, i)
+			if end < 0 {
+				return errors.New("missing closing ]")
+			}
+			i = end
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return errors.New("unexpected )")
+			}
+			depth--
+		}
+	}
+	if depth > 0 {
+		return errors.New("missing closing )")
+	}
+
+	return nil
+}
+
+// classEnd returns the index of ] closing the character class started at i
+// or -1. The ] right after [ or [^ belongs to the class.
+func classEnd(re string, i int) int {
+	j := i + 1
+	if j < len(re) && re[j] == '^' {
+		j++
+	}
+	if j < len(re) && re[j] == ']' {
+		j++
+	}
+	for ; j < len(re); j++ {
+		switch re[j] {
+		case '\\':
+			j++
+		case ']':
+			return j
+		}
+	}
+	return -1
+}
+
+// ProxySQLManaged returns true if the operator configures ProxySQL servers,
+// hostgroups, query rules and users over the admin interface instead of
+// the scripts run by the ProxySQL sidecars
+func (cr *PerconaXtraDBCluster) ProxySQLManaged() bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 && cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled &&
+		cr.Spec.ProxySQL.Managed
+}
+
+func (h *ProxySQLHostgroups) setDefaults() {
+	// the same hostgroups are set by proxysql-admin in the ProxySQL image
+	if h.Writer == 0 {
+		h.Writer = 11
+	}
+	if h.BackupWriter == 0 {
+		h.BackupWriter = 12
+	}
+	if h.Reader == 0 {
+		h.Reader = 10
+	}
+	if h.Offline == 0 {
+		h.Offline = 13
+	}
+	if h.MaxWriters == 0 {
+		h.MaxWriters = 1
+	}
+}
+
+func (h ProxySQLHostgroups) validate() error {
+	// the validation runs before the defaults are set
+	h.setDefaults()
+
+	ids := map[int]string{}
+	for _, hg := range []struct {
+		name string
+		id   int
+	}{
+		{"writer", h.Writer},
+		{"backupWriter", h.BackupWriter},
+		{"reader", h.Reader},
+		{"offline", h.Offline},
+	} {
+		if hg.id < 0 {
+			return errors.Errorf("%s hostgroup can't be negative", hg.name)
+		}
+		if other, ok := ids[hg.id]; ok {
+			return errors.Errorf("%s and %s hostgroups can't be the same", other, hg.name)
+		}
+		ids[hg.id] = hg.name
+	}
+	if h.WriterIsAlsoReader < 0 || h.WriterIsAlsoReader > 2 {
+		return errors.New("writerIsAlsoReader should be 0, 1 or 2")
+	}
+
+	return nil
 }
 
 type PodDisruptionBudgetSpec struct {
@@ -305,6 +1228,29 @@ type PodDisruptionBudgetSpec struct {
 type PodAffinity struct {
 	TopologyKey *string          `json:"antiAffinityTopologyKey,omitempty"`
 	Advanced    *corev1.Affinity `json:"advanced,omitempty"`
+	// ZoneSpread adds the anti-affinity between the zones
+	// to the one of TopologyKey. It's ignored with Advanced.
+	ZoneSpread *ZoneSpread `json:"zoneSpread,omitempty"`
+}
+
+type ZoneSpreadMode string
+
+const (
+	// ZoneSpreadPreferred spreads the pods over the zones if the scheduler can
+	ZoneSpreadPreferred ZoneSpreadMode = "preferred"
+	// ZoneSpreadRequired doesn't schedule two pods to the same zone,
+	// so the size can't be bigger than the number of the zones
+	ZoneSpreadRequired ZoneSpreadMode = "required"
+)
+
+// ZoneSpread spreads the pods of the component over the zones
+type ZoneSpread struct {
+	Mode ZoneSpreadMode `json:"mode,omitempty"`
+	// TopologyKey is topology.kubernetes.io/zone by default
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Weight is the weight of the preferred spread between 1 and 100.
+	// 0 isn't a valid weight of the scheduler, it means the default 100.
+	Weight int32 `json:"weight,omitempty"`
 }
 
 type PodResources struct {
@@ -335,6 +1281,36 @@ type PMMSpec struct {
 	RuntimeClassName         *string                 `json:"runtimeClassName,omitempty"`
 }
 
+// MetricsSpec defines Prometheus exporters that are run alongside
+// the cluster components. It doesn't require PMM server.
+type MetricsSpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Image is the mysqld_exporter image used for PXC pods
+	Image string `json:"image,omitempty"`
+	// ProxySQLImage is the proxysql_exporter image used for ProxySQL pods.
+	// HAProxy exposes metrics with its built-in exporter if the backends are managed.
+	ProxySQLImage            string                  `json:"proxysqlImage,omitempty"`
+	Args                     []string                `json:"args,omitempty"`
+	Resources                *PodResources           `json:"resources,omitempty"`
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+	ImagePullPolicy          corev1.PullPolicy       `json:"imagePullPolicy,omitempty"`
+	ServiceMonitor           *ServiceMonitorSpec     `json:"serviceMonitor,omitempty"`
+}
+
+// ServiceMonitorSpec defines ServiceMonitor objects created for the metrics services.
+// They are created only if the Prometheus Operator CRDs are installed.
+type ServiceMonitorSpec struct {
+	Enabled  bool              `json:"enabled,omitempty"`
+	Interval string            `json:"interval,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+const (
+	defaultMysqldExporterImage   = "prom/mysqld-exporter:v0.12.1"
+	defaultProxySQLExporterImage = "percona/proxysql_exporter:1.1.2"
+	defaultPreflightImage        = "mysql/mysql-server:8.0"
+)
+
 type ResourcesList struct {
 	Memory           string `json:"memory,omitempty"`
 	CPU              string `json:"cpu,omitempty"`
@@ -402,6 +1378,7 @@ type App interface {
 	AppContainer(spec *PodSpec, secrets string, cr *PerconaXtraDBCluster) (corev1.Container, error)
 	SidecarContainers(spec *PodSpec, secrets string, cr *PerconaXtraDBCluster) ([]corev1.Container, error)
 	PMMContainer(spec *PMMSpec, secrets string, cr *PerconaXtraDBCluster) (*corev1.Container, error)
+	MetricsContainer(spec *MetricsSpec, secrets string, cr *PerconaXtraDBCluster) (*corev1.Container, error)
 	LogCollectorContainer(spec *LogCollectorSpec, logPsecrets string, logRsecrets string, cr *PerconaXtraDBCluster) ([]corev1.Container, error)
 	Volumes(podSpec *PodSpec, cr *PerconaXtraDBCluster) (*Volume, error)
 	Labels() map[string]string
@@ -513,6 +1490,10 @@ func (cr *PerconaXtraDBCluster) CheckNSetDefaults(serverVersion *version.ServerV
 
 		c.PXC.reconcileAffinityOpts()
 
+		if c.PXC.StorageAutoscaling != nil {
+			c.PXC.StorageAutoscaling.setDefaults()
+		}
+
 		if c.Pause {
 			c.PXC.Size = 0
 		}
@@ -530,6 +1511,30 @@ func (cr *PerconaXtraDBCluster) CheckNSetDefaults(serverVersion *version.ServerV
 		}
 	}
 
+	if c.SecretsProvider != nil && c.SecretsProvider.Vault != nil {
+		c.SecretsProvider.Vault.setDefaults()
+	}
+
+	if c.UpgradeOptions.Canary != nil {
+		c.UpgradeOptions.Canary.setDefaults()
+	}
+
+	if c.UpgradeOptions.MajorUpgrade != nil && len(c.UpgradeOptions.MajorUpgrade.PreflightImage) == 0 {
+		c.UpgradeOptions.MajorUpgrade.PreflightImage = defaultPreflightImage
+	}
+
+	if c.Metrics != nil && c.Metrics.Enabled {
+		if len(c.Metrics.Image) == 0 {
+			c.Metrics.Image = defaultMysqldExporterImage
+		}
+		if len(c.Metrics.ProxySQLImage) == 0 {
+			c.Metrics.ProxySQLImage = defaultProxySQLExporterImage
+		}
+		if len(c.Metrics.ImagePullPolicy) == 0 {
+			c.Metrics.ImagePullPolicy = corev1.PullIfNotPresent
+		}
+	}
+
 	if c.PMM != nil && c.PMM.Enabled {
 		if len(c.PMM.ImagePullPolicy) == 0 {
 			c.PMM.ImagePullPolicy = corev1.PullAlways
@@ -603,6 +1608,13 @@ func (cr *PerconaXtraDBCluster) CheckNSetDefaults(serverVersion *version.ServerV
 			c.ProxySQL.ServiceAccountName = workloadSA
 		}
 
+		if cr.ProxySQLManaged() {
+			if c.ProxySQL.Hostgroups == nil {
+				c.ProxySQL.Hostgroups = &ProxySQLHostgroups{WriterIsAlsoReader: 1, MaxTransactionsBehind: 100}
+			}
+			c.ProxySQL.Hostgroups.setDefaults()
+		}
+
 		c.ProxySQL.reconcileAffinityOpts()
 
 		if c.Pause {
@@ -729,17 +1741,78 @@ const AffinityTopologyKeyOff = "none"
 var affinityValidTopologyKeys = map[string]struct{}{
 	AffinityTopologyKeyOff:                     {},
 	"kubernetes.io/hostname":                   {},
+	"topology.kubernetes.io/zone":              {},
+	"topology.kubernetes.io/region":            {},
 	"failure-domain.beta.kubernetes.io/zone":   {},
 	"failure-domain.beta.kubernetes.io/region": {},
 }
 
 var defaultAffinityTopologyKey = "kubernetes.io/hostname"
 
+var zoneSpreadValidTopologyKeys = map[string]struct{}{
+	"topology.kubernetes.io/zone":              {},
+	"topology.kubernetes.io/region":            {},
+	"failure-domain.beta.kubernetes.io/zone":   {},
+	"failure-domain.beta.kubernetes.io/region": {},
+}
+
+const (
+	defaultZoneSpreadTopologyKey       = "topology.kubernetes.io/zone"
+	defaultZoneSpreadWeight      int32 = 100
+)
+
+// validateScheduling rejects the affinity and the spread constraints
+// that reconcileAffinityOpts would replace or the scheduler can't use
+func (p *PodSpec) validateScheduling() error {
+	if af := p.Affinity; af != nil && af.Advanced == nil {
+		if af.TopologyKey != nil {
+			if _, ok := affinityValidTopologyKeys[*af.TopologyKey]; !ok {
+				return errors.Errorf("affinity: invalid antiAffinityTopologyKey %s", *af.TopologyKey)
+			}
+		}
+		if af.ZoneSpread != nil {
+			// the weight isn't set by the user if it's 0
+			zs := *af.ZoneSpread
+			if zs.Weight == 0 {
+				zs.Weight = defaultZoneSpreadWeight
+			}
+			switch zs.Mode {
+			case "", ZoneSpreadPreferred, ZoneSpreadRequired:
+			default:
+				return errors.Errorf("affinity.zoneSpread: invalid mode %s", zs.Mode)
+			}
+			if _, ok := zoneSpreadValidTopologyKeys[zs.TopologyKey]; !ok && zs.TopologyKey != "" {
+				return errors.Errorf("affinity.zoneSpread: invalid topologyKey %s", zs.TopologyKey)
+			}
+			if zs.Weight < 1 || zs.Weight > 100 {
+				return errors.New("affinity.zoneSpread: weight should be between 1 and 100")
+			}
+		}
+	}
+
+	for i, c := range p.TopologySpreadConstraints {
+		if c.MaxSkew < 1 {
+			return errors.Errorf("topologySpreadConstraints[%d]: maxSkew should be greater than 0", i)
+		}
+		if c.TopologyKey == "" {
+			return errors.Errorf("topologySpreadConstraints[%d]: topologyKey can't be empty", i)
+		}
+		switch c.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			return errors.Errorf("topologySpreadConstraints[%d]: invalid whenUnsatisfiable %s", i, c.WhenUnsatisfiable)
+		}
+	}
+
+	return nil
+}
+
 // reconcileAffinityOpts ensures that the affinity is set to the valid values.
 // - if the affinity doesn't set at all - set topology key to `defaultAffinityTopologyKey`
-// - if topology key is set and the value not the one of `affinityValidTopologyKeys` - set to `defaultAffinityTopologyKey`
+// - if topology key is set and the value not the one of `affinityValidTopologyKeys` - set to `defaultAffinityTopologyKey` (rejected by validateScheduling starting from crVersion 1.8.0)
 // - if topology key set to valuse of `affinityOff` - disable the affinity at all
 // - if `Advanced` affinity is set - leave everything as it is and set topology key to nil (Advanced options has a higher priority)
+// - if `ZoneSpread` is set - set the missing or invalid mode, topology key and weight to the defaults
 func (p *PodSpec) reconcileAffinityOpts() {
 	switch {
 	case p.Affinity == nil:
@@ -758,6 +1831,18 @@ func (p *PodSpec) reconcileAffinityOpts() {
 			p.Affinity.TopologyKey = &defaultAffinityTopologyKey
 		}
 	}
+
+	if zs := p.Affinity.ZoneSpread; zs != nil {
+		if zs.Mode != ZoneSpreadRequired {
+			zs.Mode = ZoneSpreadPreferred
+		}
+		if _, ok := zoneSpreadValidTopologyKeys[zs.TopologyKey]; !ok {
+			zs.TopologyKey = defaultZoneSpreadTopologyKey
+		}
+		if zs.Weight < 1 || zs.Weight > 100 {
+			zs.Weight = defaultZoneSpreadWeight
+		}
+	}
 }
 
 func (v *VolumeSpec) reconcileOpts() (changed bool) {
@@ -789,6 +1874,56 @@ func (v *VolumeSpec) validate() error {
 	return nil
 }
 
+func (v *VaultKVSpec) validate() error {
+	if len(v.Address) == 0 {
+		return errors.New("address can't be empty")
+	}
+	if len(v.Path) == 0 {
+		return errors.New("path can't be empty")
+	}
+	if len(v.Role) == 0 && len(v.TokenSecretName) == 0 {
+		return errors.New("either role or tokenSecretName should be specified")
+	}
+	if v.KVVersion != 0 && v.KVVersion != 1 && v.KVVersion != 2 {
+		return errors.Errorf("unsupported kvVersion %d", v.KVVersion)
+	}
+	return nil
+}
+
+func (v *VaultKVSpec) setDefaults() {
+	if len(v.Mount) == 0 {
+		v.Mount = defaultVaultMount
+	}
+	if len(v.AuthPath) == 0 {
+		v.AuthPath = defaultVaultAuthPath
+	}
+	if v.KVVersion == 0 {
+		v.KVVersion = defaultVaultKVVersion
+	}
+	if v.RefreshInterval <= 0 {
+		v.RefreshInterval = defaultVaultRefreshInterval
+	}
+}
+
+// VaultSecretsProvider returns the vault spec if the system users
+// credentials should be taken from Vault
+func (s *PerconaXtraDBClusterSpec) VaultSecretsProvider() *VaultKVSpec {
+	if s.SecretsProvider == nil {
+		return nil
+	}
+	return s.SecretsProvider.Vault
+}
+
+// UsersSecretName returns the name of the k8s secret with the system users
+// credentials that can be referenced by pods and jobs. If an external secrets
+// provider is used, only the internal secret managed by the operator exists.
+func (s *PerconaXtraDBClusterSpec) UsersSecretName(clusterName string) string {
+	if s.VaultSecretsProvider() != nil {
+		return "internal-" + clusterName
+	}
+	return s.SecretsName
+}
+
 func AddSidecarContainers(logger logr.Logger, existing, sidecars []corev1.Container) []corev1.Container {
 	if len(sidecars) == 0 {
 		return existing
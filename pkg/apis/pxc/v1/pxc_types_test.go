@@ -3,6 +3,7 @@ package v1
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -23,7 +24,7 @@ func TestReconcileAffinity(t *testing.T) {
 			},
 		},
 		{
-			name: "wrong antiAffinityTopologyKey",
+			name: "wrong antiAffinityTopologyKey before crVersion 1.8.0",
 			pod: &PodSpec{
 				Affinity: &PodAffinity{
 					TopologyKey: func(s string) *string { return &s }("beta.kubernetes.io/instance-type"),
@@ -48,6 +49,19 @@ func TestReconcileAffinity(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "valid zone antiAffinityTopologyKey",
+			pod: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("topology.kubernetes.io/zone"),
+				},
+			},
+			desiered: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("topology.kubernetes.io/zone"),
+				},
+			},
+		},
 		{
 			name: "valid antiAffinityTopologyKey with Advanced",
 			pod: &PodSpec{
@@ -66,6 +80,50 @@ func TestReconcileAffinity(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "zoneSpread defaults",
+			pod: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("kubernetes.io/hostname"),
+					ZoneSpread: &ZoneSpread{
+						TopologyKey: "kubernetes.io/hostname",
+					},
+				},
+			},
+			desiered: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("kubernetes.io/hostname"),
+					ZoneSpread: &ZoneSpread{
+						Mode:        ZoneSpreadPreferred,
+						TopologyKey: defaultZoneSpreadTopologyKey,
+						Weight:      100,
+					},
+				},
+			},
+		},
+		{
+			name: "zero zoneSpread weight",
+			pod: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("kubernetes.io/hostname"),
+					ZoneSpread: &ZoneSpread{
+						Mode:        ZoneSpreadPreferred,
+						TopologyKey: defaultZoneSpreadTopologyKey,
+						Weight:      0,
+					},
+				},
+			},
+			desiered: &PodSpec{
+				Affinity: &PodAffinity{
+					TopologyKey: func(s string) *string { return &s }("kubernetes.io/hostname"),
+					ZoneSpread: &ZoneSpread{
+						Mode:        ZoneSpreadPreferred,
+						TopologyKey: defaultZoneSpreadTopologyKey,
+						Weight:      defaultZoneSpreadWeight,
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -75,3 +133,255 @@ func TestReconcileAffinity(t *testing.T) {
 		}
 	}
 }
+
+func TestSetCondition(t *testing.T) {
+	status := PerconaXtraDBClusterStatus{}
+
+	if !status.SetCondition(ClusterCondition{Type: ClusterReady, Status: ConditionFalse, Reason: "PXCNotReady"}) {
+		t.Error("new condition should be reported as changed")
+	}
+	transition := status.FindCondition(ClusterReady).LastTransitionTime
+	if transition.IsZero() {
+		t.Error("LastTransitionTime isn't set for the new condition")
+	}
+
+	if status.SetCondition(ClusterCondition{Type: ClusterReady, Status: ConditionFalse, Reason: "ProxyNotReady", ObservedGeneration: 2}) {
+		t.Error("condition with the same status should not be reported as changed")
+	}
+	c := status.FindCondition(ClusterReady)
+	if c.Reason != "ProxyNotReady" || c.ObservedGeneration != 2 || !c.LastTransitionTime.Equal(&transition) {
+		t.Errorf("unexpected condition: %+v", c)
+	}
+
+	if !status.SetCondition(ClusterCondition{Type: ClusterReady, Status: ConditionTrue}) {
+		t.Error("status change should be reported")
+	}
+	status.SetCondition(ClusterCondition{Type: ClusterPaused, Status: ConditionFalse})
+	if len(status.Conditions) != 2 {
+		t.Fatalf("expected 2 conditions, got %d", len(status.Conditions))
+	}
+
+	status.RemoveCondition(ClusterReady)
+	if status.FindCondition(ClusterReady) != nil || len(status.Conditions) != 1 {
+		t.Errorf("condition wasn't removed: %+v", status.Conditions)
+	}
+}
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	now := time.Date(2021, time.March, 10, 3, 30, 0, 0, time.UTC) // Wednesday
+
+	cases := []struct {
+		name    string
+		windows []MaintenanceWindow
+		open    bool
+		next    time.Time
+	}{
+		{
+			name: "no windows",
+			open: true,
+		},
+		{
+			name:    "inside the window",
+			windows: []MaintenanceWindow{{Schedule: "0 3 * * *", Duration: "1h"}},
+			open:    true,
+		},
+		{
+			name:    "after the window",
+			windows: []MaintenanceWindow{{Schedule: "0 2 * * *", Duration: "1h"}},
+			next:    time.Date(2021, time.March, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "nearest window",
+			windows: []MaintenanceWindow{
+				{Schedule: "0 4 * * 0", Duration: "2h"},
+				{Schedule: "0 22 * * *", Duration: "2h"},
+			},
+			next: time.Date(2021, time.March, 10, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "time zone",
+			windows: []MaintenanceWindow{{Schedule: "0 4 * * *", Duration: "1h", TimeZone: "Europe/Berlin"}},
+			open:    true,
+		},
+	}
+
+	for _, c := range cases {
+		spec := PerconaXtraDBClusterSpec{MaintenanceWindows: c.windows}
+		open, next, err := spec.MaintenanceWindowOpen(now)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if open != c.open {
+			t.Errorf("%s: got open %t, expected %t", c.name, open, c.open)
+		}
+		if !next.Equal(c.next) {
+			t.Errorf("%s: got next window %s, expected %s", c.name, next, c.next)
+		}
+	}
+
+	spec := PerconaXtraDBClusterSpec{MaintenanceWindows: []MaintenanceWindow{{Schedule: "0 4 * * *", Duration: "forever"}}}
+	if _, _, err := spec.MaintenanceWindowOpen(now); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestValidateQueryRules(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules []ProxySQLQueryRule
+		valid bool
+	}{
+		{
+			name: "read/write split",
+			rules: []ProxySQLQueryRule{
+				{RuleID: 100, MatchDigest: "^SELECT.*FOR UPDATE", DestinationHostgroup: 11, Apply: true},
+				{RuleID: 200, MatchDigest: "^SELECT", DestinationHostgroup: 10, Apply: true},
+			},
+			valid: true,
+		},
+		{
+			name:  "schema without destination",
+			rules: []ProxySQLQueryRule{{RuleID: 1, SchemaName: "reports", FlagOut: 5}},
+			valid: true,
+		},
+		{
+			name: "duplicated rule id",
+			rules: []ProxySQLQueryRule{
+				{RuleID: 1, MatchDigest: "^SELECT", DestinationHostgroup: 10},
+				{RuleID: 1, MatchDigest: "^UPDATE", DestinationHostgroup: 11},
+			},
+		},
+		{
+			name:  "no match criteria",
+			rules: []ProxySQLQueryRule{{RuleID: 1, DestinationHostgroup: 10}},
+		},
+		{
+			name:  "PCRE pattern",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchPattern: "^SELECT (?!.*FOR UPDATE)", DestinationHostgroup: 10}},
+			valid: true,
+		},
+		{
+			name:  "unclosed group",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchPattern: "^SELECT (", DestinationHostgroup: 10}},
+		},
+		{
+			name:  "unclosed character class",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchDigest: "^SELECT [a-z", DestinationHostgroup: 10}},
+		},
+		{
+			name:  "trailing backslash",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchDigest: "^SELECT \\", DestinationHostgroup: 10}},
+		},
+		{
+			name:  "escaped brackets",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchDigest: "^SELECT \\( []()] [^]]", DestinationHostgroup: 10}},
+			valid: true,
+		},
+		{
+			name:  "empty pattern",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchPattern: " ", DestinationHostgroup: 10}},
+		},
+		{
+			name:  "unknown hostgroup",
+			rules: []ProxySQLQueryRule{{RuleID: 1, MatchDigest: "^SELECT", DestinationHostgroup: 42}},
+		},
+	}
+
+	for _, c := range cases {
+		err := validateQueryRules(c.rules, ProxySQLHostgroups{})
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	key := func(s string) *string { return &s }
+	cases := []struct {
+		name  string
+		pod   PodSpec
+		valid bool
+	}{
+		{
+			name:  "region antiAffinityTopologyKey",
+			pod:   PodSpec{Affinity: &PodAffinity{TopologyKey: key("topology.kubernetes.io/region")}},
+			valid: true,
+		},
+		{
+			name: "wrong antiAffinityTopologyKey",
+			pod:  PodSpec{Affinity: &PodAffinity{TopologyKey: key("beta.kubernetes.io/instance-type")}},
+		},
+		{
+			name: "wrong antiAffinityTopologyKey with Advanced",
+			pod: PodSpec{Affinity: &PodAffinity{
+				TopologyKey: key("beta.kubernetes.io/instance-type"),
+				Advanced:    &corev1.Affinity{},
+			}},
+			valid: true,
+		},
+		{
+			name: "wrong zoneSpread topologyKey",
+			pod: PodSpec{Affinity: &PodAffinity{
+				ZoneSpread: &ZoneSpread{TopologyKey: "kubernetes.io/hostname"},
+			}},
+		},
+		{
+			name: "zoneSpread without weight",
+			pod: PodSpec{Affinity: &PodAffinity{
+				ZoneSpread: &ZoneSpread{Mode: ZoneSpreadPreferred},
+			}},
+			valid: true,
+		},
+		{
+			name: "zero zoneSpread weight means the default",
+			pod: PodSpec{Affinity: &PodAffinity{
+				ZoneSpread: &ZoneSpread{Mode: ZoneSpreadPreferred, Weight: 0},
+			}},
+			valid: true,
+		},
+		{
+			name: "negative zoneSpread weight",
+			pod: PodSpec{Affinity: &PodAffinity{
+				ZoneSpread: &ZoneSpread{Weight: -1},
+			}},
+		},
+		{
+			name: "too big zoneSpread weight",
+			pod: PodSpec{Affinity: &PodAffinity{
+				ZoneSpread: &ZoneSpread{Weight: 101},
+			}},
+		},
+		{
+			name: "spread constraint",
+			pod: PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.DoNotSchedule},
+			}},
+			valid: true,
+		},
+		{
+			name: "spread constraint without maxSkew",
+			pod: PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.ScheduleAnyway},
+			}},
+		},
+		{
+			name: "spread constraint without whenUnsatisfiable",
+			pod: PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone"},
+			}},
+		},
+	}
+
+	for _, c := range cases {
+		err := c.pod.validateScheduling()
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
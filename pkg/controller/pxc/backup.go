@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -131,7 +132,11 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileBackups(cr *api.PerconaXtraDBCl
 				}
 
 				for _, todel := range oldjobs {
-					_ = r.client.Delete(context.TODO(), &todel)
+					err = r.client.Delete(context.TODO(), &todel)
+					if err == nil {
+						r.recorder.Eventf(cr, corev1.EventTypeNormal, EventBackupPruned,
+							"Backup %s is deleted to keep the last %d backups of schedule %s", todel.Name, spec.Keep, spec.Name)
+					}
 				}
 			}
 		} else {
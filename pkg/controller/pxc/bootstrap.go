@@ -0,0 +1,121 @@
+package pxc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/queries"
+)
+
+const (
+	// BootstrapFromAnnotation on the cluster says to bootstrap the cluster from
+	// the given pod instead of the pod with the highest seqno. The annotation
+	// is removed by the operator once the cluster is Primary again.
+	BootstrapFromAnnotation = "percona.com/bootstrap-from"
+	// BootstrapForceAnnotation set to "true" allows to bootstrap from the pod
+	// that is behind the other pods. Transactions missing on it will be lost.
+	BootstrapForceAnnotation = "percona.com/bootstrap-force"
+)
+
+// pxcDB connects to the PXC pod as root
+func (r *ReconcilePerconaXtraDBCluster) pxcDB(cr *api.PerconaXtraDBCluster, podName string, opts ...queries.Option) (queries.Database, error) {
+	user := "root"
+	secrets := cr.Spec.UsersSecretName(cr.Name)
+	port := int32(3306)
+	if cr.CompareVersionWith("1.6.0") >= 0 {
+		secrets = "internal-" + cr.Name
+		port = int32(33062)
+	}
+
+	return queries.New(credentials.NewSecret(r.client, cr.Namespace, secrets), user, podName+"."+cr.Name+"-pxc."+cr.Namespace, port, opts...)
+}
+
+// reconcileBootstrap finishes the bootstrap requested by BootstrapFromAnnotation.
+// The bootstrap itself is started by the full crash recovery. When the chosen
+// pod is Primary, the rest of the pods are restarted one by one to rejoin it
+// and the annotations are removed after all pods are ready.
+func (r *ReconcilePerconaXtraDBCluster) reconcileBootstrap(cr *api.PerconaXtraDBCluster) error {
+	bootstrapPod := cr.Annotations[BootstrapFromAnnotation]
+
+	state, err := r.recoveryState(cr, bootstrapPod)
+	if err != nil {
+		return errors.Wrapf(err, "get %s pod recovery state", bootstrapPod)
+	}
+	if state != nil {
+		// the bootstrap isn't started yet
+		return nil
+	}
+
+	database, err := r.pxcDB(cr, bootstrapPod)
+	if err != nil {
+		// the pod is bootstrapping
+		return nil
+	}
+	status, err := database.WsrepStatus()
+	database.Close()
+	if err != nil {
+		return errors.Wrapf(err, "get %s pod wsrep status", bootstrapPod)
+	}
+	if status["wsrep_cluster_status"] != "Primary" || status["wsrep_local_state_comment"] != "Synced" {
+		return nil
+	}
+
+	for i := 0; i < int(cr.Spec.PXC.Size); i++ {
+		podName := fmt.Sprintf("%s-pxc-%d", cr.Name, i)
+		if podName == bootstrapPod {
+			continue
+		}
+
+		pod := &corev1.Pod{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, pod)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "get pod %s", podName)
+		}
+		if isContainersReady(*pod) {
+			continue
+		}
+
+		state, err := r.recoveryState(cr, podName)
+		if err != nil {
+			return errors.Wrapf(err, "get %s pod recovery state", podName)
+		}
+		if state == nil {
+			// the pod is joining the cluster, wait for it before the next one
+			return nil
+		}
+
+		err = r.client.Delete(context.TODO(), pod)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete pod %s", podName)
+		}
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventPodRestarted, "Pod %s restarted to rejoin the cluster bootstrapped from %s", podName, bootstrapPod)
+
+		return nil
+	}
+
+	// patch a copy, the response would overwrite the defaults set in cr
+	obj := cr.DeepCopy()
+	patch := client.MergeFrom(obj.DeepCopy())
+	delete(obj.Annotations, BootstrapFromAnnotation)
+	delete(obj.Annotations, BootstrapForceAnnotation)
+	err = r.client.Patch(context.TODO(), obj, patch)
+	if err != nil {
+		return errors.Wrap(err, "remove bootstrap annotations")
+	}
+	cr.Annotations = obj.Annotations
+	cr.ResourceVersion = obj.ResourceVersion
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventBootstrapFinished, "Cluster was bootstrapped from pod %s and all pods rejoined it", bootstrapPod)
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package pxc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+// canaryLogTailLines limits the log read from the canary pod on each check
+const canaryLogTailLines = 5000
+
+func canaryEnabled(cr *api.PerconaXtraDBCluster) bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 &&
+		cr.Spec.UpgradeOptions.Canary != nil && cr.Spec.UpgradeOptions.Canary.Enabled
+}
+
+// canaryStage updates the canary pod and checks it until the soak time is
+// over. The state is kept in the CR status between reconciles. It returns
+// true when the rest of pods can be updated.
+func (r *ReconcilePerconaXtraDBCluster) canaryStage(cr *api.PerconaXtraDBCluster, sfs *appsv1.StatefulSet, secondaries []corev1.Pod, waitLimit int) bool {
+	logger := r.logger(cr.Name, cr.Namespace)
+	spec := cr.Spec.UpgradeOptions.Canary
+	revision := sfs.Status.UpdateRevision
+
+	status := cr.Status.Canary
+	if status == nil || status.Revision != revision {
+		if len(secondaries) == 0 {
+			return true
+		}
+
+		pod := secondaries[0]
+		status = &api.CanaryStatus{
+			Revision: revision,
+			Pod:      pod.Name,
+		}
+		cr.Status.Canary = status
+
+		logger.Info("apply changes to canary pod", "pod name", pod.Name)
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventCanaryStarted, "Applying revision %s to canary pod %s", revision, pod.Name)
+		if err := r.applyNWait(cr, sfs, &pod, waitLimit); err != nil {
+			r.failCanary(cr, status, fmt.Sprintf("apply changes: %v", err))
+			return false
+		}
+
+		now := metav1.Now()
+		status.StartedAt = &now
+		status.State = api.CanaryStateSoaking
+	}
+
+	switch status.State {
+	case api.CanaryStatePassed:
+		return true
+	case api.CanaryStateFailed:
+		logger.Info("can't continue 'SmartUpdate': canary failed", "pod name", status.Pod, "reason", status.Message)
+		return false
+	}
+
+	if msg := r.canaryChecks(cr, status, spec); canaryCheckFailed(status, spec, msg) {
+		r.failCanary(cr, status, msg)
+		return false
+	} else if len(msg) > 0 {
+		logger.Info("canary check failed", "pod name", status.Pod, "failures", status.Failures, "reason", msg)
+		status.Message = fmt.Sprintf("check failed %d of %d times: %s", status.Failures, spec.FailureThreshold, msg)
+		return false
+	}
+
+	soak := time.Duration(spec.SoakSeconds) * time.Second
+	if status.StartedAt != nil && time.Since(status.StartedAt.Time) < soak {
+		status.Message = fmt.Sprintf("soaking until %s", status.StartedAt.Add(soak).UTC().Format(time.RFC3339))
+		return false
+	}
+
+	if spec.RequireApproval && cr.Annotations[api.CanaryApprovalAnnotation] != revision {
+		if status.State != api.CanaryStateWaitingApproval {
+			status.State = api.CanaryStateWaitingApproval
+			status.Message = fmt.Sprintf("set annotation %s=%s to continue", api.CanaryApprovalAnnotation, revision)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventCanaryWaitingApproval,
+				"Canary pod %s passed the checks, waiting for approval of revision %s", status.Pod, revision)
+		}
+		return false
+	}
+
+	status.State = api.CanaryStatePassed
+	status.Message = ""
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventCanaryPassed, "Canary pod %s passed, applying revision %s to the rest of pods", status.Pod, revision)
+
+	return true
+}
+
+// canaryCheckFailed counts the consecutive failed checks of the canary pod.
+// It returns true if the checks failed FailureThreshold times in a row,
+// so the canary isn't failed because of a short hiccup.
+func canaryCheckFailed(status *api.CanaryStatus, spec *api.CanarySpec, msg string) bool {
+	if len(msg) == 0 {
+		status.Failures = 0
+		return false
+	}
+
+	status.Failures++
+	return status.Failures >= spec.FailureThreshold
+}
+
+func (r *ReconcilePerconaXtraDBCluster) failCanary(cr *api.PerconaXtraDBCluster, status *api.CanaryStatus, msg string) {
+	status.State = api.CanaryStateFailed
+	status.Message = msg
+	r.recorder.Eventf(cr, corev1.EventTypeWarning, EventCanaryFailed,
+		"Canary pod %s failed, revision %s isn't applied to the rest of pods: %s", status.Pod, status.Revision, msg)
+}
+
+// canaryChecks runs the health gates on the canary pod.
+// It returns the reason of the failure or an empty string.
+func (r *ReconcilePerconaXtraDBCluster) canaryChecks(cr *api.PerconaXtraDBCluster, status *api.CanaryStatus, spec *api.CanarySpec) string {
+	database, err := r.pxcDB(cr, status.Pod)
+	if err != nil {
+		return fmt.Sprintf("connect: %v", err)
+	}
+	defer database.Close()
+
+	state, err := database.WsrepLocalStateComment()
+	if err != nil {
+		return fmt.Sprintf("get wsrep state: %v", err)
+	}
+	if state != "Synced" {
+		return "node isn't synced: " + state
+	}
+
+	for _, check := range spec.Checks {
+		ok, err := database.Check(check.Query)
+		if err != nil {
+			return fmt.Sprintf("check %s: %v", check.Name, err)
+		}
+		if !ok {
+			return fmt.Sprintf("check %s failed", check.Name)
+		}
+	}
+
+	errorLines, err := r.errorLogLines(cr, status.Pod, status.StartedAt)
+	if err != nil {
+		return err.Error()
+	}
+	if spec.MaxErrorLogLines != nil && errorLines > int(*spec.MaxErrorLogLines) {
+		return fmt.Sprintf("%d errors in the log, %d allowed", errorLines, *spec.MaxErrorLogLines)
+	}
+
+	return ""
+}
+
+// errorLogLines counts the errors logged by mysqld since the canary pod was
+// updated. Only the last canaryLogTailLines lines are read.
+func (r *ReconcilePerconaXtraDBCluster) errorLogLines(cr *api.PerconaXtraDBCluster, podName string, since *metav1.Time) (int, error) {
+	logFile := mysqldErrorLog(cr)
+	if len(logFile) == 0 {
+		tailLines := int64(canaryLogTailLines)
+		lines, err := r.clientcmd.PodLogs(cr.Namespace, podName, &corev1.PodLogOptions{
+			Container: "pxc",
+			SinceTime: since,
+			TailLines: &tailLines,
+		})
+		if err != nil {
+			return 0, errors.Wrapf(err, "get logs from %s pod", podName)
+		}
+		return countErrorLines(lines, since), nil
+	}
+
+	pod := &corev1.Pod{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, pod)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get pod %s", podName)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = r.clientcmd.Exec(pod, "pxc", []string{"tail", "-n", strconv.Itoa(canaryLogTailLines), logFile}, nil, &stdout, &stderr, false)
+	if err != nil {
+		return 0, errors.Wrapf(err, "read %s in %s: %s", logFile, podName, stderr.String())
+	}
+
+	return countErrorLines(strings.Split(stdout.String(), "\n"), since), nil
+}
+
+// mysqldErrorLog returns the error log file of mysqld. It is set by
+// pxc-configure-pxc.sh if the log collector is enabled, otherwise mysqld
+// logs to the pxc container output and an empty string is returned.
+func mysqldErrorLog(cr *api.PerconaXtraDBCluster) string {
+	if cr.Spec.LogCollector == nil || !cr.Spec.LogCollector.Enabled || cr.CompareVersionWith("1.7.0") < 0 {
+		return ""
+	}
+	return "/var/lib/mysql/mysqld-error.log"
+}
+
+// countErrorLines counts the [ERROR] lines logged since the given time.
+// The lines without a timestamp are counted as well.
+func countErrorLines(lines []string, since *metav1.Time) int {
+	count := 0
+	for _, line := range lines {
+		if !strings.Contains(line, "[ERROR]") {
+			continue
+		}
+		if since != nil {
+			ts, err := time.Parse(time.RFC3339Nano, strings.SplitN(line, " ", 2)[0])
+			if err == nil && ts.Before(since.Time) {
+				continue
+			}
+		}
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,64 @@
+package pxc
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestCanaryCheckFailed(t *testing.T) {
+	spec := &api.CanarySpec{FailureThreshold: 3}
+
+	status := &api.CanaryStatus{}
+	for i, tt := range []struct {
+		msg      string
+		failed   bool
+		failures int32
+	}{
+		{"node isn't synced: Donor/Desynced", false, 1},
+		{"node isn't synced: Donor/Desynced", false, 2},
+		{"", false, 0},
+		{"check no-long-transactions failed", false, 1},
+		{"check no-long-transactions failed", false, 2},
+		{"check no-long-transactions failed", true, 3},
+	} {
+		if failed := canaryCheckFailed(status, spec, tt.msg); failed != tt.failed {
+			t.Fatalf("check %d: expected failed=%v, got %v", i, tt.failed, failed)
+		}
+		if status.Failures != tt.failures {
+			t.Fatalf("check %d: expected %d failures, got %d", i, tt.failures, status.Failures)
+		}
+	}
+}
+
+func TestCanaryErrorLogLines(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{
+		Spec: api.PerconaXtraDBClusterSpec{
+			CRVersion:    "1.8.0",
+			LogCollector: &api.LogCollectorSpec{Enabled: true},
+		},
+	}
+	if f := mysqldErrorLog(cr); f != "/var/lib/mysql/mysqld-error.log" {
+		t.Errorf("expected the errors to be read from the log collector file, got %q", f)
+	}
+	cr.Spec.LogCollector.Enabled = false
+	if f := mysqldErrorLog(cr); f != "" {
+		t.Errorf("expected the errors to be read from the container log, got %q", f)
+	}
+
+	// the tail of mysqld-error.log
+	lines := []string{
+		"2021-03-01T10:00:00.000000Z 0 [ERROR] [MY-000000] [Galera] before the update",
+		"2021-03-01T10:05:00.000000Z 0 [Note] [MY-000000] [Galera] Synchronized with group",
+		"2021-03-01T10:05:01.000000Z 0 [ERROR] [MY-010584] [Repl] Slave SQL: Error",
+		"2021-03-01T10:05:02.000000Z 12 [ERROR] [MY-011825] [InnoDB] Operating system error",
+		"",
+	}
+	since := metav1.NewTime(time.Date(2021, 3, 1, 10, 5, 0, 0, time.UTC))
+	if n := countErrorLines(lines, &since); n != 2 {
+		t.Errorf("expected 2 errors since the update, got %d", n)
+	}
+}
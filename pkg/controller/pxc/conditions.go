@@ -0,0 +1,368 @@
+package pxc
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/deployment"
+)
+
+// conditionTypes is the list of the condition types managed by the operator.
+// Conditions of any other type are dropped from the status.
+var conditionTypes = []api.ClusterConditionType{
+	api.ClusterReady,
+	api.ClusterPXCReady,
+	api.ClusterProxyReady,
+	api.ClusterBackupsHealthy,
+	api.ClusterPITRHealthy,
+	api.ClusterTLSValid,
+	api.ClusterUpgradeInProgress,
+	api.ClusterPaused,
+	api.ClusterRestoreInProgress,
+	api.ClusterFullCrashRecovery,
+	api.ClusterConfigurationValid,
+	api.ClusterUpgradeRolledBack,
+	api.ClusterProxySQLConfigured,
+	api.ClusterTopologyAvailable,
+	api.ClusterReconciled,
+	api.ClusterInit,
+	api.ClusterError,
+	api.ClusterProxySQLReady,
+	api.ClusterHAProxyReady,
+}
+
+// deprecatedConditions are kept for the tools written for the previous
+// operator versions. They duplicate the new conditions, so no events
+// are emitted for them.
+var deprecatedConditions = map[api.ClusterConditionType]bool{
+	api.ClusterInit:          true,
+	api.ClusterError:         true,
+	api.ClusterProxySQLReady: true,
+	api.ClusterHAProxyReady:  true,
+}
+
+// healthyWhenTrue holds the conditions that signal a problem when they become False
+var healthyWhenTrue = map[api.ClusterConditionType]bool{
+	api.ClusterReady:              true,
+	api.ClusterPXCReady:           true,
+	api.ClusterProxyReady:         true,
+	api.ClusterBackupsHealthy:     true,
+	api.ClusterPITRHealthy:        true,
+	api.ClusterTLSValid:           true,
+	api.ClusterConfigurationValid: true,
+	api.ClusterProxySQLConfigured: true,
+	api.ClusterTopologyAvailable:  true,
+	api.ClusterReconciled:         true,
+}
+
+// normalizeConditions drops the conditions of unknown types (e.g. left by
+// the previous versions of the operator that kept the history of the states)
+// and keeps only the last condition of every type
+func normalizeConditions(status *api.PerconaXtraDBClusterStatus) {
+	last := make(map[api.ClusterConditionType]api.ClusterCondition, len(conditionTypes))
+	for _, c := range status.Conditions {
+		last[c.Type] = c
+	}
+
+	conditions := make([]api.ClusterCondition, 0, len(conditionTypes))
+	for _, t := range conditionTypes {
+		if c, ok := last[t]; ok {
+			conditions = append(conditions, c)
+		}
+	}
+	status.Conditions = conditions
+}
+
+func boolCondition(t api.ClusterConditionType, ok bool, reason, message string) api.ClusterCondition {
+	status := api.ConditionStatus(api.ConditionFalse)
+	if ok {
+		status = api.ConditionTrue
+	}
+	return api.ClusterCondition{
+		Type:    t,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// setCondition updates the condition in the CR status and emits an event if
+// the condition status was changed, so the history of changes is kept in events
+func (r *ReconcilePerconaXtraDBCluster) setCondition(cr *api.PerconaXtraDBCluster, c api.ClusterCondition) {
+	c.ObservedGeneration = cr.Generation
+	existed := cr.Status.FindCondition(c.Type) != nil
+	if !cr.Status.SetCondition(c) || deprecatedConditions[c.Type] {
+		return
+	}
+	// don't flood events with the initial state of the idle conditions
+	if !existed && c.Status != api.ConditionTrue {
+		return
+	}
+
+	eventType := corev1.EventTypeNormal
+	if healthyWhenTrue[c.Type] && c.Status != api.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+
+	msg := fmt.Sprintf("Condition %s changed to %s", c.Type, c.Status)
+	if len(c.Reason) > 0 {
+		msg += ", reason: " + c.Reason
+	}
+	if len(c.Message) > 0 {
+		msg += ": " + c.Message
+	}
+	r.recorder.Event(cr, eventType, string(c.Type), msg)
+}
+
+func appReadyCondition(t api.ClusterConditionType, name string, status api.AppStatus) api.ClusterCondition {
+	if status.Status == api.AppStateReady {
+		return boolCondition(t, true, "AllPodsReady", "")
+	}
+	return boolCondition(t, false, name+"NotReady",
+		fmt.Sprintf("%d of %d pods are ready. %s", status.Ready, status.Size, status.Message))
+}
+
+func (r *ReconcilePerconaXtraDBCluster) backupsCondition(cr *api.PerconaXtraDBCluster) (api.ClusterCondition, error) {
+	list := api.PerconaXtraDBClusterBackupList{}
+	err := r.client.List(context.TODO(), &list, &client.ListOptions{Namespace: cr.Namespace})
+	if err != nil {
+		return api.ClusterCondition{}, errors.Wrap(err, "list backups")
+	}
+
+	var latest *api.PerconaXtraDBClusterBackup
+	for i := range list.Items {
+		b := &list.Items[i]
+		if b.Spec.PXCCluster != cr.Name {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&b.CreationTimestamp) {
+			latest = b
+		}
+	}
+
+	if latest == nil {
+		return boolCondition(api.ClusterBackupsHealthy, true, "NoBackups", ""), nil
+	}
+	if latest.Status.State == api.BackupFailed {
+		return boolCondition(api.ClusterBackupsHealthy, false, "BackupFailed",
+			fmt.Sprintf("latest backup %s failed", latest.Name)), nil
+	}
+
+	return boolCondition(api.ClusterBackupsHealthy, true, "LatestBackupNotFailed", ""), nil
+}
+
+func (r *ReconcilePerconaXtraDBCluster) pitrCondition(cr *api.PerconaXtraDBCluster) (api.ClusterCondition, error) {
+	collector := appsv1.Deployment{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: deployment.GetBinlogCollectorDeploymentName(cr), Namespace: cr.Namespace}, &collector)
+	if k8serrors.IsNotFound(err) {
+		return boolCondition(api.ClusterPITRHealthy, false, "BinlogCollectorNotFound", ""), nil
+	}
+	if err != nil {
+		return api.ClusterCondition{}, errors.Wrap(err, "get binlog collector deployment")
+	}
+
+	if collector.Status.AvailableReplicas == 0 {
+		return boolCondition(api.ClusterPITRHealthy, false, "BinlogCollectorUnavailable", ""), nil
+	}
+
+	return boolCondition(api.ClusterPITRHealthy, true, "BinlogCollectorAvailable", ""), nil
+}
+
+// tlsCondition checks the certificate in the PXC SSL secret.
+// It returns nil if TLS isn't used.
+func (r *ReconcilePerconaXtraDBCluster) tlsCondition(cr *api.PerconaXtraDBCluster) (*api.ClusterCondition, error) {
+	secret := corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: cr.Spec.PXC.SSLSecretName, Namespace: cr.Namespace}, &secret)
+	if k8serrors.IsNotFound(err) {
+		if cr.Spec.AllowUnsafeConfig {
+			return nil, nil
+		}
+		c := boolCondition(api.ClusterTLSValid, false, "SecretNotFound", "secret "+cr.Spec.PXC.SSLSecretName+" not found")
+		return &c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get ssl secret")
+	}
+
+	block, _ := pem.Decode(secret.Data["tls.crt"])
+	if block == nil {
+		c := boolCondition(api.ClusterTLSValid, false, "InvalidCertificate", "no PEM data found in tls.crt")
+		return &c, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		c := boolCondition(api.ClusterTLSValid, false, "InvalidCertificate", err.Error())
+		return &c, nil
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		c := boolCondition(api.ClusterTLSValid, false, "CertificateExpired", "certificate expired at "+cert.NotAfter.UTC().Format(time.RFC3339))
+		return &c, nil
+	}
+
+	c := boolCondition(api.ClusterTLSValid, true, "CertificateValid", "certificate expires at "+cert.NotAfter.UTC().Format(time.RFC3339))
+	return &c, nil
+}
+
+func (r *ReconcilePerconaXtraDBCluster) restoreCondition(cr *api.PerconaXtraDBCluster) (api.ClusterCondition, error) {
+	list := api.PerconaXtraDBClusterRestoreList{}
+	err := r.client.List(context.TODO(), &list, &client.ListOptions{Namespace: cr.Namespace})
+	if err != nil {
+		return api.ClusterCondition{}, errors.Wrap(err, "list restores")
+	}
+
+	for _, rs := range list.Items {
+		if rs.Spec.PXCCluster != cr.Name {
+			continue
+		}
+		switch rs.Status.State {
+		case api.RestoreNew, api.RestoreFailed, api.RestoreSucceeded:
+			continue
+		}
+		return boolCondition(api.ClusterRestoreInProgress, true, "Restoring",
+			fmt.Sprintf("restore %s is in state %s", rs.Name, rs.Status.State)), nil
+	}
+
+	return boolCondition(api.ClusterRestoreInProgress, false, "NoActiveRestore", ""), nil
+}
+
+func (r *ReconcilePerconaXtraDBCluster) fullCrashCondition(cr *api.PerconaXtraDBCluster) (api.ClusterCondition, error) {
+	if cr.Spec.PXC.Size <= 0 || cr.Status.PXC.Status == api.AppStateReady || cr.CompareVersionWith("1.7.0") < 0 {
+		return boolCondition(api.ClusterFullCrashRecovery, false, "NoFullCrash", ""), nil
+	}
+
+	err := r.checkIfPodsRunning(cr)
+	if err == ErrNotAllPXCPodsRunning {
+		return boolCondition(api.ClusterFullCrashRecovery, false, "NoFullCrash", ""), nil
+	}
+	if err != nil {
+		return api.ClusterCondition{}, err
+	}
+
+	state, err := r.recoveryState(cr, cr.Name+"-pxc-0")
+	if err != nil {
+		return api.ClusterCondition{
+			Type:    api.ClusterFullCrashRecovery,
+			Status:  api.ConditionUnknown,
+			Reason:  "RecoveryStateUnknown",
+			Message: err.Error(),
+		}, nil
+	}
+	if state != nil && state.NoAgent {
+		return boolCondition(api.ClusterFullCrashRecovery, true, "WaitingForRecoveryWithoutAgent",
+			"pxc pods are waiting for the full cluster crash recovery, the recovery agent isn't running in "+state.Node+
+				", its state is read from the logs"), nil
+	}
+	if state != nil {
+		return boolCondition(api.ClusterFullCrashRecovery, true, "WaitingForRecovery", "pxc pods are waiting for the full cluster crash recovery"), nil
+	}
+
+	return boolCondition(api.ClusterFullCrashRecovery, false, "NoFullCrash", ""), nil
+}
+
+// updateConditions sets all the cluster conditions. It expects the app
+// statuses to be already filled in.
+func (r *ReconcilePerconaXtraDBCluster) updateConditions(cr *api.PerconaXtraDBCluster, upgradeInProgress bool) error {
+	normalizeConditions(&cr.Status)
+
+	r.setCondition(cr, appReadyCondition(api.ClusterPXCReady, "PXC", cr.Status.PXC))
+
+	proxyReady := true
+	switch {
+	case cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled:
+		c := appReadyCondition(api.ClusterProxyReady, "HAProxy", cr.Status.HAProxy)
+		proxyReady = c.Status == api.ConditionTrue
+		r.setCondition(cr, c)
+		c.Type = api.ClusterHAProxyReady
+		r.setCondition(cr, c)
+		cr.Status.RemoveCondition(api.ClusterProxySQLReady)
+	case cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled:
+		c := appReadyCondition(api.ClusterProxyReady, "ProxySQL", cr.Status.ProxySQL)
+		proxyReady = c.Status == api.ConditionTrue
+		r.setCondition(cr, c)
+		c.Type = api.ClusterProxySQLReady
+		r.setCondition(cr, c)
+		cr.Status.RemoveCondition(api.ClusterHAProxyReady)
+	default:
+		cr.Status.RemoveCondition(api.ClusterProxyReady)
+		cr.Status.RemoveCondition(api.ClusterHAProxyReady)
+		cr.Status.RemoveCondition(api.ClusterProxySQLReady)
+	}
+
+	r.setCondition(cr, boolCondition(api.ClusterInit, cr.Status.Status == api.AppStateInit, string(cr.Status.Status), ""))
+	r.setCondition(cr, boolCondition(api.ClusterError, cr.Status.Status == api.AppStateError, string(cr.Status.Status), ""))
+
+	switch {
+	case cr.Status.PXC.Status != api.AppStateReady:
+		r.setCondition(cr, boolCondition(api.ClusterReady, false, "PXCNotReady", ""))
+	case !proxyReady:
+		r.setCondition(cr, boolCondition(api.ClusterReady, false, "ProxyNotReady", ""))
+	default:
+		r.setCondition(cr, boolCondition(api.ClusterReady, true, "AllComponentsReady", ""))
+	}
+
+	r.setCondition(cr, boolCondition(api.ClusterReconciled, true, "ReconcileSucceeded", ""))
+
+	if upgradeInProgress {
+		r.setCondition(cr, boolCondition(api.ClusterUpgradeInProgress, true, "StatefulSetUpdating", ""))
+	} else {
+		r.setCondition(cr, boolCondition(api.ClusterUpgradeInProgress, false, "StatefulSetsUpdated", ""))
+	}
+
+	if cr.Spec.Pause {
+		r.setCondition(cr, boolCondition(api.ClusterPaused, true, "PausedBySpec", ""))
+	} else {
+		r.setCondition(cr, boolCondition(api.ClusterPaused, false, "NotPaused", ""))
+	}
+
+	c, err := r.backupsCondition(cr)
+	if err != nil {
+		return errors.Wrap(err, "backups condition")
+	}
+	r.setCondition(cr, c)
+
+	if cr.Spec.Backup != nil && cr.Spec.Backup.PITR.Enabled {
+		c, err = r.pitrCondition(cr)
+		if err != nil {
+			return errors.Wrap(err, "pitr condition")
+		}
+		r.setCondition(cr, c)
+	} else {
+		cr.Status.RemoveCondition(api.ClusterPITRHealthy)
+	}
+
+	tlsc, err := r.tlsCondition(cr)
+	if err != nil {
+		return errors.Wrap(err, "tls condition")
+	}
+	if tlsc != nil {
+		r.setCondition(cr, *tlsc)
+	} else {
+		cr.Status.RemoveCondition(api.ClusterTLSValid)
+	}
+
+	c, err = r.restoreCondition(cr)
+	if err != nil {
+		return errors.Wrap(err, "restore condition")
+	}
+	r.setCondition(cr, c)
+
+	c, err = r.fullCrashCondition(cr)
+	if err != nil {
+		return errors.Wrap(err, "full crash recovery condition")
+	}
+	r.setCondition(cr, c)
+
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -33,9 +34,11 @@ import (
 	"github.com/percona/percona-xtradb-cluster-operator/clientcmd"
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/k8s"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
 	"github.com/percona/percona-xtradb-cluster-operator/version"
 )
 
@@ -61,12 +64,17 @@ func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
 		return nil, errors.Wrap(err, "create clientcmd")
 	}
 	return &ReconcilePerconaXtraDBCluster{
-		client:        mgr.GetClient(),
-		scheme:        mgr.GetScheme(),
-		crons:         NewCronRegistry(),
-		serverVersion: sv,
-		clientcmd:     cli,
-		lockers:       newLockStore(),
+		client:         mgr.GetClient(),
+		apiReader:      mgr.GetAPIReader(),
+		scheme:         mgr.GetScheme(),
+		crons:          NewCronRegistry(),
+		serverVersion:  sv,
+		clientcmd:      cli,
+		lockers:        newLockStore(),
+		vaults:         credentials.NewCache(),
+		nodeZones:      new(sync.Map),
+		membersChecked: new(sync.Map),
+		recorder:       mgr.GetEventRecorderFor("perconaxtradbcluster-controller"),
 	}, nil
 }
 
@@ -94,12 +102,17 @@ type ReconcilePerconaXtraDBCluster struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client         client.Client
+	apiReader      client.Reader
 	scheme         *runtime.Scheme
 	crons          CronRegistry
 	clientcmd      *clientcmd.Client
 	syncUsersState int32
 	serverVersion  *version.ServerVersion
 	lockers        lockStore
+	vaults         credentials.Cache
+	nodeZones      *sync.Map
+	membersChecked *sync.Map
+	recorder       record.EventRecorder
 }
 
 func (r *ReconcilePerconaXtraDBCluster) logger(name, namespace string) logr.Logger {
@@ -167,7 +180,16 @@ func NewCronRegistry() CronRegistry {
 // Note:
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
-func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (_ reconcile.Result, rerr error) {
+	// the series of the deleted cluster must not be created again
+	deleted := false
+	defer func(start time.Time) {
+		if deleted {
+			return
+		}
+		metrics.ObserveReconcile("pxc", request.Name, request.Namespace, start, rerr)
+	}(time.Now())
+
 	rr := reconcile.Result{
 		RequeueAfter: time.Second * 5,
 	}
@@ -192,7 +214,12 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 		if k8serrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
-			return rr, nil
+			r.vaults.Delete(request.Namespace, request.Name)
+			r.membersChecked.Delete(request.Namespace + "/" + request.Name)
+			r.deleteNodeZones(request.Namespace, request.Name)
+			metrics.DeleteCluster(request.Name, request.Namespace)
+			deleted = true
+			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
@@ -218,17 +245,29 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 		}
 	}()
 
-	if o.CompareVersionWith("1.7.0") >= 0 && *o.Spec.PXC.AutoRecovery {
+	_, manualBootstrap := o.Annotations[BootstrapFromAnnotation]
+	if o.CompareVersionWith("1.7.0") >= 0 && (*o.Spec.PXC.AutoRecovery || manualBootstrap) {
 		err = r.recoverFullClusterCrashIfNeeded(o)
 		if err != nil {
 			reqLogger.Error(err, "Failed to check if cluster needs to recover")
 		}
 	}
 
+	if manualBootstrap {
+		err = r.reconcileBootstrap(o)
+		if err != nil {
+			reqLogger.Error(err, "Failed to finish the bootstrap")
+		}
+	}
+
 	err = r.reconcileUsersSecret(o)
 	if err != nil {
 		return reconcile.Result{}, errors.Wrap(err, "reconcile users secret")
 	}
+	err = r.reconcileRecoveryAgentSecret(o)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "reconcile recovery agent secret")
+	}
 	var pxcAnnotations, proxysqlAnnotations map[string]string
 	if o.CompareVersionWith("1.5.0") >= 0 {
 		pxcAnnotations, proxysqlAnnotations, err = r.reconcileUsers(o)
@@ -248,12 +287,27 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 	}
 
 	if o.Status.PXC.Version == "" || strings.HasSuffix(o.Status.PXC.Version, "intermediate") {
-		err := r.ensurePXCVersion(o, VersionServiceClient{OpVersion: o.Version().String()})
+		err := r.ensurePXCVersion(o, r.versionService(o))
 		if err != nil {
 			reqLogger.Info("failed to ensure version, running with default", "error", err)
 		}
 	}
 
+	// the approved plan is applied right away, not on the next schedule.
+	// statusMutex is held till the end of Reconcile, so the version service
+	// cron job can't change the CR meanwhile
+	if p := o.Status.UpgradePlan; p != nil && o.Annotations[api.UpgradePlanApprovalAnnotation] == p.ID {
+		err := r.ensurePXCVersion(o, r.versionService(o))
+		if err != nil {
+			reqLogger.Info("failed to apply upgrade plan", "plan", p.ID, "error", err)
+		}
+		// the CR is read again by ensurePXCVersion
+		_, err = o.CheckNSetDefaults(r.serverVersion, reqLogger)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "wrong PXC options")
+		}
+	}
+
 	if o.ObjectMeta.DeletionTimestamp != nil {
 		finalizers := []string{}
 		for _, fnlz := range o.GetFinalizers() {
@@ -284,6 +338,16 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 		return rr, err
 	}
 
+	err = r.reconcileVolumeExpansion(o)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "reconcile volume expansion")
+	}
+
+	err = r.reconcileTopology(o)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "reconcile topology")
+	}
+
 	err = r.deploy(o)
 	if err != nil {
 		return reconcile.Result{}, err
@@ -398,6 +462,10 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 			)
 		}
 
+		if o.HAProxyBackendsManaged() {
+			currentService.Spec.Ports = append(currentService.Spec.Ports, pxc.HAProxyListenerPorts(o)...)
+		}
+
 		err = r.client.Update(context.TODO(), currentService)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, "HAProxy service upgrade error")
@@ -463,6 +531,11 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 			return reconcile.Result{}, errors.Wrap(err, "ProxySQL upgrade error")
 		}
 
+		err = r.reconcileProxySQL(o)
+		if err != nil {
+			reqLogger.Error(err, "reconcile ProxySQL configuration")
+		}
+
 		currentService := &corev1.Service{}
 		err := r.client.Get(context.TODO(), types.NamespacedName{Name: proxysqlService.Name, Namespace: proxysqlService.Namespace}, currentService)
 		if err != nil {
@@ -511,6 +584,8 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 			return reconcile.Result{}, errors.Wrap(err, "ProxySQL service upgrade error")
 		}
 	} else {
+		o.Status.RemoveCondition(api.ClusterProxySQLConfigured)
+
 		// check if there is need to delete pvc
 		deletePVC := false
 		for _, fnlz := range o.GetFinalizers() {
@@ -531,6 +606,11 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 		}
 	}
 
+	err = r.reconcileMonitoring(o)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "reconcile monitoring")
+	}
+
 	err = r.reconcileBackups(o)
 	if err != nil {
 		return reconcile.Result{}, err
@@ -540,7 +620,7 @@ func (r *ReconcilePerconaXtraDBCluster) Reconcile(request reconcile.Request) (re
 		return rr, errors.Wrap(err, "update CR version")
 	}
 
-	err = r.sheduleEnsurePXCVersion(o, VersionServiceClient{OpVersion: o.Version().String()})
+	err = r.sheduleEnsurePXCVersion(o)
 	if err != nil {
 		return reconcile.Result{}, errors.Wrap(err, "failed to ensure version")
 	}
@@ -785,7 +865,36 @@ func (r *ReconcilePerconaXtraDBCluster) createService(cr *api.PerconaXtraDBClust
 	return errors.WithMessage(err, "check if exists")
 }
 
+// validateConfiguration checks PXC.Configuration before it gets into the
+// config map, so invalid options don't crash-loop the pods on restart
+func (r *ReconcilePerconaXtraDBCluster) validateConfiguration(cr *api.PerconaXtraDBCluster) error {
+	if len(cr.Spec.PXC.Configuration) == 0 {
+		cr.Status.RemoveCondition(api.ClusterConfigurationValid)
+		return nil
+	}
+
+	warnings, err := config.ValidateConfiguration(cr)
+	if err != nil {
+		r.setCondition(cr, boolCondition(api.ClusterConfigurationValid, false, "InvalidConfiguration", err.Error()))
+		return err
+	}
+	if len(warnings) > 0 {
+		r.setCondition(cr, boolCondition(api.ClusterConfigurationValid, true, "ConfigurationWarnings", strings.Join(warnings, "; ")))
+		return nil
+	}
+	r.setCondition(cr, boolCondition(api.ClusterConfigurationValid, true, "ConfigurationValid", ""))
+
+	return nil
+}
+
 func (r *ReconcilePerconaXtraDBCluster) reconcileConfigMap(cr *api.PerconaXtraDBCluster) error {
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		err := r.validateConfiguration(cr)
+		if err != nil {
+			return err
+		}
+	}
+
 	stsApp := statefulset.NewNode(cr)
 	ls := stsApp.Labels()
 	limitMemory := ""
@@ -803,8 +912,23 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileConfigMap(cr *api.PerconaXtraDB
 			}
 		}
 	}
+	autoTune := len(limitMemory) > 0 || len(requestMemory) > 0
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		autoTune = autoTune || cr.Spec.PXC.AutoTune != nil ||
+			(cr.Spec.PXC.Resources != nil && ((cr.Spec.PXC.Resources.Limits != nil && cr.Spec.PXC.Resources.Limits.CPU != "") ||
+				(cr.Spec.PXC.Resources.Requests != nil && cr.Spec.PXC.Resources.Requests.CPU != "")))
+
+		cr.Status.AutoTuneParams = nil
+		if autoTune {
+			params, err := config.AutoTune(cr)
+			if err != nil {
+				return errors.Wrap(err, "auto-tune params")
+			}
+			cr.Status.AutoTuneParams = params
+		}
+	}
 	if cr.CompareVersionWith("1.3.0") >= 0 {
-		if len(limitMemory) > 0 || len(requestMemory) > 0 {
+		if autoTune {
 			configMap, err := config.NewAutoTuneConfigMap(cr, "auto-"+ls["app.kubernetes.io/instance"]+"-"+ls["app.kubernetes.io/component"])
 			if err != nil {
 				return errors.Wrap(err, "new auto-config map")
@@ -828,7 +952,11 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileConfigMap(cr *api.PerconaXtraDB
 			return errors.Wrap(err, "set controller ref")
 		}
 
-		err = createOrUpdateConfigmap(r.client, configMap)
+		if cr.CompareVersionWith("1.8.0") >= 0 {
+			err = r.reconcilePXCConfigMap(cr, configMap)
+		} else {
+			err = createOrUpdateConfigmap(r.client, configMap)
+		}
 		if err != nil {
 			return errors.Wrap(err, "pxc config map")
 		}
@@ -860,6 +988,26 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileConfigMap(cr *api.PerconaXtraDB
 		}
 	}
 
+	if cr.HAProxyBackendsManaged() {
+		conf, zones, err := r.haproxyAutoConfig(cr)
+		if err != nil {
+			return errors.Wrap(err, "render haproxy backends")
+		}
+		configMap := config.NewConfigMap(cr, ls["app.kubernetes.io/instance"]+"-haproxy-auto", config.HAProxyAutoConfigFile, conf)
+		if cr.Spec.HAProxy.ZoneAwareReads {
+			configMap.Data[config.HAProxyZonesMapFile] = zones
+		}
+		err = setControllerReference(cr, configMap, r.scheme)
+		if err != nil {
+			return errors.Wrap(err, "set controller ref HAProxy backends")
+		}
+
+		err = createOrUpdateConfigmap(r.client, configMap)
+		if err != nil {
+			return errors.Wrap(err, "haproxy backends config map")
+		}
+	}
+
 	if cr.Spec.LogCollector != nil && cr.Spec.LogCollector.Configuration != "" && cr.CompareVersionWith("1.7.0") >= 0 {
 		configMap := config.NewConfigMap(cr, ls["app.kubernetes.io/instance"]+"-logcollector", "fluentbit_custom.conf", cr.Spec.LogCollector.Configuration)
 		err := setControllerReference(cr, configMap, r.scheme)
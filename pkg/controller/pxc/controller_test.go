@@ -0,0 +1,100 @@
+package pxc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/apis"
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
+)
+
+func TestReconcileDeletedCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.ObserveReconcile("pxc", "deleted", "ns", time.Now(), nil)
+
+	r := &ReconcilePerconaXtraDBCluster{
+		client:         fake.NewFakeClientWithScheme(scheme),
+		lockers:        newLockStore(),
+		vaults:         credentials.NewCache(),
+		nodeZones:      new(sync.Map),
+		membersChecked: new(sync.Map),
+	}
+	res, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "deleted", Namespace: "ns"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (reconcile.Result{}) {
+		t.Errorf("expected the deleted cluster not to be requeued, got %+v", res)
+	}
+
+	families, err := crmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "cluster" && l.GetValue() == "deleted" {
+					t.Errorf("%s: series of the deleted cluster left", f.GetName())
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateStatusReconcileError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns"},
+		Status: api.PerconaXtraDBClusterStatus{
+			Status: api.AppStateReady,
+			Conditions: []api.ClusterCondition{
+				{Type: api.ClusterReady, Status: api.ConditionTrue, Reason: "AllComponentsReady"},
+				{Type: api.ClusterReconciled, Status: api.ConditionTrue, Reason: "ReconcileSucceeded"},
+			},
+		},
+	}
+	r := &ReconcilePerconaXtraDBCluster{
+		client:   fake.NewFakeClientWithScheme(scheme, cr),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	err := r.updateStatus(cr, errors.New("Operation cannot be fulfilled: the object has been modified"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := cr.Status.FindCondition(api.ClusterReady); c == nil || c.Status != api.ConditionTrue {
+		t.Errorf("expected Ready to be kept True on the reconcile error, got %+v", c)
+	}
+	if c := cr.Status.FindCondition(api.ClusterReconciled); c == nil || c.Status != api.ConditionFalse || c.Reason != "ReconcileError" {
+		t.Errorf("expected Reconciled to be False with ReconcileError, got %+v", c)
+	}
+}
@@ -0,0 +1,41 @@
+package pxc
+
+// Reasons of the events emitted for the PerconaXtraDBCluster objects.
+// The reasons of the backup and restore events are defined with their types.
+const (
+	EventFullCrashRecovery             = "FullClusterCrashRecovery"
+	EventFullCrashRecoveryFailed       = "FullClusterCrashRecoveryFailed"
+	EventFullCrashRecoveryUUIDMismatch = "FullClusterCrashRecoveryUUIDMismatch"
+	EventSmartUpdateStarted            = "SmartUpdateStarted"
+	EventSmartUpdateFinished           = "SmartUpdateFinished"
+	EventSmartUpdateFailed             = "SmartUpdateFailed"
+	EventPodRestarted                  = "PodRestarted"
+	EventPrimaryChanged                = "PrimaryChanged"
+	EventVersionUpgrade                = "VersionUpgrade"
+	EventUsersPasswordChanged          = "SystemUsersPasswordChanged"
+	EventBootstrapRejected             = "BootstrapRejected"
+	EventBootstrapFinished             = "BootstrapFinished"
+	EventVerticalScalingStarted        = "VerticalScalingStarted"
+	EventVerticalScalingFinished       = "VerticalScalingFinished"
+	EventBackupPruned                  = "BackupPruned"
+	EventConfigurationAppliedOnline    = "ConfigurationAppliedOnline"
+	EventConfigurationRestartRequired  = "ConfigurationRestartRequired"
+	EventCanaryStarted                 = "CanaryStarted"
+	EventCanaryPassed                  = "CanaryPassed"
+	EventCanaryFailed                  = "CanaryFailed"
+	EventCanaryWaitingApproval         = "CanaryWaitingApproval"
+	EventUpgradeRollback               = "UpgradeRollback"
+	EventUpgradeRollbackBlocked        = "UpgradeRollbackBlocked"
+	EventRolloutDeferred               = "RolloutDeferred"
+	EventUpgradePlanned                = "UpgradePlanned"
+	EventMajorUpgradeStarted           = "MajorUpgradeStarted"
+	EventMajorUpgradeBlocked           = "MajorUpgradeBlocked"
+	EventMajorUpgradeCompleted         = "MajorUpgradeCompleted"
+	EventVolumeExpansionStarted        = "VolumeExpansionStarted"
+	EventVolumeExpansionCompleted      = "VolumeExpansionCompleted"
+	EventVolumeExpansionPodRestart     = "VolumeExpansionPodRestart"
+	EventVolumeExpansionFailed         = "VolumeExpansionFailed"
+	EventStorageAutoscaled             = "StorageAutoscaled"
+	EventNodesForbidden                = "NodesForbidden"
+	EventStorageLimitReached           = "StorageLimitReached"
+)
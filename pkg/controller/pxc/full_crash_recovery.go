@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	v1 "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/recovery"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -35,18 +39,121 @@ func (r *ReconcilePerconaXtraDBCluster) recoverFullClusterCrashIfNeeded(cr *v1.P
 		return err
 	}
 
-	isWaiting, _, err := r.isPodWaitingForRecovery(cr.Namespace, cr.Name+"-pxc-0")
+	podName := cr.Name + "-pxc-0"
+	if pod, ok := cr.Annotations[BootstrapFromAnnotation]; ok {
+		podName = pod
+	}
+
+	state, err := r.recoveryState(cr, podName)
 	if err != nil {
-		return errors.Wrap(err, "failed to check if pxc pod 0 is waiting for recovery")
+		return errors.Wrapf(err, "failed to check if pod %s is waiting for recovery", podName)
 	}
 
-	if isWaiting {
-		return r.doFullCrashRecovery(cr.Name, cr.Namespace, int(cr.Spec.PXC.Size))
+	if state != nil {
+		return r.doFullCrashRecovery(cr)
 	}
 
 	return nil
 }
 
+// recoveryState returns the recovery state of the pod or nil if the pod
+// isn't waiting for the full cluster crash recovery.
+// Since 1.8.0 the state is published by the recovery agent,
+// clusters of older versions print it into the pod logs.
+func (r *ReconcilePerconaXtraDBCluster) recoveryState(cr *v1.PerconaXtraDBCluster, podName string) (*recovery.State, error) {
+	if cr.CompareVersionWith("1.8.0") < 0 {
+		isWaiting, seq, err := r.isPodWaitingForRecovery(cr.Namespace, podName)
+		if err != nil || !isWaiting {
+			return nil, err
+		}
+		return &recovery.State{Node: podName, Seqno: seq}, nil
+	}
+
+	pod := &corev1.Pod{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, pod)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get pod %s", podName)
+	}
+	if pod.Status.PodIP == "" {
+		return nil, nil
+	}
+
+	agent, err := r.recoveryAgent(cr, pod)
+	if err != nil {
+		return nil, errors.Wrap(err, "recovery agent client")
+	}
+	state, err := agent.State()
+	if err == recovery.ErrAgentNotRunning {
+		// the pods started by the init container of the previous
+		// versions wait for the recovery without the agent
+		isWaiting, seq, err := r.isPodWaitingForRecovery(cr.Namespace, podName)
+		if err != nil || !isWaiting {
+			return nil, err
+		}
+		return &recovery.State{Node: podName, Seqno: seq, NoAgent: true}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get recovery state of %s pod", podName)
+	}
+
+	return state, nil
+}
+
+// recoveryAgent returns the client of the recovery agent of the pod. The agent
+// is connected over TLS if the cluster has the internal certificate.
+func (r *ReconcilePerconaXtraDBCluster) recoveryAgent(cr *v1.PerconaXtraDBCluster, pod *corev1.Pod) (*recovery.Client, error) {
+	tokenSecret := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: recovery.TokenSecretName(cr.Name)}, tokenSecret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "get token secret")
+	}
+
+	sslSecret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.PXC.SSLInternalSecretName}, sslSecret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "get ssl internal secret")
+	}
+
+	// the internal certificate is issued for *.<cluster>-pxc
+	serverName := pod.Name + "." + cr.Name + "-pxc"
+	return recovery.NewClient(pod.Status.PodIP, serverName, sslSecret.Data["ca.crt"], string(tokenSecret.Data[recovery.TokenKey]))
+}
+
+// reconcileRecoveryAgentSecret creates the secret with the token
+// the recovery agent authenticates the bootstrap requests with
+func (r *ReconcilePerconaXtraDBCluster) reconcileRecoveryAgentSecret(cr *v1.PerconaXtraDBCluster) error {
+	if cr.CompareVersionWith("1.8.0") < 0 {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: recovery.TokenSecretName(cr.Name)}, secret)
+	if err == nil {
+		return nil
+	} else if !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "get secret")
+	}
+
+	token, err := generatePass()
+	if err != nil {
+		return errors.Wrap(err, "generate token")
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recovery.TokenSecretName(cr.Name),
+			Namespace: cr.Namespace,
+		},
+		Data: map[string][]byte{recovery.TokenKey: token},
+		Type: corev1.SecretTypeOpaque,
+	}
+	err = setControllerReference(cr, secret, r.scheme)
+	if err != nil {
+		return errors.Wrap(err, "set controller reference")
+	}
+
+	return errors.Wrap(r.client.Create(context.TODO(), secret), "create secret")
+}
+
 func (r *ReconcilePerconaXtraDBCluster) isPodWaitingForRecovery(namespace, podName string) (bool, int64, error) {
 	logOpts := &corev1.PodLogOptions{
 		Container: "pxc",
@@ -81,32 +188,114 @@ func parseSequence(log string) (int64, error) {
 	return seq, nil
 }
 
-func (r *ReconcilePerconaXtraDBCluster) doFullCrashRecovery(crName, namespace string, pxcSize int) error {
+// errUUIDMismatch is returned if the pods have data of the different clusters
+var errUUIDMismatch = errors.New("pods have different cluster UUIDs")
+
+// errBootstrapSeqno is returned if the pod chosen by the user is behind the other pods
+var errBootstrapSeqno = errors.New("pod has lower seqno than the other pods")
+
+// bootstrapPod chooses the pod to bootstrap the cluster from. It's the pod
+// from the BootstrapFromAnnotation if it's set, otherwise it's the pod with
+// the highest seqno. The pods must have the same cluster UUID unless
+// the pod is set explicitly. The pod set explicitly must have the highest
+// seqno among the pods of its cluster UUID unless BootstrapForceAnnotation is set.
+func bootstrapPod(cr *v1.PerconaXtraDBCluster, states map[string]*recovery.State) (string, error) {
+	if pod, ok := cr.Annotations[BootstrapFromAnnotation]; ok {
+		chosen, ok := states[pod]
+		if !ok {
+			return "", errors.Errorf("pod %s from %s annotation isn't waiting for recovery", pod, BootstrapFromAnnotation)
+		}
+		if cr.Annotations[BootstrapForceAnnotation] == "true" {
+			return pod, nil
+		}
+		for name, state := range states {
+			if state.UUID == chosen.UUID && state.Seqno > chosen.Seqno {
+				return "", errors.Wrapf(errBootstrapSeqno, "%s seqno %d, %s seqno %d", pod, chosen.Seqno, name, state.Seqno)
+			}
+		}
+		return pod, nil
+	}
+
+	pods := make([]string, 0, len(states))
+	for pod := range states {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+
+	uuid := ""
 	maxSeq := int64(-100)
 	maxSeqPod := ""
+	for _, pod := range pods {
+		state := states[pod]
+		if state.UUID != "" && state.UUID != recovery.EmptyUUID {
+			if uuid != "" && uuid != state.UUID {
+				return "", errUUIDMismatch
+			}
+			uuid = state.UUID
+		}
+
+		if state.Seqno > maxSeq {
+			maxSeq = state.Seqno
+			maxSeqPod = pod
+		}
+	}
+
+	return maxSeqPod, nil
+}
+
+func (r *ReconcilePerconaXtraDBCluster) doFullCrashRecovery(cr *v1.PerconaXtraDBCluster) (rerr error) {
+	crName, namespace := cr.Name, cr.Namespace
 
-	for i := 0; i < pxcSize; i++ {
+	states := make(map[string]*recovery.State, cr.Spec.PXC.Size)
+	for i := 0; i < int(cr.Spec.PXC.Size); i++ {
 		podName := fmt.Sprintf("%s-pxc-%d", crName, i)
-		isPodWaitingForRecovery, seq, err := r.isPodWaitingForRecovery(namespace, podName)
+		state, err := r.recoveryState(cr, podName)
 		if err != nil {
-			return errors.Wrapf(err, "parse %s pod logs", podName)
+			return errors.Wrapf(err, "get %s pod recovery state", podName)
 		}
 
-		if !isPodWaitingForRecovery {
+		if state == nil {
 			return nil
 		}
-
-		if seq > maxSeq {
-			maxSeq = seq
-			maxSeqPod = podName
-		}
+		states[podName] = state
 	}
 	logger := r.logger(crName, namespace)
 	logger.Info("We are in full cluster crash, starting recovery")
+
+	maxSeqPod, err := bootstrapPod(cr, states)
+	if err == errUUIDMismatch {
+		msg := "Full cluster crash detected, but the pods have different cluster UUIDs:"
+		for pod, state := range states {
+			msg += fmt.Sprintf(" %s=%s:%d", pod, state.UUID, state.Seqno)
+		}
+		msg += ". Set the " + BootstrapFromAnnotation + " annotation to the pod to bootstrap the cluster from"
+		r.recorder.Event(cr, corev1.EventTypeWarning, EventFullCrashRecoveryUUIDMismatch, msg)
+		logger.Info(msg)
+		return nil
+	}
+	if errors.Cause(err) == errBootstrapSeqno {
+		r.recorder.Eventf(cr, corev1.EventTypeWarning, EventBootstrapRejected,
+			"Bootstrap from the pod set in %s annotation is rejected: %v. Set %s annotation to \"true\" to bootstrap from it anyway",
+			BootstrapFromAnnotation, err, BootstrapForceAnnotation)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	maxSeq := states[maxSeqPod].Seqno
+
+	defer func() {
+		metrics.FullCrashRecoveries.WithLabelValues(crName, namespace, metrics.Result(rerr)).Inc()
+		if rerr != nil {
+			r.recorder.Eventf(cr, corev1.EventTypeWarning, EventFullCrashRecoveryFailed, "Full cluster crash recovery from pod %s failed: %v", maxSeqPod, rerr)
+		}
+	}()
+	r.recorder.Eventf(cr, corev1.EventTypeWarning, EventFullCrashRecovery,
+		"Full cluster crash detected, bootstrapping the cluster from pod %s with seqno %d", maxSeqPod, maxSeq)
 	logger.Info("Results of scanning sequences", "pod", maxSeqPod, "maxSeq", maxSeq)
 
 	pod := &corev1.Pod{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{
+	err = r.client.Get(context.TODO(), types.NamespacedName{
 		Namespace: namespace,
 		Name:      maxSeqPod,
 	}, pod)
@@ -114,14 +303,25 @@ func (r *ReconcilePerconaXtraDBCluster) doFullCrashRecovery(crName, namespace st
 		return errors.Wrap(err, "get pods defenition")
 	}
 
-	stderrBuf := &bytes.Buffer{}
-	err = r.clientcmd.Exec(pod, "pxc", []string{"/bin/sh", "-c", "kill -s USR1 1"}, nil, nil, stderrBuf, false)
-	if err != nil {
-		return errors.Wrap(err, "exec command in pod")
-	}
+	if cr.CompareVersionWith("1.8.0") >= 0 && !states[maxSeqPod].NoAgent {
+		agent, err := r.recoveryAgent(cr, pod)
+		if err != nil {
+			return errors.Wrap(err, "recovery agent client")
+		}
+		err = agent.Bootstrap()
+		if err != nil {
+			return errors.Wrap(err, "request bootstrap")
+		}
+	} else {
+		stderrBuf := &bytes.Buffer{}
+		err = r.clientcmd.Exec(pod, "pxc", []string{"/bin/sh", "-c", "kill -s USR1 1"}, nil, nil, stderrBuf, false)
+		if err != nil {
+			return errors.Wrap(err, "exec command in pod")
+		}
 
-	if stderrBuf.Len() != 0 {
-		return errors.New("invalid exec command return: " + stderrBuf.String())
+		if stderrBuf.Len() != 0 {
+			return errors.New("invalid exec command return: " + stderrBuf.String())
+		}
 	}
 
 	// sleep there a little to start script and do not send
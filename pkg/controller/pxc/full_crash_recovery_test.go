@@ -0,0 +1,91 @@
+package pxc
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/recovery"
+)
+
+func TestBootstrapPod(t *testing.T) {
+	const uuid = "8a7e2f54-2c8b-11eb-8a9e-4e0b6c8f4a8e"
+
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		states      map[string]*recovery.State
+		pod         string
+		err         bool
+	}{
+		{
+			name: "highest seqno",
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+				"cluster1-pxc-1": {UUID: uuid, Seqno: 12},
+				"cluster1-pxc-2": {UUID: recovery.EmptyUUID, Seqno: -1},
+			},
+			pod: "cluster1-pxc-1",
+		},
+		{
+			name: "uuid mismatch",
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+				"cluster1-pxc-1": {UUID: "0b2a6e3c-2c8c-11eb-9d1f-1e4f1c6b7d2a", Seqno: 12},
+			},
+			err: true,
+		},
+		{
+			name:        "override",
+			annotations: map[string]string{BootstrapFromAnnotation: "cluster1-pxc-0"},
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+				"cluster1-pxc-1": {UUID: "0b2a6e3c-2c8c-11eb-9d1f-1e4f1c6b7d2a", Seqno: 12},
+			},
+			pod: "cluster1-pxc-0",
+		},
+		{
+			name:        "override with lower seqno",
+			annotations: map[string]string{BootstrapFromAnnotation: "cluster1-pxc-0"},
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+				"cluster1-pxc-1": {UUID: uuid, Seqno: 12},
+			},
+			err: true,
+		},
+		{
+			name: "forced override with lower seqno",
+			annotations: map[string]string{
+				BootstrapFromAnnotation:  "cluster1-pxc-0",
+				BootstrapForceAnnotation: "true",
+			},
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+				"cluster1-pxc-1": {UUID: uuid, Seqno: 12},
+			},
+			pod: "cluster1-pxc-0",
+		},
+		{
+			name:        "override with unknown pod",
+			annotations: map[string]string{BootstrapFromAnnotation: "cluster1-pxc-5"},
+			states: map[string]*recovery.State{
+				"cluster1-pxc-0": {UUID: uuid, Seqno: 10},
+			},
+			err: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cr := &api.PerconaXtraDBCluster{ObjectMeta: metav1.ObjectMeta{Annotations: c.annotations}}
+			pod, err := bootstrapPod(cr, c.states)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pod != c.pod {
+				t.Errorf("expected pod %q, got %q", c.pod, pod)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pxc
+
+import (
+	"github.com/pkg/errors"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// haproxyAutoConfig renders the HAProxy backends of the cluster and the zones
+// of the nodes the zone-aware replicas frontend is chosen by. The zones of the
+// PXC pods are taken from the topology published to the status.
+func (r *ReconcilePerconaXtraDBCluster) haproxyAutoConfig(cr *api.PerconaXtraDBCluster) (conf, zones string, err error) {
+	nodes := config.HAProxyNodes(cr)
+	podZones := make(map[string]string, len(cr.Status.Topology))
+	for _, t := range cr.Status.Topology {
+		podZones[t.Pod] = t.Zone
+	}
+	for i := range nodes {
+		nodes[i].Zone = podZones[nodes[i].Name]
+	}
+
+	proxyProtocol, err := cr.ConfigHasKey("mysqld", "proxy_protocol_networks")
+	if err != nil {
+		return "", "", errors.Wrap(err, "check if config has proxy_protocol_networks key")
+	}
+	conf = config.HAProxyConfig(cr, nodes, proxyProtocol)
+
+	if cr.Spec.HAProxy.ZoneAwareReads {
+		haproxyTopology, _, err := r.podTopology(cr, statefulset.NewHAProxy(cr))
+		if err != nil {
+			return "", "", errors.Wrap(err, "get haproxy pods topology")
+		}
+		zones = config.HAProxyZonesMap(append(haproxyTopology, cr.Status.Topology...))
+	}
+
+	return conf, zones, nil
+}
@@ -0,0 +1,196 @@
+package pxc
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+// deferredSecretAnnotation on the statefulset keeps the system users secret
+// hash until it's applied to the pod template. The hash is set only once,
+// in the reconcile the secret was changed, so it would be lost otherwise.
+const deferredSecretAnnotation = "percona.com/deferred-last-applied-secret"
+
+// appliedTemplateAnnotation on the statefulset keeps the hash of the pod
+// template applied by the operator. The template read from the cluster has
+// the defaults set by Kubernetes, so it can't be compared with the new one.
+const appliedTemplateAnnotation = "percona.com/applied-template-hash"
+
+// disruptiveAnnotations are the pod template annotations changed to restart pods
+var disruptiveAnnotations = []struct {
+	name   string
+	action string
+}{
+	{"percona.com/configuration-hash", "configuration change"},
+	{"percona.com/ssl-hash", "TLS certificates rotation"},
+	{"percona.com/ssl-internal-hash", "TLS certificates rotation"},
+	{"percona.com/vault-config-hash", "vault configuration change"},
+	{"last-applied-secret", "system users secret change"},
+}
+
+// restoreDeferredSecret puts the deferred users secret hash back to the
+// pod template unless the secret was changed again in this reconcile
+func restoreDeferredSecret(currentSet *appsv1.StatefulSet, newAnnotations map[string]string) {
+	hash, ok := currentSet.Annotations[deferredSecretAnnotation]
+	if !ok {
+		return
+	}
+	if _, ok := newAnnotations["last-applied-secret"]; !ok {
+		currentSet.Spec.Template.Annotations["last-applied-secret"] = hash
+	}
+}
+
+// templateHash returns the hash of the pod template
+func templateHash(template *corev1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", md5.Sum(data)), nil
+}
+
+// recordAppliedTemplate keeps the hash of the pod template of the statefulset
+func recordAppliedTemplate(currentSet *appsv1.StatefulSet) {
+	hash, err := templateHash(&currentSet.Spec.Template)
+	if err != nil {
+		return
+	}
+	if currentSet.Annotations == nil {
+		currentSet.Annotations = make(map[string]string)
+	}
+	currentSet.Annotations[appliedTemplateAnnotation] = hash
+}
+
+// disruptiveChanges returns the changes of the pod template that restart pods.
+// Any change of the template restarts pods, so the template is compared
+// with appliedHash. The known changes are reported by their names.
+func disruptiveChanges(old, template *corev1.PodTemplateSpec, appliedHash string) []string {
+	var changes []string
+	add := func(action string) {
+		for _, c := range changes {
+			if c == action {
+				return
+			}
+		}
+		changes = append(changes, action)
+	}
+
+	oldContainers := make(map[string]corev1.Container, len(old.Spec.Containers))
+	for _, c := range old.Spec.Containers {
+		oldContainers[c.Name] = c
+	}
+	if len(oldContainers) != len(template.Spec.Containers) {
+		add("containers change")
+	}
+	for _, c := range template.Spec.Containers {
+		oc, ok := oldContainers[c.Name]
+		switch {
+		case !ok:
+			add("containers change")
+		case oc.Image != c.Image:
+			add("image change")
+		case resourcesChanged(oc.Resources, c.Resources):
+			add("resources change")
+		}
+	}
+
+	for _, a := range disruptiveAnnotations {
+		if old.Annotations[a.name] != template.Annotations[a.name] {
+			add(a.action)
+		}
+	}
+
+	// the template applied by the previous operator versions has no hash
+	if len(changes) == 0 && len(appliedHash) > 0 {
+		hash, err := templateHash(template)
+		if err == nil && hash != appliedHash {
+			add("pod template change")
+		}
+	}
+
+	return changes
+}
+
+// deferRollout returns true if the changes of the pod template restart pods
+// and they have to wait for the maintenance window. The deferred changes are
+// reported in the status. A rollout in progress isn't interrupted.
+func (r *ReconcilePerconaXtraDBCluster) deferRollout(cr *api.PerconaXtraDBCluster, sfs api.StatefulApp, currentSet *appsv1.StatefulSet, old, template *corev1.PodTemplateSpec) bool {
+	component := sfs.Labels()["app.kubernetes.io/component"]
+
+	if cr.CompareVersionWith("1.8.0") < 0 || len(cr.Spec.MaintenanceWindows) == 0 {
+		cr.Status.Maintenance = nil
+		delete(currentSet.Annotations, deferredSecretAnnotation)
+		return false
+	}
+
+	if cr.Status.Maintenance == nil {
+		cr.Status.Maintenance = &api.MaintenanceStatus{}
+	}
+	status := cr.Status.Maintenance
+
+	open, next, err := cr.Spec.MaintenanceWindowOpen(time.Now())
+	if err != nil {
+		// the windows are checked by the CR validation, so it shouldn't happen
+		r.logger(cr.Name, cr.Namespace).Error(err, "check maintenance windows")
+		open = true
+	}
+	status.NextWindow = nil
+	if !open {
+		status.NextWindow = &metav1.Time{Time: next}
+	}
+
+	changes := disruptiveChanges(old, template, currentSet.Annotations[appliedTemplateAnnotation])
+	inProgress := currentSet.Status.UpdatedReplicas < currentSet.Status.Replicas ||
+		currentSet.Annotations[resizeAnnotation] == "true"
+
+	if open || len(changes) == 0 || inProgress || cr.Annotations[api.MaintenanceOverrideAnnotation] == "true" {
+		setPendingActions(status, component, nil)
+		delete(currentSet.Annotations, deferredSecretAnnotation)
+		return false
+	}
+
+	if hash := template.Annotations["last-applied-secret"]; hash != old.Annotations["last-applied-secret"] {
+		if currentSet.Annotations == nil {
+			currentSet.Annotations = make(map[string]string)
+		}
+		currentSet.Annotations[deferredSecretAnnotation] = hash
+	}
+
+	if setPendingActions(status, component, changes) {
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventRolloutDeferred,
+			"Rollout of statefulset %s (%s) is deferred until the maintenance window at %s",
+			currentSet.Name, strings.Join(changes, ", "), next.UTC().Format(time.RFC3339))
+	}
+
+	return true
+}
+
+// setPendingActions replaces the pending actions of the component.
+// It returns true if the component had no pending actions before.
+func setPendingActions(status *api.MaintenanceStatus, component string, actions []string) bool {
+	prefix := component + ": "
+
+	pending := make([]string, 0, len(status.PendingActions)+len(actions))
+	existed := false
+	for _, a := range status.PendingActions {
+		if strings.HasPrefix(a, prefix) {
+			existed = true
+			continue
+		}
+		pending = append(pending, a)
+	}
+	for _, a := range actions {
+		pending = append(pending, prefix+a)
+	}
+	status.PendingActions = pending
+
+	return !existed && len(actions) > 0
+}
@@ -0,0 +1,34 @@
+package pxc
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDisruptiveChanges(t *testing.T) {
+	old := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "pxc", Image: "percona/percona-xtradb-cluster:8.0.21-12.1"}},
+		},
+	}
+	applied, err := templateHash(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := disruptiveChanges(old, old.DeepCopy(), applied); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	template := old.DeepCopy()
+	template.Spec.Tolerations = []corev1.Toleration{{Key: "node.alpha.kubernetes.io/unreachable", Operator: corev1.TolerationOpExists}}
+	if changes := disruptiveChanges(old, template, applied); len(changes) != 1 || changes[0] != "pod template change" {
+		t.Errorf("expected the pod template change, got %v", changes)
+	}
+
+	template.Spec.Containers[0].Image = "percona/percona-xtradb-cluster:8.0.22-13.1"
+	if changes := disruptiveChanges(old, template, applied); len(changes) != 1 || changes[0] != "image change" {
+		t.Errorf("expected the image change, got %v", changes)
+	}
+}
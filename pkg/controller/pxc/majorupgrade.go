@@ -0,0 +1,358 @@
+package pxc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+)
+
+func majorUpgradeEnabled(cr *api.PerconaXtraDBCluster) bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 && cr.Spec.UpgradeOptions.MajorUpgrade != nil &&
+		cr.Spec.UpgradeOptions.MajorUpgrade.Enabled
+}
+
+// majorUpgrade returns the major versions if the new PXC image upgrades
+// the cluster to the next major version, e.g. 5.7 to 8.0
+func majorUpgrade(old *corev1.PodTemplateSpec, image string) (from, to string, ok bool) {
+	for _, c := range old.Spec.Containers {
+		if c.Name == app.Name {
+			from = config.ImageMajorVersion(c.Image)
+		}
+	}
+	to = config.ImageMajorVersion(image)
+
+	return from, to, len(from) > 0 && len(to) > 0 && from < to
+}
+
+// holdMajorUpgrade returns true if the PXC pods have to stay on the old
+// image until the pre-flight checks are passed and the backup is made.
+// The progress of the upgrade is kept in the status.
+func (r *ReconcilePerconaXtraDBCluster) holdMajorUpgrade(cr *api.PerconaXtraDBCluster, currentSet *appsv1.StatefulSet, old *corev1.PodTemplateSpec, appC corev1.Container) bool {
+	if !majorUpgradeEnabled(cr) {
+		return false
+	}
+
+	from, to, ok := majorUpgrade(old, appC.Image)
+	if !ok {
+		// the new image is already rolled out
+		r.trackNodeUpgrade(cr, currentSet)
+		return false
+	}
+
+	status := cr.Status.MajorUpgrade
+	if status == nil || status.Image != appC.Image || status.Stage == api.MajorUpgradeStageCompleted {
+		now := metav1.Now()
+		status = &api.MajorUpgradeStatus{
+			From:               from,
+			To:                 to,
+			Image:              appC.Image,
+			Stage:              api.MajorUpgradeStagePreflight,
+			StartedAt:          &now,
+			ObservedGeneration: cr.Generation,
+		}
+		cr.Status.MajorUpgrade = status
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventMajorUpgradeStarted,
+			"Major upgrade of PXC from %s to %s is started", from, to)
+	}
+
+	err := r.majorUpgradeStep(cr, status)
+	if err != nil {
+		r.logger(cr.Name, cr.Namespace).Error(err, "major upgrade", "stage", status.Stage)
+	}
+
+	if status.Stage == api.MajorUpgradeStageNodes {
+		r.trackNodeUpgrade(cr, currentSet)
+		return false
+	}
+
+	return true
+}
+
+// majorUpgradeStep moves the upgrade through the stages preceding the rollout
+func (r *ReconcilePerconaXtraDBCluster) majorUpgradeStep(cr *api.PerconaXtraDBCluster, status *api.MajorUpgradeStatus) error {
+	switch status.Stage {
+	case api.MajorUpgradeStageBlocked:
+		job := &batchv1.Job{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: app.UpgradeCheckJobName(cr), Namespace: cr.Namespace}, job)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "get upgrade check job")
+		}
+		changed := status.ObservedGeneration != cr.Generation
+		if changed && err == nil {
+			err = r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return errors.Wrap(err, "delete upgrade check job")
+			}
+			return nil
+		}
+		if !changed && err == nil {
+			return nil
+		}
+		// the job is deleted by the user or for the changed CR, so the checks are repeated
+		status.Stage = api.MajorUpgradeStagePreflight
+		status.ObservedGeneration = cr.Generation
+		status.Message = ""
+		return r.majorUpgradeStep(cr, status)
+	case api.MajorUpgradeStagePreflight:
+		blockers, done, err := r.preflightBlockers(cr, status)
+		if err != nil || !done {
+			return err
+		}
+		if len(blockers) > 0 {
+			status.Stage = api.MajorUpgradeStageBlocked
+			status.Message = strings.Join(blockers, "; ")
+			r.recorder.Eventf(cr, corev1.EventTypeWarning, EventMajorUpgradeBlocked,
+				"Major upgrade to PXC %s is blocked: %s. Fix it and delete job %s to repeat the checks",
+				status.To, status.Message, app.UpgradeCheckJobName(cr))
+			return nil
+		}
+		status.Stage = api.MajorUpgradeStageBackup
+		status.Message = ""
+		return r.majorUpgradeStep(cr, status)
+	case api.MajorUpgradeStageBackup:
+		done, err := r.majorUpgradeBackup(cr, status)
+		if err != nil || !done {
+			return err
+		}
+		status.Stage = api.MajorUpgradeStageNodes
+		status.Message = ""
+	}
+
+	return nil
+}
+
+// preflightBlockers checks the configuration and the data against the target
+// version. It returns false until the upgrade check job is finished.
+func (r *ReconcilePerconaXtraDBCluster) preflightBlockers(cr *api.PerconaXtraDBCluster, status *api.MajorUpgradeStatus) ([]string, bool, error) {
+	job := app.UpgradeCheckJob(cr, cr.Spec.UpgradeOptions.MajorUpgrade.PreflightImage, status.To)
+	current := &batchv1.Job{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, current)
+	if k8serrors.IsNotFound(err) {
+		err = setControllerReference(cr, job, r.scheme)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "set owner reference")
+		}
+		err = r.client.Create(context.TODO(), job)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "create upgrade check job")
+		}
+		status.Message = "upgrade checker is running"
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "get upgrade check job")
+	}
+
+	var blockers []string
+	switch {
+	case current.Status.Succeeded > 0:
+	case jobFailed(current):
+		blockers = append(blockers, fmt.Sprintf("upgrade checker found errors, see the logs of job %s", current.Name))
+	default:
+		return nil, false, nil
+	}
+
+	if _, err := config.ValidateConfigurationFor(cr, status.To); err != nil {
+		blockers = append(blockers, err.Error())
+	}
+
+	sqlMode, err := runningSQLMode(r, cr)
+	if err != nil {
+		return nil, false, err
+	}
+	if modes := config.IncompatibleSQLModes(sqlMode); len(modes) > 0 {
+		blockers = append(blockers, "sql_mode has modes removed in PXC "+status.To+": "+strings.Join(modes, ", "))
+	}
+
+	return blockers, true, nil
+}
+
+// runningSQLMode returns sql_mode of the first PXC pod.
+// It's a variable, so the checks can be tested without the database.
+var runningSQLMode = func(r *ReconcilePerconaXtraDBCluster, cr *api.PerconaXtraDBCluster) (string, error) {
+	database, err := r.pxcDB(cr, cr.Name+"-pxc-0")
+	if err != nil {
+		return "", errors.Wrap(err, "connect to pxc-0")
+	}
+	defer database.Close()
+
+	sqlMode, err := database.StringVariable("sql_mode")
+	return sqlMode, errors.Wrap(err, "get sql_mode")
+}
+
+func jobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// majorUpgradeBackup returns true if the cluster has the backup completed
+// after the upgrade was started. The backup is made by the operator if the
+// storage is set, otherwise it's expected from the user.
+func (r *ReconcilePerconaXtraDBCluster) majorUpgradeBackup(cr *api.PerconaXtraDBCluster, status *api.MajorUpgradeStatus) (bool, error) {
+	storage := cr.Spec.UpgradeOptions.MajorUpgrade.BackupStorageName
+	if len(storage) == 0 {
+		list := api.PerconaXtraDBClusterBackupList{}
+		err := r.client.List(context.TODO(), &list, &client.ListOptions{Namespace: cr.Namespace})
+		if err != nil {
+			return false, errors.Wrap(err, "get backup list")
+		}
+		for _, bcp := range list.Items {
+			if bcp.Spec.PXCCluster == cr.Name && bcp.Status.State == api.BackupSucceeded &&
+				bcp.Status.CompletedAt != nil && !bcp.Status.CompletedAt.Before(status.StartedAt) {
+				status.Backup = bcp.Name
+				return true, nil
+			}
+		}
+		status.Message = "waiting for the backup of the cluster"
+		return false, nil
+	}
+
+	if len(status.Backup) == 0 {
+		bcp := &api.PerconaXtraDBClusterBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-major-upgrade-%d", cr.Name, status.StartedAt.Unix()),
+				Namespace: cr.Namespace,
+				Labels: map[string]string{
+					"cluster": cr.Name,
+				},
+			},
+			Spec: api.PXCBackupSpec{
+				PXCCluster:  cr.Name,
+				StorageName: storage,
+			},
+		}
+		err := r.client.Create(context.TODO(), bcp)
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, errors.Wrap(err, "create backup")
+		}
+		status.Backup = bcp.Name
+		status.Message = "backup is running"
+		return false, nil
+	}
+
+	bcp := &api.PerconaXtraDBClusterBackup{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: status.Backup, Namespace: cr.Namespace}, bcp)
+	if err != nil {
+		return false, errors.Wrapf(err, "get backup %s", status.Backup)
+	}
+
+	switch bcp.Status.State {
+	case api.BackupSucceeded:
+		return true, nil
+	case api.BackupFailed:
+		status.Stage = api.MajorUpgradeStageBlocked
+		status.Message = "backup " + bcp.Name + " failed"
+		status.Backup = ""
+		r.recorder.Eventf(cr, corev1.EventTypeWarning, EventMajorUpgradeBlocked,
+			"Major upgrade to PXC %s is blocked: backup %s failed", status.To, bcp.Name)
+	}
+
+	return false, nil
+}
+
+// trackNodeUpgrade updates the stages of the PXC pods while they are
+// restarted with the new image and checks the upgraded cluster at the end
+func (r *ReconcilePerconaXtraDBCluster) trackNodeUpgrade(cr *api.PerconaXtraDBCluster, currentSet *appsv1.StatefulSet) {
+	status := cr.Status.MajorUpgrade
+	if status == nil || (status.Stage != api.MajorUpgradeStageNodes && status.Stage != api.MajorUpgradeStagePostUpgrade) {
+		return
+	}
+
+	logger := r.logger(cr.Name, cr.Namespace)
+
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     currentSet.Namespace,
+			LabelSelector: labels.SelectorFromSet(currentSet.Spec.Selector.MatchLabels),
+		},
+	)
+	if err != nil {
+		logger.Error(err, "get pod list")
+		return
+	}
+
+	nodes := make([]api.NodeUpgradeStatus, 0, len(list.Items))
+	upgraded := 0
+	for _, pod := range list.Items {
+		stage := api.NodeUpgradeStagePending
+		if pod.Labels["controller-revision-hash"] == currentSet.Status.UpdateRevision {
+			stage = api.NodeUpgradeStageUpgrading
+			if isContainersReady(pod) {
+				stage = api.NodeUpgradeStageUpgraded
+				upgraded++
+			}
+		}
+		nodes = append(nodes, api.NodeUpgradeStatus{Pod: pod.Name, Stage: stage})
+	}
+	status.Nodes = nodes
+
+	if currentSet.Spec.Replicas == nil || upgraded < int(*currentSet.Spec.Replicas) {
+		status.Stage = api.MajorUpgradeStageNodes
+		return
+	}
+	status.Stage = api.MajorUpgradeStagePostUpgrade
+
+	for _, n := range nodes {
+		msg, err := r.checkUpgradedNode(cr, n.Pod, status.To)
+		if err != nil {
+			logger.Error(err, "check upgraded node", "pod", n.Pod)
+			return
+		}
+		if len(msg) > 0 {
+			status.Message = msg
+			return
+		}
+	}
+
+	status.Stage = api.MajorUpgradeStageCompleted
+	status.Message = ""
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventMajorUpgradeCompleted,
+		"Major upgrade of PXC from %s to %s is completed", status.From, status.To)
+}
+
+// checkUpgradedNode returns the reason the pod isn't upgraded yet, or an empty string
+func (r *ReconcilePerconaXtraDBCluster) checkUpgradedNode(cr *api.PerconaXtraDBCluster, pod, major string) (string, error) {
+	database, err := r.pxcDB(cr, pod)
+	if err != nil {
+		return "", errors.Wrap(err, "connect")
+	}
+	defer database.Close()
+
+	version, err := database.Version()
+	if err != nil {
+		return "", errors.Wrap(err, "get version")
+	}
+	if !strings.HasPrefix(version, major+".") {
+		return fmt.Sprintf("pod %s runs PXC %s", pod, version), nil
+	}
+
+	state, err := database.WsrepLocalStateComment()
+	if err != nil {
+		return "", errors.Wrap(err, "get wsrep state")
+	}
+	if state != "Synced" {
+		return fmt.Sprintf("pod %s is %s", pod, state), nil
+	}
+
+	return "", nil
+}
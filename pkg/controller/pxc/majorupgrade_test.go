@@ -0,0 +1,183 @@
+package pxc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/apis"
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+)
+
+func TestMajorUpgrade(t *testing.T) {
+	template := func(image string) *corev1.PodTemplateSpec {
+		return &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "pmm-client", Image: "percona/pmm-client:2.12.0"},
+					{Name: app.Name, Image: image},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		old, image string
+		major      bool
+	}{
+		{"percona/percona-xtradb-cluster:5.7.31-31.45", "percona/percona-xtradb-cluster:8.0.21-12.1", true},
+		{"percona/percona-xtradb-cluster:5.7.31-31.45", "registry:5000/pxc:8.0.21-12.1@sha256:1f3a", true},
+		{"percona/percona-xtradb-cluster:8.0.20-11.1", "percona/percona-xtradb-cluster:8.0.21-12.1", false},
+		{"percona/percona-xtradb-cluster:8.0.21-12.1", "percona/percona-xtradb-cluster:5.7.31-31.45", false},
+		{"percona/percona-xtradb-cluster:5.7.31-31.45", "percona/percona-xtradb-cluster", false},
+	}
+
+	for _, c := range cases {
+		from, to, ok := majorUpgrade(template(c.old), c.image)
+		if ok != c.major {
+			t.Errorf("%s -> %s: got major upgrade %v (%s -> %s), expected %v", c.old, c.image, ok, from, to, c.major)
+		}
+	}
+}
+
+func TestMajorUpgradeStep(t *testing.T) {
+	runningSQLMode = func(*ReconcilePerconaXtraDBCluster, *api.PerconaXtraDBCluster) (string, error) {
+		return "ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES", nil
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	startedAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	completedAt := metav1.NewTime(startedAt.Add(30 * time.Minute))
+	newCR := func(stage api.MajorUpgradeStage, backup string) *api.PerconaXtraDBCluster {
+		return &api.PerconaXtraDBCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns", Generation: 1},
+			Spec: api.PerconaXtraDBClusterSpec{
+				CRVersion: "1.8.0",
+				PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.22-13.1"}},
+				UpgradeOptions: api.UpgradeOptions{
+					MajorUpgrade: &api.MajorUpgradeSpec{Enabled: true, PreflightImage: "mysql/mysql-server:8.0"},
+				},
+			},
+			Status: api.PerconaXtraDBClusterStatus{
+				MajorUpgrade: &api.MajorUpgradeStatus{
+					From:               "5.7",
+					To:                 "8.0",
+					Image:              "percona/percona-xtradb-cluster:8.0.22-13.1",
+					Stage:              stage,
+					StartedAt:          &startedAt,
+					Backup:             backup,
+					ObservedGeneration: 1,
+				},
+			},
+		}
+	}
+	job := func(status batchv1.JobStatus) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-upgrade-check", Namespace: "ns"},
+			Status:     status,
+		}
+	}
+	backup := &api.PerconaXtraDBClusterBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup1", Namespace: "ns"},
+		Spec:       api.PXCBackupSpec{PXCCluster: "cluster1"},
+		Status:     api.PXCBackupStatus{State: api.BackupSucceeded, CompletedAt: &completedAt},
+	}
+
+	tests := map[string]struct {
+		cr      *api.PerconaXtraDBCluster
+		objects []runtime.Object
+		stage   api.MajorUpgradeStage
+		message string
+	}{
+		"preflight started": {
+			cr:      newCR(api.MajorUpgradeStagePreflight, ""),
+			stage:   api.MajorUpgradeStagePreflight,
+			message: "upgrade checker is running",
+		},
+		"preflight failed": {
+			cr: newCR(api.MajorUpgradeStagePreflight, ""),
+			objects: []runtime.Object{job(batchv1.JobStatus{
+				Failed:     3,
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+			})},
+			stage:   api.MajorUpgradeStageBlocked,
+			message: "upgrade checker found errors",
+		},
+		"preflight passed": {
+			cr:      newCR(api.MajorUpgradeStagePreflight, ""),
+			objects: []runtime.Object{job(batchv1.JobStatus{Succeeded: 1}), backup},
+			stage:   api.MajorUpgradeStageNodes,
+		},
+		"preflight passed without backup": {
+			cr:      newCR(api.MajorUpgradeStagePreflight, ""),
+			objects: []runtime.Object{job(batchv1.JobStatus{Succeeded: 1})},
+			stage:   api.MajorUpgradeStageBackup,
+			message: "waiting for the backup of the cluster",
+		},
+		"resume preflight after restart": {
+			cr:      newCR(api.MajorUpgradeStagePreflight, ""),
+			objects: []runtime.Object{job(batchv1.JobStatus{Active: 1})},
+			stage:   api.MajorUpgradeStagePreflight,
+		},
+		"resume backup after restart": {
+			cr:      newCR(api.MajorUpgradeStageBackup, "backup1"),
+			objects: []runtime.Object{backup},
+			stage:   api.MajorUpgradeStageNodes,
+		},
+		"blocked until the cluster is changed": {
+			cr:      newCR(api.MajorUpgradeStageBlocked, ""),
+			objects: []runtime.Object{job(batchv1.JobStatus{Failed: 3})},
+			stage:   api.MajorUpgradeStageBlocked,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &ReconcilePerconaXtraDBCluster{
+				client:   fake.NewFakeClientWithScheme(scheme, tt.objects...),
+				scheme:   scheme,
+				recorder: record.NewFakeRecorder(10),
+			}
+			status := tt.cr.Status.MajorUpgrade
+
+			if err := r.majorUpgradeStep(tt.cr, status); err != nil {
+				t.Fatal(err)
+			}
+			if status.Stage != tt.stage {
+				t.Errorf("expected stage %s, got %s (%s)", tt.stage, status.Stage, status.Message)
+			}
+			if !strings.Contains(status.Message, tt.message) {
+				t.Errorf("expected message %q, got %q", tt.message, status.Message)
+			}
+
+			if status.Stage == api.MajorUpgradeStagePreflight {
+				job := &batchv1.Job{}
+				err := r.client.Get(context.TODO(), types.NamespacedName{Name: "cluster1-upgrade-check", Namespace: "ns"}, job)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(tt.objects) == 0 && job.Spec.Template.Spec.Containers[0].Image != "mysql/mysql-server:8.0" {
+					t.Errorf("got upgrade checker image %s", job.Spec.Template.Spec.Containers[0].Image)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package pxc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/queries"
+)
+
+// membersCheckInterval is how often the Galera status of the pods is collected.
+// The previous result is kept in the status in between.
+const membersCheckInterval = 30 * time.Second
+
+// timeouts of the members status queries, so an unresponsive pod
+// doesn't block the status update
+const (
+	membersDialTimeout = 10 * time.Second
+	membersReadTimeout = 30 * time.Second
+)
+
+// pxcMembers collects the Galera status of every PXC pod.
+// Errors don't fail the status update, they are reported in the member message.
+// prev is returned as is if the status was collected less than
+// membersCheckInterval ago.
+func (r *ReconcilePerconaXtraDBCluster) pxcMembers(cr *api.PerconaXtraDBCluster, prev []api.PXCMemberStatus) []api.PXCMemberStatus {
+	key := cr.Namespace + "/" + cr.Name
+	if last, ok := r.membersChecked.Load(key); ok && prev != nil && time.Since(last.(time.Time)) < membersCheckInterval {
+		return prev
+	}
+	r.membersChecked.Store(key, time.Now())
+
+	logger := r.logger(cr.Name, cr.Namespace)
+
+	node := statefulset.NewNode(cr)
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(node.Labels()),
+		},
+	)
+	if err != nil {
+		logger.Error(err, "get pxc pods list")
+		return nil
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return podOrdinal(list.Items[i].Name) < podOrdinal(list.Items[j].Name) })
+
+	primary := ""
+	if (cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled && cr.Status.HAProxy.Status == api.AppStateReady) ||
+		(cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled && cr.Status.ProxySQL.Status == api.AppStateReady) {
+		primary, err = r.getPrimaryPod(cr)
+		if err != nil {
+			logger.Error(err, "get primary pod")
+		}
+	}
+
+	members := make([]api.PXCMemberStatus, 0, len(list.Items))
+	for _, pod := range list.Items {
+		member := api.PXCMemberStatus{
+			Name:   pod.Name,
+			Writer: isWriter(primary, pod),
+		}
+
+		if !isContainersReady(pod) {
+			member.Message = "pod isn't ready"
+			members = append(members, member)
+			continue
+		}
+
+		database, err := r.pxcDB(cr, pod.Name, queries.WithTimeouts(membersDialTimeout, membersReadTimeout))
+		if err != nil {
+			member.Message = "connect: " + err.Error()
+			members = append(members, member)
+			continue
+		}
+
+		status, err := database.WsrepStatus()
+		database.Close()
+		if err != nil {
+			member.Message = "get wsrep status: " + err.Error()
+			members = append(members, member)
+			continue
+		}
+
+		setWsrepStatus(&member, status)
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// isWriter returns true if the primary reported by the proxy is the pod.
+// The primary is the pod IP or its hostname.
+func isWriter(primary string, pod corev1.Pod) bool {
+	return len(primary) > 0 && (primary == pod.Status.PodIP || primary == pod.Name || strings.HasPrefix(primary, pod.Name+"."))
+}
+
+// setWsrepStatus fills the member status from the wsrep_% status variables
+func setWsrepStatus(member *api.PXCMemberStatus, status map[string]string) {
+	member.State = status["wsrep_local_state_comment"]
+	member.ClusterStatus = status["wsrep_cluster_status"]
+	member.FlowControlPaused = status["wsrep_flow_control_paused"]
+	member.Seqno, _ = strconv.ParseInt(status["wsrep_last_committed"], 10, 64)
+	member.RecvQueue, _ = strconv.ParseInt(status["wsrep_local_recv_queue"], 10, 64)
+}
+
+func isContainersReady(pod corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.ContainersReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podOrdinal returns the ordinal of the statefulset pod, e.g. 10 for cluster1-pxc-10
+func podOrdinal(name string) int {
+	n, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package pxc
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestPodOrdinalSort(t *testing.T) {
+	pods := []string{"cluster1-pxc-10", "cluster1-pxc-2", "cluster1-pxc-0", "cluster1-pxc-1"}
+	sort.Slice(pods, func(i, j int) bool { return podOrdinal(pods[i]) < podOrdinal(pods[j]) })
+
+	expected := []string{"cluster1-pxc-0", "cluster1-pxc-1", "cluster1-pxc-2", "cluster1-pxc-10"}
+	for i := range pods {
+		if pods[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, pods)
+		}
+	}
+}
+
+func TestSetWsrepStatus(t *testing.T) {
+	member := api.PXCMemberStatus{Name: "cluster1-pxc-1"}
+	setWsrepStatus(&member, map[string]string{
+		"wsrep_local_state_comment": "Donor/Desynced",
+		"wsrep_cluster_status":      "Primary",
+		"wsrep_flow_control_paused": "0.012",
+		"wsrep_last_committed":      "1024",
+		"wsrep_local_recv_queue":    "3",
+		"wsrep_ready":               "ON",
+	})
+
+	expected := api.PXCMemberStatus{
+		Name:              "cluster1-pxc-1",
+		State:             "Donor/Desynced",
+		ClusterStatus:     "Primary",
+		FlowControlPaused: "0.012",
+		Seqno:             1024,
+		RecvQueue:         3,
+	}
+	if !reflect.DeepEqual(member, expected) {
+		t.Errorf("expected %+v, got %+v", expected, member)
+	}
+}
+
+func TestIsWriter(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1-pxc-1"},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.11"},
+	}
+
+	cases := map[string]bool{
+		"":                                 false,
+		"10.0.0.11":                        true,
+		"10.0.0.111":                       false,
+		"cluster1-pxc-1":                   true,
+		"cluster1-pxc-1.cluster1-pxc.pxc":  true,
+		"cluster1-pxc-10.cluster1-pxc.pxc": false,
+		"cluster1-pxc-11":                  false,
+	}
+	for primary, writer := range cases {
+		if isWriter(primary, pod) != writer {
+			t.Errorf("primary %q: expected writer=%v", primary, writer)
+		}
+	}
+}
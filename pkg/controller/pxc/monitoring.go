@@ -0,0 +1,148 @@
+package pxc
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+)
+
+// reconcileMonitoring creates the services for the metrics exporters
+// and the ServiceMonitors for them if the Prometheus Operator is installed
+func (r *ReconcilePerconaXtraDBCluster) reconcileMonitoring(cr *api.PerconaXtraDBCluster) error {
+	// nothing was ever configured, so there is nothing to clean up
+	if cr.Spec.Metrics == nil {
+		return nil
+	}
+
+	targets := []struct {
+		component string
+		port      int32
+		enabled   bool
+	}{
+		{"pxc", app.MysqldExporterPort, true},
+		{"proxysql", app.ProxySQLExporterPort, cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled},
+		{"haproxy", app.HAProxyMetricsPort, cr.HAProxyMetricsEnabled()},
+	}
+
+	for _, t := range targets {
+		svc := pxc.NewServiceMetrics(cr, t.component, t.port)
+
+		if !cr.Spec.Metrics.Enabled || !t.enabled {
+			err := r.deleteServices([]*corev1.Service{svc})
+			if err != nil {
+				return errors.Wrapf(err, "delete %s metrics service", t.component)
+			}
+			err = r.deleteServiceMonitor(svc)
+			if err != nil {
+				return errors.Wrapf(err, "delete %s service monitor", t.component)
+			}
+			continue
+		}
+
+		err := r.createService(cr, svc)
+		if err != nil {
+			return errors.Wrapf(err, "create %s metrics service", t.component)
+		}
+
+		sm := cr.Spec.Metrics.ServiceMonitor
+		if sm == nil || !sm.Enabled {
+			err = r.deleteServiceMonitor(svc)
+		} else {
+			err = r.reconcileServiceMonitor(cr, svc, sm)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s service monitor", t.component)
+		}
+	}
+
+	return nil
+}
+
+func newServiceMonitor(name, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("monitoring.coreos.com/v1")
+	obj.SetKind("ServiceMonitor")
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+func (r *ReconcilePerconaXtraDBCluster) reconcileServiceMonitor(cr *api.PerconaXtraDBCluster, svc *corev1.Service, spec *api.ServiceMonitorSpec) error {
+	matchLabels := make(map[string]interface{}, len(svc.Labels))
+	for k, v := range svc.Labels {
+		matchLabels[k] = v
+	}
+	endpoint := map[string]interface{}{
+		"port": app.MetricsPortName,
+		"path": "/metrics",
+	}
+	if len(spec.Interval) > 0 {
+		endpoint["interval"] = spec.Interval
+	}
+	smSpec := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{svc.Namespace},
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+
+	labels := make(map[string]string, len(svc.Labels)+len(spec.Labels))
+	for k, v := range svc.Labels {
+		labels[k] = v
+	}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+
+	current := newServiceMonitor(svc.Name, svc.Namespace)
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, current)
+	if meta.IsNoMatchError(err) {
+		// Prometheus Operator CRDs aren't installed
+		return nil
+	}
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "get")
+	}
+
+	if k8serrors.IsNotFound(err) {
+		obj := newServiceMonitor(svc.Name, svc.Namespace)
+		obj.SetLabels(labels)
+		obj.Object["spec"] = smSpec
+		err = setControllerReference(cr, obj, r.scheme)
+		if err != nil {
+			return errors.Wrap(err, "setControllerReference")
+		}
+		return errors.WithMessage(r.client.Create(context.TODO(), obj), "create")
+	}
+
+	if reflect.DeepEqual(current.Object["spec"], smSpec) && reflect.DeepEqual(current.GetLabels(), labels) {
+		return nil
+	}
+
+	current.SetLabels(labels)
+	current.Object["spec"] = smSpec
+
+	return errors.WithMessage(r.client.Update(context.TODO(), current), "update")
+}
+
+func (r *ReconcilePerconaXtraDBCluster) deleteServiceMonitor(svc *corev1.Service) error {
+	err := r.client.Delete(context.TODO(), newServiceMonitor(svc.Name, svc.Namespace))
+	if err != nil && !k8serrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+
+	return nil
+}
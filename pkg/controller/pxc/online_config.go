@@ -0,0 +1,171 @@
+package pxc
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// configHashAnnotation on the PXC config map holds the hash of the
+// configuration the pods were restarted with the last time
+const configHashAnnotation = "percona.com/configuration-hash"
+
+// reconcilePXCConfigMap writes PXC.Configuration to the config map.
+// If only dynamic variables were changed, they are set online on all pods
+// and the configuration hash is kept, so the pods aren't restarted.
+func (r *ReconcilePerconaXtraDBCluster) reconcilePXCConfigMap(cr *api.PerconaXtraDBCluster, configMap *corev1.ConfigMap) error {
+	current := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: configMap.Namespace, Name: configMap.Name}, current)
+	if k8serrors.IsNotFound(err) {
+		configMap.Annotations = map[string]string{
+			configHashAnnotation: fmt.Sprintf("%x", md5.Sum([]byte(cr.Spec.PXC.Configuration))),
+		}
+		return r.client.Create(context.TODO(), configMap)
+	}
+	if err != nil {
+		return errors.Wrap(err, "get current configmap")
+	}
+
+	hash := r.applyConfigurationChanges(cr, current, fmt.Sprintf("%x", md5.Sum([]byte(cr.Spec.PXC.Configuration))))
+	if reflect.DeepEqual(current.Data, configMap.Data) && current.Annotations[configHashAnnotation] == hash {
+		return nil
+	}
+
+	if current.Annotations == nil {
+		current.Annotations = make(map[string]string)
+	}
+	current.Annotations[configHashAnnotation] = hash
+	current.Data = configMap.Data
+
+	return r.client.Update(context.TODO(), current)
+}
+
+// applyConfigurationChanges compares the configuration in the config map
+// with the new one and returns the configuration hash for the pods.
+// The hash is changed if the pods have to be restarted.
+func (r *ReconcilePerconaXtraDBCluster) applyConfigurationChanges(cr *api.PerconaXtraDBCluster, current *corev1.ConfigMap, newHash string) string {
+	logger := r.logger(cr.Name, cr.Namespace)
+
+	oldConf := current.Data["init.cnf"]
+	oldHash := current.Annotations[configHashAnnotation]
+	if len(oldHash) == 0 {
+		oldHash = fmt.Sprintf("%x", md5.Sum([]byte(oldConf)))
+	}
+
+	diff, err := config.DiffConfiguration(oldConf, cr.Spec.PXC.Configuration)
+	if err != nil {
+		logger.Error(err, "compare configurations")
+		return newHash
+	}
+	if diff.Empty() {
+		return oldHash
+	}
+
+	dynamic := make([]string, 0, len(diff.Dynamic))
+	for name := range diff.Dynamic {
+		dynamic = append(dynamic, name)
+	}
+	sort.Strings(dynamic)
+
+	if len(diff.Static) > 0 {
+		cr.Status.ConfigChanges = &api.ConfigChangesStatus{
+			RestartRequired: append(diff.Static, dynamic...),
+		}
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventConfigurationRestartRequired,
+			"Options %s can't be changed online, restarting pods", strings.Join(diff.Static, ", "))
+		return newHash
+	}
+
+	err = r.setGlobalVariables(cr, dynamic, diff.Dynamic)
+	if err != nil {
+		cr.Status.ConfigChanges = &api.ConfigChangesStatus{
+			RestartRequired: dynamic,
+		}
+		r.recorder.Eventf(cr, corev1.EventTypeWarning, EventConfigurationRestartRequired,
+			"Failed to apply variables %s online, restarting pods: %v", strings.Join(dynamic, ", "), err)
+		return newHash
+	}
+
+	cr.Status.ConfigChanges = &api.ConfigChangesStatus{
+		AppliedOnline: dynamic,
+	}
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventConfigurationAppliedOnline,
+		"Variables %s were applied online without restart", strings.Join(dynamic, ", "))
+
+	return oldHash
+}
+
+// setGlobalVariables sets the variables on all PXC pods. It fails if any pod
+// isn't ready, as the pod could read the old configuration while starting.
+func (r *ReconcilePerconaXtraDBCluster) setGlobalVariables(cr *api.PerconaXtraDBCluster, names []string, values map[string]interface{}) error {
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(statefulset.NewNode(cr).Labels()),
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "get pxc pods list")
+	}
+
+	for _, pod := range list.Items {
+		if !isContainersReady(pod) {
+			return errors.Errorf("pod %s isn't ready", pod.Name)
+		}
+	}
+
+	for _, pod := range list.Items {
+		database, err := r.pxcDB(cr, pod.Name)
+		if err != nil {
+			return errors.Wrapf(err, "connect to pod %s", pod.Name)
+		}
+
+		for _, name := range names {
+			err = database.SetGlobal(name, values[name])
+			if err != nil {
+				database.Close()
+				return errors.Wrapf(err, "set %s on pod %s", name, pod.Name)
+			}
+		}
+		database.Close()
+	}
+
+	return nil
+}
+
+// appliedConfigHash returns the hash of the configuration the PXC pods
+// have to be restarted with
+func (r *ReconcilePerconaXtraDBCluster) appliedConfigHash(cr *api.PerconaXtraDBCluster) string {
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(cr.Spec.PXC.Configuration)))
+	if len(cr.Spec.PXC.Configuration) == 0 {
+		return hash
+	}
+
+	ls := statefulset.NewNode(cr).Labels()
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{
+		Namespace: cr.Namespace,
+		Name:      ls["app.kubernetes.io/instance"] + "-" + ls["app.kubernetes.io/component"],
+	}, cm)
+	if err != nil || len(cm.Annotations[configHashAnnotation]) == 0 {
+		return hash
+	}
+
+	return cm.Annotations[configHashAnnotation]
+}
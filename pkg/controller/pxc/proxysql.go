@@ -0,0 +1,247 @@
+package pxc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/queries"
+)
+
+// proxySQLExcludedUsers are the system users of PXC that aren't added to ProxySQL
+var proxySQLExcludedUsers = []string{"monitor", "operator", "clustercheck", "xtrabackup", "replication", "pmmserver", "proxyadmin"}
+
+// proxySQLConfig is the configuration the operator keeps in the ProxySQL admin tables
+type proxySQLConfig struct {
+	servers    []queries.ProxySQLServer
+	hostgroups queries.GaleraHostgroups
+	rules      []queries.ProxySQLQueryRule
+	// users are nil if they can't be read from PXC, so they are left as is
+	users []queries.ProxySQLUser
+}
+
+// reconcileProxySQL applies the servers, the hostgroups, the query rules and
+// the PXC users to every ready ProxySQL pod. The changed tables are loaded to
+// runtime and saved to disk, so they survive the restart of ProxySQL.
+func (r *ReconcilePerconaXtraDBCluster) reconcileProxySQL(cr *api.PerconaXtraDBCluster) error {
+	if !cr.ProxySQLManaged() {
+		cr.Status.QueryRules = nil
+		cr.Status.RemoveCondition(api.ClusterProxySQLConfigured)
+		return nil
+	}
+
+	conf := desiredProxySQLConfig(cr)
+
+	if cr.Status.PXC.Ready > 0 {
+		users, err := r.pxcUsers(cr, conf.hostgroups.Writer)
+		if err != nil {
+			r.logger(cr.Name, cr.Namespace).Error(err, "get PXC users for ProxySQL")
+		}
+		conf.users = users
+	}
+
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(statefulset.NewProxy(cr).Labels()),
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "get proxysql pod list")
+	}
+
+	var failed []string
+	hits := make(map[int]int64, len(conf.rules))
+	for _, pod := range list.Items {
+		if !isContainersReady(pod) {
+			continue
+		}
+		podConf := conf
+		if cr.Spec.ProxySQL.ZoneAwareReads && pod.Spec.NodeName != "" {
+			zone, err := r.nodeZone(cr, pod.Spec.NodeName)
+			if err != nil {
+				r.logger(cr.Name, cr.Namespace).Error(err, "get zone of proxysql pod", "pod", pod.Name)
+			}
+			podConf.servers = zoneReaders(cr, conf, zone)
+		}
+		podHits, err := r.applyProxySQLConfig(cr, pod.Name, podConf)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", pod.Name, err))
+			continue
+		}
+		for id, n := range podHits {
+			hits[id] += n
+		}
+	}
+
+	cr.Status.QueryRules = nil
+	for _, rule := range conf.rules {
+		cr.Status.QueryRules = append(cr.Status.QueryRules, api.QueryRuleStatus{RuleID: rule.RuleID, Hits: hits[rule.RuleID]})
+	}
+
+	if len(failed) > 0 {
+		r.setCondition(cr, boolCondition(api.ClusterProxySQLConfigured, false, "ConfigurationFailed", strings.Join(failed, "; ")))
+		return errors.Errorf("configure proxysql: %s", strings.Join(failed, "; "))
+	}
+	r.setCondition(cr, boolCondition(api.ClusterProxySQLConfigured, true, "ConfigurationApplied", ""))
+
+	return nil
+}
+
+func desiredProxySQLConfig(cr *api.PerconaXtraDBCluster) proxySQLConfig {
+	hg := cr.Spec.ProxySQL.Hostgroups
+	conf := proxySQLConfig{
+		hostgroups: queries.GaleraHostgroups{
+			Writer:                hg.Writer,
+			BackupWriter:          hg.BackupWriter,
+			Reader:                hg.Reader,
+			Offline:               hg.Offline,
+			MaxWriters:            hg.MaxWriters,
+			WriterIsAlsoReader:    hg.WriterIsAlsoReader,
+			MaxTransactionsBehind: hg.MaxTransactionsBehind,
+		},
+	}
+
+	// ProxySQL moves the servers from the writer hostgroup by their Galera state
+	for i := 0; i < int(cr.Spec.PXC.Size); i++ {
+		conf.servers = append(conf.servers, queries.ProxySQLServer{
+			Hostgroup:      hg.Writer,
+			Hostname:       fmt.Sprintf("%s-pxc-%d.%s-pxc.%s", cr.Name, i, cr.Name, cr.Namespace),
+			Port:           3306,
+			Weight:         1000,
+			MaxConnections: 1000,
+			UseSSL:         !cr.Spec.AllowUnsafeConfig,
+		})
+	}
+	sort.Slice(conf.servers, func(i, j int) bool {
+		return conf.servers[i].Hostname < conf.servers[j].Hostname
+	})
+
+	for _, rule := range cr.Spec.ProxySQL.QueryRules {
+		conf.rules = append(conf.rules, queries.ProxySQLQueryRule{
+			RuleID:               rule.RuleID,
+			Active:               rule.Active == nil || *rule.Active,
+			Username:             rule.Username,
+			SchemaName:           rule.SchemaName,
+			FlagIn:               rule.FlagIn,
+			MatchDigest:          rule.MatchDigest,
+			MatchPattern:         rule.MatchPattern,
+			NegateMatchPattern:   rule.NegateMatchPattern,
+			FlagOut:              rule.FlagOut,
+			DestinationHostgroup: rule.DestinationHostgroup,
+			Apply:                rule.Apply,
+			Comment:              rule.Comment,
+		})
+	}
+	sort.Slice(conf.rules, func(i, j int) bool {
+		return conf.rules[i].RuleID < conf.rules[j].RuleID
+	})
+
+	return conf
+}
+
+// zoneReaders adds the PXC pods to the reader hostgroup with the weights
+// preferring the zone of the ProxySQL pod. The writer is chosen by the
+// weights in the writer hostgroup, which are the same for all ProxySQL pods.
+func zoneReaders(cr *api.PerconaXtraDBCluster, conf proxySQLConfig, zone string) []queries.ProxySQLServer {
+	if len(zone) == 0 {
+		return conf.servers
+	}
+
+	servers := append([]queries.ProxySQLServer(nil), conf.servers...)
+	for _, t := range cr.Status.Topology {
+		weight := 1
+		if t.Zone == zone {
+			weight = 1000
+		}
+		servers = append(servers, queries.ProxySQLServer{
+			Hostgroup:      conf.hostgroups.Reader,
+			Hostname:       t.Pod + "." + cr.Name + "-pxc." + cr.Namespace,
+			Port:           3306,
+			Weight:         weight,
+			MaxConnections: 1000,
+			UseSSL:         !cr.Spec.AllowUnsafeConfig,
+		})
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Hostgroup != servers[j].Hostgroup {
+			return servers[i].Hostgroup < servers[j].Hostgroup
+		}
+		return servers[i].Hostname < servers[j].Hostname
+	})
+
+	return servers
+}
+
+// pxcUsers returns the PXC users with the writer as the default hostgroup
+func (r *ReconcilePerconaXtraDBCluster) pxcUsers(cr *api.PerconaXtraDBCluster, writer int) ([]queries.ProxySQLUser, error) {
+	var lastErr error
+	for i := 0; i < int(cr.Spec.PXC.Size); i++ {
+		database, err := r.pxcDB(cr, fmt.Sprintf("%s-pxc-%d", cr.Name, i))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		users, err := database.NativePasswordUsers(proxySQLExcludedUsers)
+		database.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "get users")
+		}
+		for i := range users {
+			users[i].DefaultHostgroup = writer
+		}
+		if users == nil {
+			users = []queries.ProxySQLUser{}
+		}
+		return users, nil
+	}
+
+	return nil, errors.Wrap(lastErr, "connect to PXC")
+}
+
+// applyProxySQLConfig configures the ProxySQL pod and returns the hits of its query rules
+func (r *ReconcilePerconaXtraDBCluster) applyProxySQLConfig(cr *api.PerconaXtraDBCluster, pod string, conf proxySQLConfig) (map[int]int64, error) {
+	database, err := queries.New(credentials.NewSecret(r.client, cr.Namespace, "internal-"+cr.Name),
+		"proxyadmin", pod+"."+cr.Name+"-proxysql-unready."+cr.Namespace, 6032)
+	if err != nil {
+		return nil, errors.Wrap(err, "connect")
+	}
+	defer database.Close()
+
+	err = database.SetGaleraHostgroups(conf.hostgroups)
+	if err != nil {
+		return nil, errors.Wrap(err, "set galera hostgroups")
+	}
+	err = database.SetProxySQLServers(conf.servers)
+	if err != nil {
+		return nil, errors.Wrap(err, "set servers")
+	}
+	err = database.SetProxySQLQueryRules(conf.rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "set query rules")
+	}
+	if conf.users != nil {
+		err = database.SetProxySQLUsers(conf.users)
+		if err != nil {
+			return nil, errors.Wrap(err, "set users")
+		}
+	}
+
+	hits, err := database.QueryRuleHits()
+	if err != nil {
+		return nil, errors.Wrap(err, "get query rules hits")
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,43 @@
+package pxc
+
+import (
+	"testing"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestDesiredProxySQLConfig(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{}
+	cr.Name = "cluster1"
+	cr.Namespace = "pxc"
+	cr.Spec.CRVersion = "1.8.0"
+	cr.Spec.PXC = &api.PXCSpec{PodSpec: &api.PodSpec{Size: 3}}
+	cr.Spec.ProxySQL = &api.PodSpec{
+		Enabled:    true,
+		Hostgroups: &api.ProxySQLHostgroups{Writer: 20, BackupWriter: 21, Reader: 22, Offline: 23, MaxWriters: 1},
+		QueryRules: []api.ProxySQLQueryRule{
+			{RuleID: 200, MatchDigest: "^SELECT", DestinationHostgroup: 22, Apply: true},
+			{RuleID: 100, MatchDigest: "^SELECT.*FOR UPDATE", DestinationHostgroup: 20, Apply: true},
+		},
+	}
+
+	conf := desiredProxySQLConfig(cr)
+
+	if len(conf.servers) != 3 {
+		t.Fatalf("got %d servers, expected 3", len(conf.servers))
+	}
+	for _, s := range conf.servers {
+		if s.Hostgroup != 20 {
+			t.Errorf("server %s is in hostgroup %d, expected the writer hostgroup", s.Hostname, s.Hostgroup)
+		}
+	}
+	if host := "cluster1-pxc-0.cluster1-pxc.pxc"; conf.servers[0].Hostname != host {
+		t.Errorf("got server %s, expected %s", conf.servers[0].Hostname, host)
+	}
+	if conf.rules[0].RuleID != 100 || conf.rules[1].RuleID != 200 {
+		t.Errorf("query rules aren't ordered by rule id: %+v", conf.rules)
+	}
+	if conf.hostgroups.Reader != 22 {
+		t.Errorf("got reader hostgroup %d, expected 22", conf.hostgroups.Reader)
+	}
+}
@@ -0,0 +1,353 @@
+package pxc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// crashLoopRestarts is the number of restarts after which the crash-looping
+// pod of the new revision fails the upgrade
+const crashLoopRestarts = 3
+
+func rollbackEnabled(cr *api.PerconaXtraDBCluster) bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 && cr.Spec.UpgradeOptions.Rollback
+}
+
+// rollbackBlocker returns the reason why the upgrade can't be rolled back
+// automatically or an empty string. The data directory upgraded to the next
+// major version of PXC can't be used by the previous one. The configuration
+// isn't kept in the known-good state, so the upgrade that came with another
+// configuration hash isn't rolled back either.
+func rollbackBlocker(cr *api.PerconaXtraDBCluster, good *api.KnownGoodState, configHash string) string {
+	if s := cr.Status.MajorUpgrade; s != nil && s.Stage != api.MajorUpgradeStageCompleted {
+		return fmt.Sprintf("major upgrade to PXC %s is in progress", s.To)
+	}
+
+	if len(good.ConfigHash) > 0 && len(configHash) > 0 && configHash != good.ConfigHash {
+		return "PXC configuration was changed since the last known-good state"
+	}
+
+	if cr.Spec.PXC == nil {
+		return ""
+	}
+	from := config.ImageMajorVersion(good.PXCImage)
+	to := config.ImageMajorVersion(cr.Spec.PXC.Image)
+	if len(from) > 0 && len(to) > 0 && from != to {
+		return fmt.Sprintf("PXC can't be rolled back from %s to %s", to, from)
+	}
+
+	return ""
+}
+
+// knownGoodState returns the current state of the cluster if the spec is
+// applied to all PXC pods and they are ready. Otherwise it returns nil.
+func (r *ReconcilePerconaXtraDBCluster) knownGoodState(cr *api.PerconaXtraDBCluster) (*api.KnownGoodState, error) {
+	sfs := statefulset.NewNode(cr).StatefulSet()
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: sfs.Name, Namespace: sfs.Namespace}, sfs)
+	if err != nil {
+		return nil, errors.Wrap(err, "get pxc statefulset")
+	}
+
+	status := sfs.Status
+	if status.ObservedGeneration < sfs.Generation ||
+		status.UpdatedReplicas < status.Replicas ||
+		status.ReadyReplicas < status.Replicas {
+		return nil, nil
+	}
+	for _, c := range sfs.Spec.Template.Spec.Containers {
+		if c.Name == app.Name && c.Image != cr.Spec.PXC.Image {
+			return nil, nil
+		}
+	}
+
+	state := &api.KnownGoodState{
+		CRVersion:  cr.Spec.CRVersion,
+		PXCImage:   cr.Spec.PXC.Image,
+		ConfigHash: sfs.Spec.Template.Annotations["percona.com/configuration-hash"],
+	}
+	if cr.Spec.ProxySQL != nil {
+		state.ProxySQLImage = cr.Spec.ProxySQL.Image
+	}
+	if cr.Spec.HAProxy != nil {
+		state.HAProxyImage = cr.Spec.HAProxy.Image
+	}
+	if cr.Spec.Backup != nil {
+		state.BackupImage = cr.Spec.Backup.Image
+	}
+	if cr.Spec.PMM != nil {
+		state.PMMImage = cr.Spec.PMM.Image
+	}
+	if cr.Spec.LogCollector != nil {
+		state.LogCollectorImage = cr.Spec.LogCollector.Image
+	}
+
+	return state, nil
+}
+
+// recordKnownGood keeps the state of the ready cluster in the status,
+// so the next upgrade can be rolled back to it
+func (r *ReconcilePerconaXtraDBCluster) recordKnownGood(cr *api.PerconaXtraDBCluster) error {
+	if !rollbackEnabled(cr) || cr.Status.Status != api.AppStateReady {
+		return nil
+	}
+
+	state, err := r.knownGoodState(cr)
+	if err != nil || state == nil {
+		return err
+	}
+
+	last := cr.Status.LastKnownGood
+	if last != nil && *last != *state && cr.Status.FindCondition(api.ClusterUpgradeRolledBack) != nil {
+		r.setCondition(cr, boolCondition(api.ClusterUpgradeRolledBack, false, "UpgradeSucceeded", ""))
+	}
+	cr.Status.LastKnownGood = state
+
+	return nil
+}
+
+// upgraded returns true if the images or the CR version differ from the known-good state
+func upgraded(spec *api.PerconaXtraDBClusterSpec, good *api.KnownGoodState) bool {
+	changed := func(image, goodImage string) bool {
+		return len(goodImage) > 0 && image != goodImage
+	}
+
+	switch {
+	case changed(spec.CRVersion, good.CRVersion),
+		spec.PXC != nil && changed(spec.PXC.Image, good.PXCImage),
+		spec.ProxySQL != nil && changed(spec.ProxySQL.Image, good.ProxySQLImage),
+		spec.HAProxy != nil && changed(spec.HAProxy.Image, good.HAProxyImage),
+		spec.Backup != nil && changed(spec.Backup.Image, good.BackupImage),
+		spec.PMM != nil && changed(spec.PMM.Image, good.PMMImage),
+		spec.LogCollector != nil && changed(spec.LogCollector.Image, good.LogCollectorImage):
+		return true
+	}
+
+	return false
+}
+
+// failedUpgrade returns the CR version and the images of the spec
+// that differ from the known-good state
+func failedUpgrade(spec *api.PerconaXtraDBClusterSpec, good *api.KnownGoodState) *api.KnownGoodState {
+	failed := &api.KnownGoodState{}
+	record := func(failedImage *string, image, goodImage string) {
+		if len(goodImage) > 0 && image != goodImage {
+			*failedImage = image
+		}
+	}
+
+	record(&failed.CRVersion, spec.CRVersion, good.CRVersion)
+	if spec.PXC != nil {
+		record(&failed.PXCImage, spec.PXC.Image, good.PXCImage)
+	}
+	if spec.ProxySQL != nil {
+		record(&failed.ProxySQLImage, spec.ProxySQL.Image, good.ProxySQLImage)
+	}
+	if spec.HAProxy != nil {
+		record(&failed.HAProxyImage, spec.HAProxy.Image, good.HAProxyImage)
+	}
+	if spec.Backup != nil {
+		record(&failed.BackupImage, spec.Backup.Image, good.BackupImage)
+	}
+	if spec.PMM != nil {
+		record(&failed.PMMImage, spec.PMM.Image, good.PMMImage)
+	}
+	if spec.LogCollector != nil {
+		record(&failed.LogCollectorImage, spec.LogCollector.Image, good.LogCollectorImage)
+	}
+
+	return failed
+}
+
+// failedUpgradeImage returns the image of the version that was rolled back
+// before or an empty string
+func failedUpgradeImage(failed *api.KnownGoodState, v DepVersion) string {
+	if failed == nil {
+		return ""
+	}
+
+	for _, image := range [][2]string{
+		{failed.PXCImage, v.PXCImage},
+		{failed.ProxySQLImage, v.ProxySqlImage},
+		{failed.HAProxyImage, v.HAProxyImage},
+		{failed.BackupImage, v.BackupImage},
+		{failed.PMMImage, v.PMMImage},
+		{failed.LogCollectorImage, v.LogCollectorImage},
+	} {
+		if len(image[0]) > 0 && image[0] == image[1] {
+			return image[0]
+		}
+	}
+
+	return ""
+}
+
+func revertSpec(spec *api.PerconaXtraDBClusterSpec, good *api.KnownGoodState) {
+	revert := func(image *string, goodImage string) {
+		if len(goodImage) > 0 {
+			*image = goodImage
+		}
+	}
+
+	revert(&spec.CRVersion, good.CRVersion)
+	if spec.PXC != nil {
+		revert(&spec.PXC.Image, good.PXCImage)
+	}
+	if spec.ProxySQL != nil {
+		revert(&spec.ProxySQL.Image, good.ProxySQLImage)
+	}
+	if spec.HAProxy != nil {
+		revert(&spec.HAProxy.Image, good.HAProxyImage)
+	}
+	if spec.Backup != nil {
+		revert(&spec.Backup.Image, good.BackupImage)
+	}
+	if spec.PMM != nil {
+		revert(&spec.PMM.Image, good.PMMImage)
+	}
+	if spec.LogCollector != nil {
+		revert(&spec.LogCollector.Image, good.LogCollectorImage)
+	}
+}
+
+// rollbackUpgrade reverts the CR and the PXC statefulset template to the
+// last known-good state if the health gates of SmartUpdate failed.
+// The not ready pods of the failed revision are deleted to be recreated
+// from the reverted template, the rest are updated by the next SmartUpdate.
+func (r *ReconcilePerconaXtraDBCluster) rollbackUpgrade(cr *api.PerconaXtraDBCluster, sfsApp api.StatefulApp, reason string) error {
+	good := cr.Status.LastKnownGood
+	if !rollbackEnabled(cr) || good == nil || !upgraded(&cr.Spec, good) {
+		return nil
+	}
+
+	sfs := sfsApp.StatefulSet()
+
+	logger := r.logger(cr.Name, cr.Namespace)
+	failedRevision := sfs.Status.UpdateRevision
+
+	if blocker := rollbackBlocker(cr, good, sfs.Spec.Template.Annotations["percona.com/configuration-hash"]); len(blocker) > 0 {
+		logger.Info("upgrade isn't rolled back", "revision", failedRevision, "reason", blocker)
+		if c := cr.Status.FindCondition(api.ClusterUpgradeRolledBack); c == nil || c.Reason != "RollbackBlocked" {
+			r.recorder.Eventf(cr, corev1.EventTypeWarning, EventUpgradeRollbackBlocked,
+				"Upgrade isn't rolled back: %s. Fix the cluster manually", blocker)
+		}
+		r.setCondition(cr, boolCondition(api.ClusterUpgradeRolledBack, false, "RollbackBlocked",
+			fmt.Sprintf("revision %s of statefulset %s failed: %s; %s", failedRevision, sfs.Name, reason, blocker)))
+		return nil
+	}
+
+	logger.Info("rolling back the upgrade", "revision", failedRevision, "reason", reason)
+
+	failed := failedUpgrade(&cr.Spec, good)
+
+	// the CR is reverted too, otherwise the next reconcile applies the new images again
+	current := &api.PerconaXtraDBCluster{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current)
+	if err != nil {
+		return errors.Wrap(err, "get cluster")
+	}
+	revertSpec(&current.Spec, good)
+	err = r.client.Update(context.TODO(), current)
+	if err != nil {
+		return errors.Wrap(err, "update cluster")
+	}
+	revertSpec(&cr.Spec, good)
+	cr.ResourceVersion = current.ResourceVersion
+
+	currentSet := &appsv1.StatefulSet{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: sfs.Name, Namespace: sfs.Namespace}, currentSet)
+	if err != nil {
+		return errors.Wrap(err, "get statefulset")
+	}
+	for i := range currentSet.Spec.Template.Spec.Containers {
+		c := &currentSet.Spec.Template.Spec.Containers[i]
+		switch c.Name {
+		case app.Name:
+			c.Image = good.PXCImage
+		case "pmm-client":
+			if len(good.PMMImage) > 0 {
+				c.Image = good.PMMImage
+			}
+		case "logs", "logrotate":
+			if len(good.LogCollectorImage) > 0 {
+				c.Image = good.LogCollectorImage
+			}
+		}
+	}
+	err = r.client.Update(context.TODO(), currentSet)
+	if err != nil {
+		return errors.Wrap(err, "update statefulset")
+	}
+
+	list := corev1.PodList{}
+	err = r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     sfs.Namespace,
+			LabelSelector: labels.SelectorFromSet(sfsApp.Labels()),
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "get pod list")
+	}
+	for _, pod := range list.Items {
+		if pod.Labels["controller-revision-hash"] != failedRevision || isContainersReady(pod) {
+			continue
+		}
+		logger.Info("delete failed pod", "pod name", pod.Name)
+		err = r.client.Delete(context.TODO(), &pod)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete pod %s", pod.Name)
+		}
+	}
+
+	cr.Status.FailedUpgrade = failed
+	r.setCondition(cr, boolCondition(api.ClusterUpgradeRolledBack, true, "HealthChecksFailed",
+		fmt.Sprintf("revision %s of statefulset %s is rolled back: %s", failedRevision, sfs.Name, reason)))
+	r.recorder.Eventf(cr, corev1.EventTypeWarning, EventUpgradeRollback,
+		"Upgrade is rolled back to crVersion %s and PXC image %s: %s", good.CRVersion, good.PXCImage, reason)
+
+	return nil
+}
+
+// crashLoopingPod returns the name of the pod of the new revision whose
+// pxc container keeps crashing, or an empty string
+func (r *ReconcilePerconaXtraDBCluster) crashLoopingPod(sfs api.StatefulApp) (string, error) {
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     sfs.StatefulSet().Namespace,
+			LabelSelector: labels.SelectorFromSet(sfs.Labels()),
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "get pod list")
+	}
+
+	for _, pod := range list.Items {
+		if pod.Labels["controller-revision-hash"] != sfs.StatefulSet().Status.UpdateRevision {
+			continue
+		}
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.Name == app.Name && c.RestartCount >= crashLoopRestarts &&
+				c.State.Waiting != nil && c.State.Waiting.Reason == "CrashLoopBackOff" {
+				return pod.Name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
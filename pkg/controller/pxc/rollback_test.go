@@ -0,0 +1,121 @@
+package pxc
+
+import (
+	"testing"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestRevertSpec(t *testing.T) {
+	good := &api.KnownGoodState{
+		CRVersion:     "1.8.0",
+		PXCImage:      "percona/percona-xtradb-cluster:8.0.21-12.1",
+		HAProxyImage:  "percona/percona-xtradb-cluster-operator:1.8.0-haproxy",
+		ProxySQLImage: "percona/percona-xtradb-cluster-operator:1.8.0-proxysql",
+	}
+	spec := &api.PerconaXtraDBClusterSpec{
+		CRVersion: "1.8.0",
+		PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.22-13.1"}},
+		HAProxy:   &api.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.8.0-haproxy"},
+	}
+
+	if !upgraded(spec, good) {
+		t.Fatal("expected the upgrade to be detected")
+	}
+
+	revertSpec(spec, good)
+	if spec.PXC.Image != good.PXCImage {
+		t.Errorf("got PXC image %s, expected %s", spec.PXC.Image, good.PXCImage)
+	}
+	if spec.ProxySQL != nil {
+		t.Error("disabled ProxySQL spec shouldn't be created")
+	}
+	if upgraded(spec, good) {
+		t.Error("reverted spec is still upgraded")
+	}
+}
+
+func TestRollbackBlocker(t *testing.T) {
+	good := &api.KnownGoodState{PXCImage: "percona/percona-xtradb-cluster:5.7.32-31.47", ConfigHash: "a"}
+	cr := func(image string, status *api.MajorUpgradeStatus) *api.PerconaXtraDBCluster {
+		return &api.PerconaXtraDBCluster{
+			Spec: api.PerconaXtraDBClusterSpec{
+				PXC: &api.PXCSpec{PodSpec: &api.PodSpec{Image: image}},
+			},
+			Status: api.PerconaXtraDBClusterStatus{MajorUpgrade: status},
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		cr         *api.PerconaXtraDBCluster
+		configHash string
+		blocked    bool
+	}{
+		"minor upgrade": {
+			cr:         cr("percona/percona-xtradb-cluster:5.7.33-31.49", nil),
+			configHash: "a",
+		},
+		"minor upgrade with another configuration": {
+			cr:         cr("percona/percona-xtradb-cluster:5.7.33-31.49", nil),
+			configHash: "b",
+			blocked:    true,
+		},
+		"major upgrade": {
+			cr:      cr("percona/percona-xtradb-cluster:8.0.22-13.1", nil),
+			blocked: true,
+		},
+		"major upgrade in progress": {
+			cr: cr("percona/percona-xtradb-cluster:5.7.33-31.49",
+				&api.MajorUpgradeStatus{To: "8.0", Stage: api.MajorUpgradeStageBackup}),
+			blocked: true,
+		},
+		"major upgrade completed": {
+			cr: cr("percona/percona-xtradb-cluster:5.7.33-31.49",
+				&api.MajorUpgradeStatus{To: "8.0", Stage: api.MajorUpgradeStageCompleted}),
+		},
+	} {
+		if blocker := rollbackBlocker(tt.cr, good, tt.configHash); (len(blocker) > 0) != tt.blocked {
+			t.Errorf("%s: expected blocked=%v, got %q", name, tt.blocked, blocker)
+		}
+	}
+}
+
+func TestFailedUpgrade(t *testing.T) {
+	good := &api.KnownGoodState{
+		CRVersion:    "1.8.0",
+		PXCImage:     "percona/percona-xtradb-cluster:8.0.21-12.1",
+		HAProxyImage: "percona/percona-xtradb-cluster-operator:1.8.0-haproxy",
+	}
+	spec := &api.PerconaXtraDBClusterSpec{
+		CRVersion: "1.8.0",
+		PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.22-13.1"}},
+		HAProxy:   &api.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.8.0-haproxy"},
+	}
+
+	failed := failedUpgrade(spec, good)
+	expected := api.KnownGoodState{PXCImage: "percona/percona-xtradb-cluster:8.0.22-13.1"}
+	if *failed != expected {
+		t.Fatalf("got failed upgrade %+v, expected %+v", *failed, expected)
+	}
+
+	for name, tt := range map[string]struct {
+		version DepVersion
+		skipped bool
+	}{
+		"failed version": {
+			version: DepVersion{PXCImage: "percona/percona-xtradb-cluster:8.0.22-13.1", HAProxyImage: good.HAProxyImage},
+			skipped: true,
+		},
+		"next version": {
+			version: DepVersion{PXCImage: "percona/percona-xtradb-cluster:8.0.23-14.1", HAProxyImage: good.HAProxyImage},
+		},
+	} {
+		if image := failedUpgradeImage(failed, tt.version); (len(image) > 0) != tt.skipped {
+			t.Errorf("%s: expected skipped=%v, got %q", name, tt.skipped, image)
+		}
+	}
+
+	if image := failedUpgradeImage(nil, DepVersion{PXCImage: expected.PXCImage}); len(image) > 0 {
+		t.Errorf("got %q without the failed upgrade", image)
+	}
+}
@@ -16,6 +16,12 @@ import (
 )
 
 func (r *ReconcilePerconaXtraDBCluster) reconcileUsersSecret(cr *api.PerconaXtraDBCluster) error {
+	// credentials are managed by the external provider
+	// and shouldn't be stored in the k8s secret
+	if cr.Spec.VaultSecretsProvider() != nil {
+		return nil
+	}
+
 	secretObj := corev1.Secret{}
 	err := r.client.Get(context.TODO(),
 		types.NamespacedName{
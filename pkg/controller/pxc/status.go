@@ -7,7 +7,6 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,26 +17,12 @@ import (
 	"github.com/pkg/errors"
 )
 
-const maxStatusesQuantity = 20
-
 func (r *ReconcilePerconaXtraDBCluster) updateStatus(cr *api.PerconaXtraDBCluster, reconcileErr error) (err error) {
-	clusterCondition := api.ClusterCondition{
-		Status:             api.ConditionTrue,
-		Type:               api.ClusterInit,
-		LastTransitionTime: metav1.NewTime(time.Now()),
-	}
-
 	if reconcileErr != nil {
+		normalizeConditions(&cr.Status)
+		r.setCondition(cr, boolCondition(api.ClusterReconciled, false, "ReconcileError", reconcileErr.Error()))
+		r.setCondition(cr, boolCondition(api.ClusterError, true, "ReconcileError", reconcileErr.Error()))
 		if cr.Status.Status != api.ClusterError {
-			clusterCondition = api.ClusterCondition{
-				Status:             api.ConditionTrue,
-				Type:               api.ClusterError,
-				Message:            reconcileErr.Error(),
-				Reason:             "ErrorReconcile",
-				LastTransitionTime: metav1.NewTime(time.Now()),
-			}
-			cr.Status.Conditions = append(cr.Status.Conditions, clusterCondition)
-
 			cr.Status.Messages = append(cr.Status.Messages, "Error: "+reconcileErr.Error())
 			cr.Status.Status = api.ClusterError
 		}
@@ -47,31 +32,13 @@ func (r *ReconcilePerconaXtraDBCluster) updateStatus(cr *api.PerconaXtraDBCluste
 
 	cr.Status.Messages = cr.Status.Messages[:0]
 
-	pxcStatus, err := r.appStatus(statefulset.NewNode(cr), cr.Spec.PXC.PodSpec, cr.Namespace, cr.CompareVersionWith("1.7.0") >= 0)
+	pxcStatus, err := r.appStatus(cr, statefulset.NewNode(cr), cr.Spec.PXC.PodSpec)
 	if err != nil {
 		return fmt.Errorf("get pxc status: %v", err)
 	}
 	pxcStatus.Version = cr.Status.PXC.Version
 	pxcStatus.Image = cr.Status.PXC.Image
-	if pxcStatus.Status != cr.Status.PXC.Status {
-		if pxcStatus.Status == api.AppStateReady {
-			clusterCondition = api.ClusterCondition{
-				Status:             api.ConditionTrue,
-				Type:               api.ClusterPXCReady,
-				LastTransitionTime: metav1.NewTime(time.Now()),
-			}
-		}
-
-		if pxcStatus.Status == api.AppStateError {
-			clusterCondition = api.ClusterCondition{
-				Status:             api.ConditionTrue,
-				Message:            "PXC" + pxcStatus.Message,
-				Reason:             "ErrorPXC",
-				Type:               api.ClusterError,
-				LastTransitionTime: metav1.NewTime(time.Now()),
-			}
-		}
-	}
+	prevMembers := cr.Status.PXC.Members
 
 	cr.Status.PXC = pxcStatus
 	cr.Status.Host = cr.Name + "-" + "pxc." + cr.Namespace
@@ -82,32 +49,12 @@ func (r *ReconcilePerconaXtraDBCluster) updateStatus(cr *api.PerconaXtraDBCluste
 	inProgres := false
 
 	if cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled {
-		haProxyStatus, err := r.appStatus(statefulset.NewHAProxy(cr), cr.Spec.HAProxy, cr.Namespace, cr.CompareVersionWith("1.7.0") >= 0)
+		haProxyStatus, err := r.appStatus(cr, statefulset.NewHAProxy(cr), cr.Spec.HAProxy)
 		if err != nil {
 			return fmt.Errorf("get haproxy status: %v", err)
 		}
 		haProxyStatus.Version = cr.Status.HAProxy.Version
 
-		if haProxyStatus.Status != cr.Status.HAProxy.Status {
-			if haProxyStatus.Status == api.AppStateReady {
-				clusterCondition = api.ClusterCondition{
-					Status:             api.ConditionTrue,
-					Type:               api.ClusterHAProxyReady,
-					LastTransitionTime: metav1.NewTime(time.Now()),
-				}
-			}
-
-			if haProxyStatus.Status == api.AppStateError {
-				clusterCondition = api.ClusterCondition{
-					Status:             api.ConditionTrue,
-					Message:            "HAProxy:" + haProxyStatus.Message,
-					Reason:             "ErrorHAProxy",
-					Type:               api.ClusterError,
-					LastTransitionTime: metav1.NewTime(time.Now()),
-				}
-			}
-		}
-
 		cr.Status.HAProxy = haProxyStatus
 
 		cr.Status.Host = cr.Name + "-" + "haproxy." + cr.Namespace
@@ -140,32 +87,12 @@ func (r *ReconcilePerconaXtraDBCluster) updateStatus(cr *api.PerconaXtraDBCluste
 	}
 
 	if cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled {
-		proxyStatus, err := r.appStatus(statefulset.NewProxy(cr), cr.Spec.ProxySQL, cr.Namespace, cr.CompareVersionWith("1.7.0") >= 0)
+		proxyStatus, err := r.appStatus(cr, statefulset.NewProxy(cr), cr.Spec.ProxySQL)
 		if err != nil {
 			return fmt.Errorf("get proxysql status: %v", err)
 		}
 		proxyStatus.Version = cr.Status.ProxySQL.Version
 
-		if proxyStatus.Status != cr.Status.ProxySQL.Status {
-			if proxyStatus.Status == api.AppStateReady {
-				clusterCondition = api.ClusterCondition{
-					Status:             api.ConditionTrue,
-					Type:               api.ClusterProxyReady,
-					LastTransitionTime: metav1.NewTime(time.Now()),
-				}
-			}
-
-			if proxyStatus.Status == api.AppStateError {
-				clusterCondition = api.ClusterCondition{
-					Status:             api.ConditionTrue,
-					Message:            "ProxySQL:" + proxyStatus.Message,
-					Reason:             "ErrorProxySQL",
-					Type:               api.ClusterError,
-					LastTransitionTime: metav1.NewTime(time.Now()),
-				}
-			}
-		}
-
 		cr.Status.ProxySQL = proxyStatus
 
 		cr.Status.Host = cr.Name + "-" + "proxysql." + cr.Namespace
@@ -205,68 +132,42 @@ func (r *ReconcilePerconaXtraDBCluster) updateStatus(cr *api.PerconaXtraDBCluste
 		}
 	}
 
+	cr.Status.PXC.Members = r.pxcMembers(cr, prevMembers)
+
 	switch {
 	case (cr.Status.PXC.Status == cr.Status.ProxySQL.Status && cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled) ||
 		(cr.Status.PXC.Status == cr.Status.HAProxy.Status && cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled):
-		if cr.Status.PXC.Status == api.AppStateReady {
-			clusterCondition = api.ClusterCondition{
-				Status:             api.ConditionTrue,
-				Type:               api.ClusterReady,
-				LastTransitionTime: metav1.NewTime(time.Now()),
-			}
-		}
 		cr.Status.Status = cr.Status.PXC.Status
 	case (cr.Spec.ProxySQL == nil || !cr.Spec.ProxySQL.Enabled) &&
 		(cr.Spec.HAProxy == nil || !cr.Spec.HAProxy.Enabled) &&
 		cr.Status.PXC.Status == api.AppStateReady:
-		clusterCondition = api.ClusterCondition{
-			Status:             api.ConditionTrue,
-			Type:               api.ClusterReady,
-			LastTransitionTime: metav1.NewTime(time.Now()),
-		}
 		cr.Status.Status = cr.Status.PXC.Status
 	case cr.Status.PXC.Status == api.AppStateError ||
 		cr.Status.ProxySQL.Status == api.AppStateError ||
 		cr.Status.HAProxy.Status == api.AppStateError:
-		clusterCondition = api.ClusterCondition{
-			Status:             api.ConditionTrue,
-			Type:               api.ClusterError,
-			LastTransitionTime: metav1.NewTime(time.Now()),
-		}
 		cr.Status.Status = api.AppStateError
 	case cr.Status.PXC.Status == api.AppStateInit ||
 		(cr.Spec.ProxySQL != nil && cr.Status.ProxySQL.Status == api.AppStateInit) ||
 		(cr.Spec.HAProxy != nil && cr.Status.HAProxy.Status == api.AppStateInit):
-		clusterCondition = api.ClusterCondition{
-			Status:             api.ConditionTrue,
-			Type:               api.ClusterInit,
-			LastTransitionTime: metav1.NewTime(time.Now()),
-		}
 		cr.Status.Status = api.AppStateInit
 	default:
 		cr.Status.Status = api.AppStateUnknown
 	}
 
-	if len(cr.Status.Conditions) == 0 {
-		cr.Status.Conditions = append(cr.Status.Conditions, clusterCondition)
-	} else {
-		lastClusterCondition := cr.Status.Conditions[len(cr.Status.Conditions)-1]
-
-		switch {
-		case lastClusterCondition.Type != clusterCondition.Type:
-			cr.Status.Conditions = append(cr.Status.Conditions, clusterCondition)
-		default:
-			cr.Status.Conditions[len(cr.Status.Conditions)-1] = lastClusterCondition
-		}
+	if inProgres {
+		cr.Status.Status = api.AppStateInit
 	}
 
-	if len(cr.Status.Conditions) > maxStatusesQuantity {
-		cr.Status.Conditions = cr.Status.Conditions[len(cr.Status.Conditions)-maxStatusesQuantity:]
+	err = r.updateConditions(cr, inProgres)
+	if err != nil {
+		return errors.Wrap(err, "update conditions")
 	}
 
-	if inProgres {
-		cr.Status.Status = api.AppStateInit
+	err = r.recordKnownGood(cr)
+	if err != nil {
+		return errors.Wrap(err, "record known-good state")
 	}
+
 	cr.Status.ObservedGeneration = cr.ObjectMeta.Generation
 	return r.writeStatus(cr)
 }
@@ -275,8 +176,15 @@ func (r *ReconcilePerconaXtraDBCluster) writeStatus(cr *api.PerconaXtraDBCluster
 	err := r.client.Status().Update(context.TODO(), cr)
 	if err != nil {
 		// may be it's k8s v1.10 and erlier (e.g. oc3.9) that doesn't support status updates
-		// so try to update whole CR
-		err := r.client.Update(context.TODO(), cr)
+		// so try to update whole CR. The spec is taken from the stored CR,
+		// the one changed in memory (e.g. by the storage autoscaling) isn't saved.
+		current := &api.PerconaXtraDBCluster{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current)
+		if err != nil {
+			return fmt.Errorf("get cluster: %v", err)
+		}
+		current.Status = cr.Status
+		err = r.client.Update(context.TODO(), current)
 		if err != nil {
 			return fmt.Errorf("send update: %v", err)
 		}
@@ -294,7 +202,9 @@ func (r *ReconcilePerconaXtraDBCluster) upgradeInProgress(cr *api.PerconaXtraDBC
 	return sfsObj.Status.Replicas > sfsObj.Status.UpdatedReplicas, nil
 }
 
-func (r *ReconcilePerconaXtraDBCluster) appStatus(app api.StatefulApp, podSpec *api.PodSpec, namespace string, cr170OrGreater bool) (api.AppStatus, error) {
+func (r *ReconcilePerconaXtraDBCluster) appStatus(cr *api.PerconaXtraDBCluster, app api.StatefulApp, podSpec *api.PodSpec) (api.AppStatus, error) {
+	namespace := cr.Namespace
+	cr170OrGreater := cr.CompareVersionWith("1.7.0") >= 0
 	list := corev1.PodList{}
 	err := r.client.List(context.TODO(),
 		&list,
@@ -327,11 +237,14 @@ func (r *ReconcilePerconaXtraDBCluster) appStatus(app api.StatefulApp, podSpec *
 					} else {
 						isPodReady := true
 						if cr170OrGreater {
-							isPodWaitingForRecovery, _, err := r.isPodWaitingForRecovery(namespace, pod.Name)
+							recoveryState, err := r.recoveryState(cr, pod.Name)
 							if err != nil {
-								return api.AppStatus{}, fmt.Errorf("parse %s pod logs: %v", pod.Name, err)
+								// the state is unknown until the next probe,
+								// the pod isn't counted as ready meanwhile
+								status.Message += pod.Name + ": recovery state is unknown: " + err.Error() + "; "
+								continue
 							}
-							isPodReady = !isPodWaitingForRecovery && pod.ObjectMeta.Labels["controller-revision-hash"] == sfs.Status.UpdateRevision
+							isPodReady = recoveryState == nil && pod.ObjectMeta.Labels["controller-revision-hash"] == sfs.Status.UpdateRevision
 						}
 
 						if isPodReady {
@@ -0,0 +1,284 @@
+package pxc
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// autoscaleStorage checks the datadir usage of the PXC pods and raises the
+// size of the volumes in the status if the usage exceeds the threshold.
+// The size is applied by reconcileVolumeExpansion. The grown size is kept
+// if the autoscaling is disabled, the volumes can't be shrunk anyway.
+func (r *ReconcilePerconaXtraDBCluster) autoscaleStorage(cr *api.PerconaXtraDBCluster) error {
+	as := cr.Spec.PXC.StorageAutoscaling
+	if cr.CompareVersionWith("1.8.0") < 0 || as == nil || !as.Enabled {
+		if st := cr.Status.StorageAutoscaling; st != nil {
+			st.LastCheck = nil
+			st.Pods = nil
+			st.GrownFromKiB = 0
+		}
+		return nil
+	}
+
+	st := cr.Status.StorageAutoscaling
+	if st == nil {
+		st = &api.StorageAutoscalingStatus{}
+		cr.Status.StorageAutoscaling = st
+	}
+	if st.LastCheck != nil && time.Since(st.LastCheck.Time) < time.Duration(as.CheckInterval)*time.Second {
+		return nil
+	}
+
+	usage, err := r.datadirUsage(cr)
+	if err != nil {
+		return errors.Wrap(err, "get datadir usage")
+	}
+	now := metav1.Now()
+	st.LastCheck = &now
+	st.Pods = usage
+
+	r.growStorage(cr, as, st)
+
+	return nil
+}
+
+// growStorage raises the size in the status if the datadir usage of any pod
+// exceeds the threshold and the previous growth is already applied
+func (r *ReconcilePerconaXtraDBCluster) growStorage(cr *api.PerconaXtraDBCluster, as *api.StorageAutoscalingSpec, st *api.StorageAutoscalingStatus) {
+	maxUsage := 0
+	for _, u := range st.Pods {
+		if u.UsedPercent > maxUsage {
+			maxUsage = u.UsedPercent
+		}
+	}
+	if maxUsage < as.ThresholdPercent {
+		return
+	}
+
+	// the usage drops once the filesystem is resized
+	if growthPending(st, cr.Status.VolumeExpansion) {
+		return
+	}
+
+	current := storageSize(cr)
+	maxSize := resource.MustParse(as.MaxSize)
+	if current.Cmp(maxSize) >= 0 {
+		r.recorder.Eventf(cr, corev1.EventTypeWarning, EventStorageLimitReached,
+			"Datadir usage is %d%%, volumes can't be grown beyond maxSize %s", maxUsage, as.MaxSize)
+		return
+	}
+
+	next := grownSize(current, as.GrowthPercent)
+	if next.Cmp(maxSize) >= 0 {
+		next = maxSize
+		r.recorder.Eventf(cr, corev1.EventTypeWarning, EventStorageLimitReached,
+			"Volumes are grown to maxSize %s, the next growth isn't possible", as.MaxSize)
+	}
+
+	st.GrownFromKiB = 0
+	for _, u := range st.Pods {
+		if u.CapacityKiB > st.GrownFromKiB {
+			st.GrownFromKiB = u.CapacityKiB
+		}
+	}
+	now := metav1.Now()
+	st.Size = next.String()
+	st.LastGrowth = &now
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventStorageAutoscaled,
+		"Datadir usage is %d%%, growing volumes from %s to %s", maxUsage, current.String(), st.Size)
+}
+
+// growthPending returns true until the last growth is applied,
+// i.e. the volumes are expanded and every pod has the bigger filesystem
+func growthPending(st *api.StorageAutoscalingStatus, expansions []api.VolumeExpansionStatus) bool {
+	for _, e := range expansions {
+		if e.Component == app.Name && e.State == api.VolumeExpansionInProgress {
+			return true
+		}
+	}
+
+	if st.GrownFromKiB == 0 {
+		return false
+	}
+	for _, u := range st.Pods {
+		if len(u.Error) == 0 && u.CapacityKiB <= st.GrownFromKiB {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyStorageAutoscaling raises the requested size of the PXC volumes
+// to the one grown by the autoscaling. The spec isn't saved, so the grown
+// size is kept in the status and by the volumes themselves: the request
+// isn't lowered below the size of the existing volumes if the status is lost.
+func (r *ReconcilePerconaXtraDBCluster) applyStorageAutoscaling(cr *api.PerconaXtraDBCluster) error {
+	st := cr.Status.StorageAutoscaling
+	if cr.Spec.PXC.VolumeSpec == nil || cr.Spec.PXC.VolumeSpec.PersistentVolumeClaim == nil {
+		return nil
+	}
+	if cr.Spec.PXC.StorageAutoscaling == nil && (st == nil || st.Size == "") {
+		return nil
+	}
+
+	size, err := r.volumesSize(cr)
+	if err != nil {
+		return errors.Wrap(err, "get size of the volumes")
+	}
+	if st != nil && st.Size != "" {
+		grown, err := resource.ParseQuantity(st.Size)
+		if err == nil && grown.Cmp(size) > 0 {
+			size = grown
+		}
+	}
+	current := storageSize(cr)
+	if size.Cmp(current) <= 0 {
+		return nil
+	}
+
+	pvc := cr.Spec.PXC.VolumeSpec.PersistentVolumeClaim
+	requests := corev1.ResourceList{}
+	for k, v := range pvc.Resources.Requests {
+		requests[k] = v
+	}
+	requests[corev1.ResourceStorage] = size
+	pvc.Resources.Requests = requests
+
+	return nil
+}
+
+// volumesSize returns the biggest size requested by the claim template
+// of the PXC StatefulSet and by its PVCs
+func (r *ReconcilePerconaXtraDBCluster) volumesSize(cr *api.PerconaXtraDBCluster) (resource.Quantity, error) {
+	var size resource.Quantity
+	max := func(q resource.Quantity) {
+		if q.Cmp(size) > 0 {
+			size = q
+		}
+	}
+
+	node := statefulset.NewNode(cr)
+	sfs := appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: node.StatefulSet().Name, Namespace: cr.Namespace}, &sfs)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return size, errors.Wrap(err, "get statefulset")
+	}
+	for _, t := range sfs.Spec.VolumeClaimTemplates {
+		max(t.Spec.Resources.Requests[corev1.ResourceStorage])
+	}
+
+	list := corev1.PersistentVolumeClaimList{}
+	err = r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(node.Labels()),
+		},
+	)
+	if err != nil {
+		return size, errors.Wrap(err, "get PVC list")
+	}
+	for _, pvc := range list.Items {
+		max(pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+	}
+
+	return size, nil
+}
+
+// storageSize returns the requested size of the PXC volumes
+func storageSize(cr *api.PerconaXtraDBCluster) resource.Quantity {
+	if cr.Spec.PXC.VolumeSpec == nil || cr.Spec.PXC.VolumeSpec.PersistentVolumeClaim == nil {
+		return resource.Quantity{}
+	}
+	return cr.Spec.PXC.VolumeSpec.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]
+}
+
+// grownSize returns the size grown by the percent and rounded up to GiB
+func grownSize(size resource.Quantity, percent int) resource.Quantity {
+	const gib = int64(1 << 30)
+	next := size.Value() + size.Value()*int64(percent)/100
+	next = (next + gib - 1) / gib * gib
+	return *resource.NewQuantity(next, resource.BinarySI)
+}
+
+// datadirUsage returns the usage of the datadir filesystem of the ready PXC pods.
+// The pod whose usage can't be read is reported with the error.
+func (r *ReconcilePerconaXtraDBCluster) datadirUsage(cr *api.PerconaXtraDBCluster) ([]api.PodStorageUsage, error) {
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(statefulset.NewNode(cr).Labels()),
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "get pod list")
+	}
+
+	var usage []api.PodStorageUsage
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if !isContainersReady(*pod) {
+			continue
+		}
+		var stdout, stderr bytes.Buffer
+		err := r.clientcmd.Exec(pod, app.Name, []string{"df", "-P", "-k", "/var/lib/mysql"}, nil, &stdout, &stderr, false)
+		if err != nil {
+			err = errors.Wrapf(err, "exec df: %s", stderr.String())
+		}
+		var used int
+		var capacity int64
+		if err == nil {
+			used, capacity, err = parseDFUsage(stdout.String())
+		}
+		if err != nil {
+			r.logger(cr.Name, cr.Namespace).Error(err, "get datadir usage", "pod", pod.Name)
+			usage = append(usage, api.PodStorageUsage{Pod: pod.Name, Error: err.Error()})
+			continue
+		}
+		usage = append(usage, api.PodStorageUsage{Pod: pod.Name, UsedPercent: used, CapacityKiB: capacity})
+	}
+
+	return usage, nil
+}
+
+// parseDFUsage returns the used percent and the size in KiB
+// of the filesystem from the POSIX df output
+func parseDFUsage(out string) (used int, capacity int64, err error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return 0, 0, errors.Errorf("unexpected output: %q", out)
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 5 {
+		return 0, 0, errors.Errorf("unexpected output: %q", out)
+	}
+	capacity, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "size %q", fields[1])
+	}
+	used, err = strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "capacity %q", fields[4])
+	}
+	return used, capacity, nil
+}
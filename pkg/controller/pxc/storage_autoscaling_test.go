@@ -0,0 +1,262 @@
+package pxc
+
+import (
+	"testing"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/apis"
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGrownSize(t *testing.T) {
+	cases := []struct {
+		size     string
+		percent  int
+		expected string
+	}{
+		{"100Gi", 20, "120Gi"},
+		{"6G", 20, "7Gi"},
+		{"10Gi", 1, "11Gi"},
+	}
+
+	for _, c := range cases {
+		next := grownSize(resource.MustParse(c.size), c.percent)
+		if next.String() != c.expected {
+			t.Errorf("%s grown by %d%%: got %s, expected %s", c.size, c.percent, next.String(), c.expected)
+		}
+	}
+}
+
+func TestParseDFUsage(t *testing.T) {
+	out := "Filesystem     1024-blocks    Used Available Capacity Mounted on\n" +
+		"/dev/sdb          6102624 5003852   1082388      83% /var/lib/mysql\n"
+	used, capacity, err := parseDFUsage(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if used != 83 {
+		t.Errorf("got %d%%, expected 83%%", used)
+	}
+	if capacity != 6102624 {
+		t.Errorf("got %d KiB, expected 6102624 KiB", capacity)
+	}
+
+	if _, _, err := parseDFUsage("df: /var/lib/mysql: No such file or directory\n"); err == nil {
+		t.Error("expected error for the output without the filesystem")
+	}
+}
+
+func TestGrowStorage(t *testing.T) {
+	as := &api.StorageAutoscalingSpec{
+		Enabled:          true,
+		ThresholdPercent: 80,
+		GrowthPercent:    20,
+		MaxSize:          "15Gi",
+	}
+	usage := func(used int, capacity ...int64) []api.PodStorageUsage {
+		pods := []api.PodStorageUsage{}
+		for _, c := range capacity {
+			pods = append(pods, api.PodStorageUsage{UsedPercent: used, CapacityKiB: c})
+		}
+		return pods
+	}
+	grownAt := metav1.Now()
+
+	tests := map[string]struct {
+		size       string
+		status     api.StorageAutoscalingStatus
+		expansions []api.VolumeExpansionStatus
+		expected   string
+	}{
+		"usage below threshold": {
+			size:   "10Gi",
+			status: api.StorageAutoscalingStatus{Pods: usage(79, 10<<20, 10<<20)},
+		},
+		"usage above threshold": {
+			size:     "10Gi",
+			status:   api.StorageAutoscalingStatus{Pods: usage(85, 10<<20, 10<<20)},
+			expected: "12Gi",
+		},
+		"volumes are being expanded": {
+			size:   "10Gi",
+			status: api.StorageAutoscalingStatus{Pods: usage(85, 10<<20, 10<<20)},
+			expansions: []api.VolumeExpansionStatus{
+				{Component: app.Name, State: api.VolumeExpansionInProgress},
+			},
+		},
+		"filesystem of a pod isn't resized yet": {
+			size: "12Gi",
+			status: api.StorageAutoscalingStatus{
+				Size:         "12Gi",
+				LastGrowth:   &grownAt,
+				GrownFromKiB: 10 << 20,
+				Pods:         usage(85, 12<<20, 10<<20),
+			},
+			expansions: []api.VolumeExpansionStatus{
+				{Component: app.Name, State: api.VolumeExpansionCompleted},
+			},
+			expected: "12Gi",
+		},
+		"all filesystems are resized": {
+			size: "12Gi",
+			status: api.StorageAutoscalingStatus{
+				Size:         "12Gi",
+				LastGrowth:   &grownAt,
+				GrownFromKiB: 10 << 20,
+				Pods:         usage(85, 12<<20, 12<<20),
+			},
+			expected: "15Gi",
+		},
+		"maxSize reached": {
+			size:   "15Gi",
+			status: api.StorageAutoscalingStatus{Pods: usage(95, 15<<20)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cr := &api.PerconaXtraDBCluster{}
+			cr.Spec.PXC = &api.PXCSpec{PodSpec: &api.PodSpec{
+				VolumeSpec: &api.VolumeSpec{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.size)},
+						},
+					},
+				},
+			}}
+			cr.Status.VolumeExpansion = tt.expansions
+			st := tt.status.DeepCopy()
+
+			r := &ReconcilePerconaXtraDBCluster{recorder: record.NewFakeRecorder(10)}
+			r.growStorage(cr, as, st)
+
+			if st.Size != tt.expected {
+				t.Errorf("expected size %q, got %q", tt.expected, st.Size)
+			}
+			if tt.expected != "" && tt.expected != tt.status.Size && st.GrownFromKiB != tt.status.Pods[0].CapacityKiB {
+				t.Errorf("expected the capacity %d KiB before the growth, got %d", tt.status.Pods[0].CapacityKiB, st.GrownFromKiB)
+			}
+		})
+	}
+}
+
+func TestAutoscaleStorageDisabled(t *testing.T) {
+	grownAt := metav1.Now()
+	cr := &api.PerconaXtraDBCluster{}
+	cr.Spec.CRVersion = "1.8.0"
+	cr.Spec.PXC = &api.PXCSpec{
+		StorageAutoscaling: &api.StorageAutoscalingSpec{Enabled: false},
+		PodSpec: &api.PodSpec{
+			VolumeSpec: &api.VolumeSpec{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+					},
+				},
+			},
+		},
+	}
+	cr.Status.StorageAutoscaling = &api.StorageAutoscalingStatus{
+		Size:       "12Gi",
+		LastCheck:  &grownAt,
+		LastGrowth: &grownAt,
+		Pods:       []api.PodStorageUsage{{Pod: "pxc-0", UsedPercent: 85}},
+	}
+
+	r := &ReconcilePerconaXtraDBCluster{
+		client:   fake.NewFakeClientWithScheme(newStorageScheme(t)),
+		recorder: record.NewFakeRecorder(10),
+	}
+	if err := r.autoscaleStorage(cr); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.applyStorageAutoscaling(cr); err != nil {
+		t.Fatal(err)
+	}
+
+	size := storageSize(cr)
+	if size.String() != "12Gi" {
+		t.Errorf("expected the grown size 12Gi to be kept, got %s", size.String())
+	}
+	if st := cr.Status.StorageAutoscaling; st.LastCheck != nil || st.Pods != nil {
+		t.Errorf("expected the usage to be cleared, got %+v", st)
+	}
+}
+
+func newStorageScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestApplyStorageAutoscalingLostStatus(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns"},
+	}
+	cr.Spec.CRVersion = "1.8.0"
+	cr.Spec.PXC = &api.PXCSpec{
+		StorageAutoscaling: &api.StorageAutoscalingSpec{Enabled: true},
+		PodSpec: &api.PodSpec{
+			VolumeSpec: &api.VolumeSpec{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+					},
+				},
+			},
+		},
+	}
+
+	// the volumes were grown to 12Gi, the status is lost
+	node := statefulset.NewNode(cr)
+	sfs := node.StatefulSet()
+	sfs.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{Name: "datadir"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("12Gi")},
+			},
+		},
+	}}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "datadir-cluster1-pxc-0", Namespace: "ns", Labels: node.Labels()},
+		Spec:       sfs.Spec.VolumeClaimTemplates[0].Spec,
+	}
+
+	r := &ReconcilePerconaXtraDBCluster{client: fake.NewFakeClientWithScheme(newStorageScheme(t), sfs, pvc)}
+	if err := r.applyStorageAutoscaling(cr); err != nil {
+		t.Fatal(err)
+	}
+
+	size := storageSize(cr)
+	if size.String() != "12Gi" {
+		t.Errorf("expected the size of the volumes 12Gi, got %s", size.String())
+	}
+}
+
+func TestGrowthPendingSkipsFailedPods(t *testing.T) {
+	st := &api.StorageAutoscalingStatus{
+		GrownFromKiB: 10 << 20,
+		Pods: []api.PodStorageUsage{
+			{Pod: "cluster1-pxc-0", UsedPercent: 50, CapacityKiB: 12 << 20},
+			{Pod: "cluster1-pxc-1", Error: "exec df: container not found"},
+		},
+	}
+	if growthPending(st, nil) {
+		t.Error("the pod without the usage shouldn't keep the growth pending")
+	}
+}
@@ -0,0 +1,135 @@
+package pxc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// zoneLabels are the node labels with the zone, the deprecated one is the fallback
+var zoneLabels = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+
+// nodeZoneTTL is how long the zone of the node is kept by the reconciler.
+// The node can be replaced by a node with the same name in another zone.
+const nodeZoneTTL = 10 * time.Minute
+
+type cachedZone struct {
+	zone    string
+	expires time.Time
+}
+
+// reconcileTopology publishes the node and the zone of the PXC pods
+// to the status, the proxies route the reads by it
+func (r *ReconcilePerconaXtraDBCluster) reconcileTopology(cr *api.PerconaXtraDBCluster) error {
+	if !cr.TopologyAware() {
+		cr.Status.Topology = nil
+		cr.Status.RemoveCondition(api.ClusterTopologyAvailable)
+		return nil
+	}
+
+	topology, forbidden, err := r.podTopology(cr, statefulset.NewNode(cr))
+	if err != nil {
+		return errors.Wrap(err, "get pxc pods topology")
+	}
+	cr.Status.Topology = topology
+
+	if forbidden {
+		if c := cr.Status.FindCondition(api.ClusterTopologyAvailable); c == nil || c.Status != api.ConditionFalse {
+			r.recorder.Event(cr, corev1.EventTypeWarning, EventNodesForbidden,
+				"Operator can't read the nodes, the zones of the pods are unknown and the reads aren't zone-aware. Allow get on nodes to the operator service account")
+		}
+		r.setCondition(cr, boolCondition(api.ClusterTopologyAvailable, false, "NodesForbidden", "operator has no access to the nodes"))
+		return nil
+	}
+	r.setCondition(cr, boolCondition(api.ClusterTopologyAvailable, true, "ZonesKnown", ""))
+
+	return nil
+}
+
+// podTopology returns the node and the zone of the scheduled pods of the app.
+// If the nodes can't be read, the pods are returned without zones.
+func (r *ReconcilePerconaXtraDBCluster) podTopology(cr *api.PerconaXtraDBCluster, sfs api.StatefulApp) (topology []api.PodTopology, forbidden bool, err error) {
+	list := corev1.PodList{}
+	err = r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(sfs.Labels()),
+		},
+	)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "get pod list")
+	}
+
+	for _, pod := range list.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		zone := ""
+		if !forbidden {
+			zone, err = r.nodeZone(cr, pod.Spec.NodeName)
+			if k8serrors.IsForbidden(err) {
+				r.logger(cr.Name, cr.Namespace).Info("no access to nodes, the pods are left without zones")
+				forbidden = true
+			} else if err != nil {
+				return nil, false, errors.Wrapf(err, "get node %s", pod.Spec.NodeName)
+			}
+		}
+		topology = append(topology, api.PodTopology{Pod: pod.Name, Node: pod.Spec.NodeName, Zone: zone})
+	}
+	sort.Slice(topology, func(i, j int) bool {
+		return topology[i].Pod < topology[j].Pod
+	})
+
+	return topology, forbidden, nil
+}
+
+// nodeZone returns the zone of the node. The nodes are read bypassing
+// the cache, so the operator doesn't watch them, and the zones are kept
+// by the reconciler for nodeZoneTTL.
+func (r *ReconcilePerconaXtraDBCluster) nodeZone(cr *api.PerconaXtraDBCluster, name string) (string, error) {
+	key := cr.Namespace + "/" + cr.Name + "/" + name
+	if cached, ok := r.nodeZones.Load(key); ok && time.Now().Before(cached.(cachedZone).expires) {
+		return cached.(cachedZone).zone, nil
+	}
+
+	node := corev1.Node{}
+	err := r.apiReader.Get(context.TODO(), types.NamespacedName{Name: name}, &node)
+	if err != nil {
+		r.nodeZones.Delete(key)
+		return "", err
+	}
+
+	zone := ""
+	for _, l := range zoneLabels {
+		if z, ok := node.Labels[l]; ok {
+			zone = z
+			break
+		}
+	}
+	r.nodeZones.Store(key, cachedZone{zone: zone, expires: time.Now().Add(nodeZoneTTL)})
+
+	return zone, nil
+}
+
+// deleteNodeZones drops the zones of the nodes kept for the deleted cluster
+func (r *ReconcilePerconaXtraDBCluster) deleteNodeZones(namespace, name string) {
+	prefix := namespace + "/" + name + "/"
+	r.nodeZones.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			r.nodeZones.Delete(key)
+		}
+		return true
+	})
+}
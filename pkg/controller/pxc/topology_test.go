@@ -0,0 +1,55 @@
+package pxc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestNodeZone(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-east-1a"},
+		},
+	}
+	r := &ReconcilePerconaXtraDBCluster{
+		apiReader: fake.NewFakeClient(node),
+		nodeZones: new(sync.Map),
+	}
+	cr := &api.PerconaXtraDBCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns"}}
+
+	zone, err := r.nodeZone(cr, "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone != "us-east-1a" {
+		t.Errorf("expected zone us-east-1a, got %s", zone)
+	}
+
+	key := "ns/cluster1/node1"
+	r.nodeZones.Store(key, cachedZone{zone: "us-east-1b", expires: time.Now().Add(time.Minute)})
+	if zone, _ := r.nodeZone(cr, "node1"); zone != "us-east-1b" {
+		t.Errorf("expected the cached zone us-east-1b, got %s", zone)
+	}
+
+	r.nodeZones.Store(key, cachedZone{zone: "us-east-1b", expires: time.Now().Add(-time.Second)})
+	if zone, _ := r.nodeZone(cr, "node1"); zone != "us-east-1a" {
+		t.Errorf("expected the expired zone to be read again, got %s", zone)
+	}
+
+	r.nodeZones.Store("ns/cluster2/node1", cachedZone{zone: "us-east-1a"})
+	r.deleteNodeZones("ns", "cluster1")
+	if _, ok := r.nodeZones.Load(key); ok {
+		t.Error("zones of the deleted cluster are kept")
+	}
+	if _, ok := r.nodeZones.Load("ns/cluster2/node1"); !ok {
+		t.Error("zones of another cluster are deleted")
+	}
+}
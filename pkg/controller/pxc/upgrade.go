@@ -10,12 +10,15 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
 
 	"github.com/pkg/errors"
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	v1 "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/queries"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +35,7 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 	if err != nil {
 		return errors.Wrap(err, "failed to get sate")
 	}
+	oldTemplate := currentSet.Spec.Template.DeepCopy()
 
 	currentSet.Spec.UpdateStrategy = sfs.UpdateStrategy(cr)
 
@@ -43,6 +47,11 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 	currentSet.Spec.Template.Spec.SecurityContext = podSpec.PodSecurityContext
 	currentSet.Spec.Template.Spec.ImagePullSecrets = podSpec.ImagePullSecrets
 
+	err = r.reconcileConfigMap(cr)
+	if err != nil {
+		return errors.Wrap(err, "upgradePod/updateApp error: update db config error")
+	}
+
 	// embed DB configuration hash
 	// TODO: code duplication with deploy function
 	configHash := r.getConfigHash(cr, sfs)
@@ -52,6 +61,7 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 	}
 
 	pxc.MergeTemplateAnnotations(currentSet, newAnnotations)
+	restoreDeferredSecret(currentSet, newAnnotations)
 
 	if cr.CompareVersionWith("1.1.0") >= 0 {
 		currentSet.Spec.Template.Annotations["percona.com/configuration-hash"] = configHash
@@ -60,11 +70,6 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 		currentSet.Spec.Template.Spec.ServiceAccountName = podSpec.ServiceAccountName
 	}
 
-	err = r.reconcileConfigMap(cr)
-	if err != nil {
-		return errors.Wrap(err, "upgradePod/updateApp error: update db config error")
-	}
-
 	// change TLS secret configuration
 	sslHash, err := r.getSecretHash(cr, cr.Spec.PXC.SSLSecretName, cr.Spec.AllowUnsafeConfig)
 	if err != nil {
@@ -98,7 +103,7 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 	var newContainers []corev1.Container
 	var newInitContainers []corev1.Container
 
-	secrets := cr.Spec.SecretsName
+	secrets := cr.Spec.UsersSecretName(cr.Name)
 	if cr.CompareVersionWith("1.6.0") >= 0 {
 		secrets = "internal-" + cr.Name
 	}
@@ -114,6 +119,17 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 		}
 	}
 
+	// metrics exporter container
+	if cr.Spec.Metrics != nil && cr.Spec.Metrics.Enabled {
+		metricsC, err := sfs.MetricsContainer(cr.Spec.Metrics, secrets, cr)
+		if err != nil {
+			return errors.Wrap(err, "metrics container error")
+		}
+		if metricsC != nil {
+			newContainers = append(newContainers, *metricsC)
+		}
+	}
+
 	// log-collector container
 	if cr.Spec.LogCollector != nil && cr.Spec.LogCollector.Enabled && cr.CompareVersionWith("1.7.0") >= 0 {
 		logCollectorC, err := sfs.LogCollectorContainer(cr.Spec.LogCollector, cr.Spec.LogCollectorSecretName, secrets, cr)
@@ -166,11 +182,34 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 		return errors.Wrap(err, "volumes error")
 	}
 
-	currentSet.Spec.Template.Spec.Containers = newContainers
-	currentSet.Spec.Template.Spec.InitContainers = newInitContainers
-	currentSet.Spec.Template.Spec.Affinity = pxc.PodAffinity(podSpec.Affinity, sfs)
+	newTemplate := currentSet.Spec.Template.DeepCopy()
+	newTemplate.Spec.Containers = newContainers
+	newTemplate.Spec.InitContainers = newInitContainers
+	newTemplate.Spec.Affinity = pxc.PodAffinity(podSpec.Affinity, sfs)
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		newTemplate.Spec.TopologySpreadConstraints = pxc.TopologySpreadConstraints(podSpec.TopologySpreadConstraints, sfs)
+	}
 	if sfsVolume != nil && sfsVolume.Volumes != nil {
-		currentSet.Spec.Template.Spec.Volumes = sfsVolume.Volumes
+		newTemplate.Spec.Volumes = sfsVolume.Volumes
+	}
+
+	deferred := r.deferRollout(cr, sfs, currentSet, oldTemplate, newTemplate)
+	if !deferred && isPXC(sfs) {
+		deferred = r.holdMajorUpgrade(cr, currentSet, oldTemplate, appC)
+	}
+
+	resize := false
+	if !deferred && isPXC(sfs) && cr.CompareVersionWith("1.8.0") >= 0 {
+		resize = r.reconcileResize(cr, currentSet, appC)
+	}
+
+	if deferred {
+		currentSet.Spec.Template = *oldTemplate
+	} else {
+		currentSet.Spec.Template = *newTemplate
+		if cr.CompareVersionWith("1.8.0") >= 0 {
+			recordAppliedTemplate(currentSet)
+		}
 	}
 
 	err = r.client.Update(context.TODO(), currentSet)
@@ -178,14 +217,14 @@ func (r *ReconcilePerconaXtraDBCluster) updatePod(sfs api.StatefulApp, podSpec *
 		return errors.Wrap(err, "update error")
 	}
 
-	if cr.Spec.UpdateStrategy != v1.SmartUpdateStatefulSetStrategyType {
+	if cr.Spec.UpdateStrategy != v1.SmartUpdateStatefulSetStrategyType && !resize {
 		return nil
 	}
 
 	return r.smartUpdate(sfs, cr)
 }
 
-func (r *ReconcilePerconaXtraDBCluster) smartUpdate(sfs api.StatefulApp, cr *api.PerconaXtraDBCluster) error {
+func (r *ReconcilePerconaXtraDBCluster) smartUpdate(sfs api.StatefulApp, cr *api.PerconaXtraDBCluster) (rerr error) {
 	if !isPXC(sfs) {
 		return nil
 	}
@@ -209,10 +248,32 @@ func (r *ReconcilePerconaXtraDBCluster) smartUpdate(sfs api.StatefulApp, cr *api
 	}
 
 	if sfs.StatefulSet().Status.ReadyReplicas < sfs.StatefulSet().Status.Replicas {
+		if rollbackEnabled(cr) {
+			pod, err := r.crashLoopingPod(sfs)
+			if err != nil {
+				return errors.Wrap(err, "check crash-looping pods")
+			}
+			if len(pod) > 0 {
+				return r.rollbackOnFailure(cr, sfs, errors.Errorf("pod %s is crash-looping", pod))
+			}
+		}
 		logger.Info("can't start/continue 'SmartUpdate': waiting for all replicas are ready")
 		return nil
 	}
 
+	metrics.SmartUpdateInProgress.WithLabelValues(cr.Name, cr.Namespace).Set(1)
+	defer func(start time.Time) {
+		metrics.SmartUpdateInProgress.WithLabelValues(cr.Name, cr.Namespace).Set(0)
+		metrics.SmartUpdateDuration.WithLabelValues(cr.Name, cr.Namespace, metrics.Result(rerr)).Observe(time.Since(start).Seconds())
+		if rerr != nil {
+			r.recorder.Eventf(cr, corev1.EventTypeWarning, EventSmartUpdateFailed, "SmartUpdate of statefulset %s failed: %v", sfs.StatefulSet().Name, rerr)
+		}
+	}(time.Now())
+	if c := cr.Status.Canary; !canaryEnabled(cr) || c == nil || c.Revision != sfs.StatefulSet().Status.UpdateRevision {
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventSmartUpdateStarted,
+			"Applying revision %s of statefulset %s", sfs.StatefulSet().Status.UpdateRevision, sfs.StatefulSet().Name)
+	}
+
 	list := corev1.PodList{}
 	if err := r.client.List(context.TODO(),
 		&list,
@@ -247,28 +308,79 @@ func (r *ReconcilePerconaXtraDBCluster) smartUpdate(sfs api.StatefulApp, cr *api
 	})
 
 	var primaryPod corev1.Pod
+	var secondaries []corev1.Pod
 	for _, pod := range list.Items {
-		pod := pod
 		if strings.HasPrefix(primary, fmt.Sprintf("%s.%s.%s", pod.Name, sfs.StatefulSet().Name, sfs.StatefulSet().Namespace)) {
 			primaryPod = pod
 		} else {
-			logger.Info("apply changes to secondary pod", "pod name", pod.Name)
-			if err := r.applyNWait(cr, sfs.StatefulSet(), &pod, waitLimit); err != nil {
-				return errors.Wrap(err, "failed to apply changes")
+			secondaries = append(secondaries, pod)
+		}
+	}
+
+	if canaryEnabled(cr) {
+		if !r.canaryStage(cr, sfs.StatefulSet(), secondaries, waitLimit) {
+			if c := cr.Status.Canary; c != nil && c.State == api.CanaryStateFailed {
+				return r.rollbackOnFailure(cr, sfs, errors.Errorf("canary pod %s failed: %s", c.Pod, c.Message))
 			}
+			return nil
+		}
+	}
+
+	for _, pod := range secondaries {
+		pod := pod
+		logger.Info("apply changes to secondary pod", "pod name", pod.Name)
+		if err := r.applyNWait(cr, sfs.StatefulSet(), &pod, waitLimit); err != nil {
+			return r.rollbackOnFailure(cr, sfs, errors.Wrap(err, "failed to apply changes"))
 		}
 	}
 
 	logger.Info("apply changes to primary pod", "pod name", primaryPod.Name)
 	if err := r.applyNWait(cr, sfs.StatefulSet(), &primaryPod, waitLimit); err != nil {
-		return errors.Wrap(err, "failed to apply changes")
+		return r.rollbackOnFailure(cr, sfs, errors.Wrap(err, "failed to apply changes"))
 	}
 
+	r.checkPrimaryChange(cr, primaryPod.Name)
+
 	logger.Info("smart update finished")
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventSmartUpdateFinished,
+		"Revision %s of statefulset %s is applied", sfs.StatefulSet().Status.UpdateRevision, sfs.StatefulSet().Name)
 
 	return nil
 }
 
+// rollbackOnFailure rolls the upgrade back to the last known-good state.
+// SmartUpdate is still reported as failed with the original error.
+func (r *ReconcilePerconaXtraDBCluster) rollbackOnFailure(cr *api.PerconaXtraDBCluster, sfs api.StatefulApp, cause error) error {
+	if err := r.rollbackUpgrade(cr, sfs, cause.Error()); err != nil {
+		r.logger(cr.Name, cr.Namespace).Error(err, "failed to roll back the upgrade")
+	}
+	return cause
+}
+
+// checkPrimaryChange emits an event if the primary isn't the oldPrimary pod anymore
+func (r *ReconcilePerconaXtraDBCluster) checkPrimaryChange(cr *api.PerconaXtraDBCluster, oldPrimary string) {
+	logger := r.logger(cr.Name, cr.Namespace)
+
+	primary, err := r.getPrimaryPod(cr)
+	if err != nil {
+		logger.Error(err, "get primary pod")
+		return
+	}
+
+	pod := corev1.Pod{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: oldPrimary}, &pod)
+	if err != nil {
+		logger.Error(err, "get pod", "pod name", oldPrimary)
+		return
+	}
+
+	if primary == pod.Status.PodIP || strings.HasPrefix(primary, pod.Name) {
+		return
+	}
+
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventPrimaryChanged, "Primary changed from %s to %s", oldPrimary, primary)
+}
+
 func (r *ReconcilePerconaXtraDBCluster) applyNWait(cr *api.PerconaXtraDBCluster, sfs *appsv1.StatefulSet, pod *corev1.Pod, waitLimit int) error {
 	logger := r.logger(cr.Name, cr.Namespace)
 
@@ -278,6 +390,8 @@ func (r *ReconcilePerconaXtraDBCluster) applyNWait(cr *api.PerconaXtraDBCluster,
 		if err := r.client.Delete(context.TODO(), pod); err != nil {
 			return errors.Wrap(err, "failed to delete pod")
 		}
+		metrics.SmartUpdatePodsRestarted.WithLabelValues(cr.Name, cr.Namespace).Inc()
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventPodRestarted, "Pod %s is restarted to apply revision %s", pod.Name, sfs.Status.UpdateRevision)
 	}
 
 	if err := r.waitPodRestart(sfs.Status.UpdateRevision, pod, waitLimit, logger); err != nil {
@@ -296,9 +410,8 @@ func (r *ReconcilePerconaXtraDBCluster) applyNWait(cr *api.PerconaXtraDBCluster,
 }
 
 func (r *ReconcilePerconaXtraDBCluster) waitUntilOnline(cr *api.PerconaXtraDBCluster, sfsName string, pod *corev1.Pod, waitLimit int, logger logr.Logger) error {
-	if cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled {
-		time.Sleep(5 * time.Second)
-		return nil
+	if cr.Spec.ProxySQL == nil || !cr.Spec.ProxySQL.Enabled {
+		return r.waitPodReady(cr, pod, waitLimit, logger)
 	}
 
 	database, err := r.proxyDB(cr)
@@ -328,6 +441,38 @@ func (r *ReconcilePerconaXtraDBCluster) waitUntilOnline(cr *api.PerconaXtraDBClu
 		})
 }
 
+// haproxyCheckInterval is the interval of the HAProxy server checks
+var haproxyCheckInterval = 10 * time.Second
+
+// waitPodReady waits until all containers of the pod are ready. HAProxy
+// marks the server up on the next check, so one more check interval is
+// waited for if HAProxy is enabled.
+func (r *ReconcilePerconaXtraDBCluster) waitPodReady(cr *api.PerconaXtraDBCluster, pod *corev1.Pod, waitLimit int, logger logr.Logger) error {
+	err := retry(time.Second*5, time.Duration(waitLimit)*time.Second,
+		func() (bool, error) {
+			p := corev1.Pod{}
+			err := r.client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, &p)
+			if k8serrors.IsNotFound(err) {
+				return false, nil
+			}
+			if err != nil {
+				return false, errors.Wrap(err, "get pod")
+			}
+
+			return isContainersReady(p), nil
+		})
+	if err != nil {
+		return err
+	}
+
+	if cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled {
+		time.Sleep(haproxyCheckInterval)
+	}
+
+	logger.Info("pod is online", "pod name", pod.Name)
+	return nil
+}
+
 // retry runs func "f" every "in" time until "limit" is reached
 // it also doesn't have an extra tail wait after the limit is reached
 // and f func runs first time instantly
@@ -389,12 +534,12 @@ func (r *ReconcilePerconaXtraDBCluster) proxyDB(cr *api.PerconaXtraDBCluster) (q
 	} else {
 		return database, errors.New("can't detect enabled proxy, please enable HAProxy or ProxySQL")
 	}
-	secrets := cr.Spec.SecretsName
+	secrets := cr.Spec.UsersSecretName(cr.Name)
 	if cr.CompareVersionWith("1.6.0") >= 0 {
 		secrets = "internal-" + cr.Name
 	}
 	for i := 0; ; i++ {
-		db, err := queries.New(r.client, cr.Namespace, secrets, user, host, port)
+		db, err := queries.New(credentials.NewSecret(r.client, cr.Namespace, secrets), user, host, port)
 		if err != nil && i < int(proxySize) {
 			time.Sleep(time.Second)
 		} else if err != nil && i == int(proxySize) {
@@ -409,6 +554,10 @@ func (r *ReconcilePerconaXtraDBCluster) proxyDB(cr *api.PerconaXtraDBCluster) (q
 }
 
 func (r *ReconcilePerconaXtraDBCluster) getPrimaryPod(cr *api.PerconaXtraDBCluster) (string, error) {
+	if (cr.Spec.HAProxy == nil || !cr.Spec.HAProxy.Enabled) && (cr.Spec.ProxySQL == nil || !cr.Spec.ProxySQL.Enabled) {
+		return r.primaryWithoutProxy(cr)
+	}
+
 	database, err := r.proxyDB(cr)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get proxySQL db")
@@ -425,19 +574,67 @@ func (r *ReconcilePerconaXtraDBCluster) getPrimaryPod(cr *api.PerconaXtraDBClust
 		return host, nil
 	}
 
+	if cr.ProxySQLManaged() {
+		return database.PrimaryHostIn(cr.Spec.ProxySQL.Hostgroups.Writer)
+	}
+
 	return database.PrimaryHost()
 }
 
+// primaryWithoutProxy returns the pod used as the primary if there is no proxy
+// to ask. Clients can write to any pod then, so the synced pod with the lowest
+// ordinal is chosen and it is restarted last.
+func (r *ReconcilePerconaXtraDBCluster) primaryWithoutProxy(cr *api.PerconaXtraDBCluster) (string, error) {
+	list := corev1.PodList{}
+	err := r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(statefulset.NewNode(cr).Labels()),
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "get pxc pods list")
+	}
+
+	for _, pod := range primaryCandidates(list.Items) {
+		database, err := r.pxcDB(cr, pod.Name)
+		if err != nil {
+			continue
+		}
+		state, err := database.WsrepLocalStateComment()
+		database.Close()
+		if err == nil && state == "Synced" {
+			return pod.Name, nil
+		}
+	}
+
+	return "", errors.New("no synced pxc pods found")
+}
+
+// primaryCandidates returns the ready pods ordered by the ordinal
+func primaryCandidates(pods []corev1.Pod) []corev1.Pod {
+	var ready []corev1.Pod
+	for _, pod := range pods {
+		if isContainersReady(pod) {
+			ready = append(ready, pod)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return podOrdinal(ready[i].Name) < podOrdinal(ready[j].Name) })
+
+	return ready
+}
+
 func (r *ReconcilePerconaXtraDBCluster) waitPXCSynced(cr *api.PerconaXtraDBCluster, host string, waitLimit int) error {
 	user := "root"
-	secrets := cr.Spec.SecretsName
+	secrets := cr.Spec.UsersSecretName(cr.Name)
 	port := int32(3306)
 	if cr.CompareVersionWith("1.6.0") >= 0 {
 		secrets = "internal-" + cr.Name
 		port = int32(33062)
 	}
 
-	database, err := queries.New(r.client, cr.Namespace, secrets, user, host, port)
+	database, err := queries.New(credentials.NewSecret(r.client, cr.Namespace, secrets), user, host, port)
 	if err != nil {
 		return errors.Wrap(err, "failed to access PXC database")
 	}
@@ -516,9 +713,19 @@ func (r *ReconcilePerconaXtraDBCluster) isBackupRunning(cr *api.PerconaXtraDBClu
 func (r *ReconcilePerconaXtraDBCluster) getConfigHash(cr *api.PerconaXtraDBCluster, sfs api.StatefulApp) string {
 	configString := cr.Spec.PXC.Configuration
 	if sfs.Labels()["app.kubernetes.io/component"] == "haproxy" {
+		// the backends rendered by the operator aren't hashed,
+		// the haproxy-reload sidecar reloads HAProxy on their change
 		configString = cr.Spec.HAProxy.Configuration
 	} else if sfs.Labels()["app.kubernetes.io/component"] == "proxysql" {
 		configString = cr.Spec.ProxySQL.Configuration
+	} else if cr.CompareVersionWith("1.8.0") >= 0 {
+		// dynamic variables are applied online, see reconcilePXCConfigMap
+		hash := r.appliedConfigHash(cr)
+		if cr.Spec.PXC.AutoTune == nil {
+			return hash
+		}
+		// the auto-config isn't reloaded without a restart
+		configString = hash + fmt.Sprintf("%+v", *cr.Spec.PXC.AutoTune)
 	}
 	hash := fmt.Sprintf("%x", md5.Sum([]byte(configString)))
 
@@ -0,0 +1,99 @@
+package pxc
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func planRequired(cr *api.PerconaXtraDBCluster) bool {
+	return cr.CompareVersionWith("1.8.0") >= 0 && cr.Spec.UpgradeOptions.PlanOnly &&
+		cr.Status.PXC.Version != ""
+}
+
+// upgradePlan returns the components whose images are changed by the new
+// versions, the same ones ensurePXCVersion updates. It returns nil if
+// there is nothing to upgrade.
+func upgradePlan(cr *api.PerconaXtraDBCluster, newVersion DepVersion) *api.UpgradePlan {
+	var components []api.ComponentUpgrade
+	add := func(name string, status api.AppStatus, image string, version, newImage string) {
+		if image == newImage {
+			return
+		}
+		components = append(components, api.ComponentUpgrade{
+			Name:        name,
+			FromVersion: status.Version,
+			ToVersion:   version,
+			FromImage:   image,
+			ToImage:     newImage,
+		})
+	}
+
+	if cr.Spec.PXC != nil {
+		add("pxc", cr.Status.PXC, cr.Spec.PXC.Image, newVersion.PXCVersion, newVersion.PXCImage)
+	}
+	if cr.Spec.Backup != nil {
+		add("backup", cr.Status.Backup, cr.Spec.Backup.Image, newVersion.BackupVersion, newVersion.BackupImage)
+	}
+	if cr.Spec.PMM != nil && cr.Spec.PMM.Enabled {
+		add("pmm", cr.Status.PMM, cr.Spec.PMM.Image, newVersion.PMMVersion, newVersion.PMMImage)
+	}
+	if cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled {
+		add("proxysql", cr.Status.ProxySQL, cr.Spec.ProxySQL.Image, newVersion.ProxySqlVersion, newVersion.ProxySqlImage)
+	}
+	if cr.Spec.HAProxy != nil && cr.Spec.HAProxy.Enabled {
+		add("haproxy", cr.Status.HAProxy, cr.Spec.HAProxy.Image, newVersion.HAProxyVersion, newVersion.HAProxyImage)
+	}
+	if cr.Spec.LogCollector != nil && cr.Spec.LogCollector.Enabled {
+		add("logcollector", cr.Status.LogCollector, cr.Spec.LogCollector.Image, newVersion.LogCollectorVersion, newVersion.LogCollectorImage)
+	}
+
+	if len(components) == 0 {
+		return nil
+	}
+
+	images := make([]string, 0, len(components))
+	for _, c := range components {
+		images = append(images, c.Name+"="+c.ToImage)
+	}
+
+	return &api.UpgradePlan{
+		ID:         fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(images, ","))))[:10],
+		Components: components,
+	}
+}
+
+// waitPlanApproval keeps the plan in the status until it's approved with
+// the annotation. The new plan is also reported with an event.
+func (r *ReconcilePerconaXtraDBCluster) waitPlanApproval(cr *api.PerconaXtraDBCluster, plan *api.UpgradePlan) error {
+	if cr.Status.UpgradePlan != nil && cr.Status.UpgradePlan.ID == plan.ID {
+		return nil
+	}
+
+	now := metav1.Now()
+	plan.PlannedAt = &now
+	cr.Status.UpgradePlan = plan
+
+	changes := make([]string, 0, len(plan.Components))
+	for _, c := range plan.Components {
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", c.Name, c.FromVersion, c.ToVersion))
+	}
+	r.logger(cr.Name, cr.Namespace).Info("upgrade is planned", "plan", plan.ID, "changes", changes)
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventUpgradePlanned,
+		"Upgrade plan %s: %s. Set annotation %s=%s to apply it",
+		plan.ID, strings.Join(changes, ", "), api.UpgradePlanApprovalAnnotation, plan.ID)
+
+	err := r.client.Status().Update(context.TODO(), cr)
+	if err != nil {
+		return errors.Wrap(err, "update CR status")
+	}
+
+	return nil
+}
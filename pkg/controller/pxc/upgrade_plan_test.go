@@ -0,0 +1,92 @@
+package pxc
+
+import (
+	"testing"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestUpgradePlan(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{
+		Spec: api.PerconaXtraDBClusterSpec{
+			PXC:      &api.PXCSpec{PodSpec: &api.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.21-12.1"}},
+			HAProxy:  &api.PodSpec{Enabled: true, Image: "percona/percona-xtradb-cluster-operator:1.8.0-haproxy"},
+			ProxySQL: &api.PodSpec{Enabled: false, Image: "percona/percona-xtradb-cluster-operator:1.7.0-proxysql"},
+			Backup:   &api.PXCScheduledBackup{Image: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup"},
+		},
+		Status: api.PerconaXtraDBClusterStatus{
+			PXC: api.AppStatus{Version: "8.0.21-12.1"},
+		},
+	}
+	current := DepVersion{
+		PXCImage:      cr.Spec.PXC.Image,
+		HAProxyImage:  cr.Spec.HAProxy.Image,
+		ProxySqlImage: cr.Spec.ProxySQL.Image,
+		BackupImage:   cr.Spec.Backup.Image,
+	}
+
+	tests := map[string]struct {
+		version    func(v DepVersion) DepVersion
+		components []string
+	}{
+		"nothing to upgrade": {
+			version: func(v DepVersion) DepVersion { return v },
+		},
+		"pxc": {
+			version: func(v DepVersion) DepVersion {
+				v.PXCImage, v.PXCVersion = "percona/percona-xtradb-cluster:8.0.22-13.1", "8.0.22-13.1"
+				return v
+			},
+			components: []string{"pxc"},
+		},
+		"pxc and haproxy": {
+			version: func(v DepVersion) DepVersion {
+				v.PXCImage = "percona/percona-xtradb-cluster:8.0.22-13.1"
+				v.HAProxyImage = "percona/percona-xtradb-cluster-operator:1.9.0-haproxy"
+				return v
+			},
+			components: []string{"pxc", "haproxy"},
+		},
+		"disabled proxysql": {
+			version: func(v DepVersion) DepVersion {
+				v.ProxySqlImage = "percona/percona-xtradb-cluster-operator:1.9.0-proxysql"
+				return v
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		plan := upgradePlan(cr, tt.version(current))
+		if len(tt.components) == 0 {
+			if plan != nil {
+				t.Errorf("%s: expected no plan, got %+v", name, plan)
+			}
+			continue
+		}
+		if plan == nil {
+			t.Errorf("%s: expected the plan", name)
+			continue
+		}
+		if len(plan.Components) != len(tt.components) {
+			t.Errorf("%s: expected components %v, got %+v", name, tt.components, plan.Components)
+			continue
+		}
+		for i, c := range plan.Components {
+			if c.Name != tt.components[i] {
+				t.Errorf("%s: expected component %s, got %s", name, tt.components[i], c.Name)
+			}
+		}
+		if plan.Components[0].FromImage != cr.Spec.PXC.Image || plan.Components[0].FromVersion != "8.0.21-12.1" {
+			t.Errorf("%s: unexpected pxc upgrade %+v", name, plan.Components[0])
+		}
+	}
+
+	// the plan is approved by the ID, it depends only on the target images
+	v := tests["pxc"].version(current)
+	if a, b := upgradePlan(cr, v), upgradePlan(cr, v); a.ID != b.ID {
+		t.Errorf("plan ID isn't stable: %s, %s", a.ID, b.ID)
+	}
+	if a, b := upgradePlan(cr, v), upgradePlan(cr, tests["pxc and haproxy"].version(current)); a.ID == b.ID {
+		t.Errorf("plans of different images have the same ID %s", a.ID)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/users"
@@ -18,19 +19,17 @@ import (
 
 const internalPrefix = "internal-"
 
+// podUsers are the system users whose passwords are passed to the pods.
+// Only they are copied from the external secrets provider to the internal
+// secret, any other data of the provider isn't stored in the cluster.
+var podUsers = []string{"root", "xtrabackup", "monitor", "clustercheck", "proxyadmin", "operator", "pmmserver"}
+
 func (r *ReconcilePerconaXtraDBCluster) reconcileUsers(cr *api.PerconaXtraDBCluster) (pxcAnnotations, proxysqlAnnotations map[string]string, err error) {
-	sysUsersSecretObj := corev1.Secret{}
-	err = r.client.Get(context.TODO(),
-		types.NamespacedName{
-			Namespace: cr.Namespace,
-			Name:      cr.Spec.SecretsName,
-		},
-		&sysUsersSecretObj,
-	)
+	sysUsersSecretObj, err := r.sysUsersSecret(cr)
 	if err != nil && k8serrors.IsNotFound(err) {
 		return nil, nil, nil
 	} else if err != nil {
-		return nil, nil, errors.Wrapf(err, "get sys users secret '%s'", cr.Spec.SecretsName)
+		return nil, nil, errors.Wrap(err, "get sys users credentials")
 	}
 
 	secretName := internalPrefix + cr.Name
@@ -62,7 +61,7 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileUsers(cr *api.PerconaXtraDBClus
 	}
 
 	if cr.Status.PXC.Ready > 0 {
-		err := r.manageOperatorAdminUser(cr, &sysUsersSecretObj, &internalSysSecretObj)
+		err := r.manageOperatorAdminUser(cr, sysUsersSecretObj, &internalSysSecretObj)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "manage operator admin user")
 		}
@@ -101,7 +100,7 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileUsers(cr *api.PerconaXtraDBClus
 		return nil, nil, nil
 	}
 
-	restartPXC, restartProxy, err := r.manageSysUsers(cr, &sysUsersSecretObj, &internalSysSecretObj)
+	restartPXC, restartProxy, err := r.manageSysUsers(cr, sysUsersSecretObj, &internalSysSecretObj)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "manage sys users")
 	}
@@ -124,6 +123,48 @@ func (r *ReconcilePerconaXtraDBCluster) reconcileUsers(cr *api.PerconaXtraDBClus
 	return pxcAnnotations, proxysqlAnnotations, nil
 }
 
+// sysUsersSecret returns the secret with the system users credentials.
+// If the external secrets provider is used, the secret is filled with
+// the provider's passwords of podUsers and doesn't exist in the cluster.
+func (r *ReconcilePerconaXtraDBCluster) sysUsersSecret(cr *api.PerconaXtraDBCluster) (*corev1.Secret, error) {
+	secretObj := &corev1.Secret{}
+
+	vault := cr.Spec.VaultSecretsProvider()
+	if vault == nil {
+		err := r.client.Get(context.TODO(),
+			types.NamespacedName{
+				Namespace: cr.Namespace,
+				Name:      cr.Spec.SecretsName,
+			},
+			secretObj,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return secretObj, nil
+	}
+
+	src, err := r.vaults.Vault(r.client, cr.Namespace, cr.Name, *vault)
+	if err != nil {
+		return nil, errors.Wrap(err, "init vault source")
+	}
+	data, err := src.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	secretObj.Name = cr.Spec.SecretsName
+	secretObj.Namespace = cr.Namespace
+	secretObj.Data = make(map[string][]byte, len(podUsers))
+	for _, user := range podUsers {
+		if pass, ok := data[user]; ok {
+			secretObj.Data[user] = pass
+		}
+	}
+
+	return secretObj, nil
+}
+
 func (r *ReconcilePerconaXtraDBCluster) manageMonitorUser(cr *api.PerconaXtraDBCluster, internalSysSecretObj *corev1.Secret) error {
 	annotationName := "grant-for-1.6.0-monitor-user"
 	if internalSysSecretObj.Annotations[annotationName] == "done" {
@@ -281,6 +322,7 @@ func (r *ReconcilePerconaXtraDBCluster) manageSysUsers(cr *api.PerconaXtraDBClus
 	}
 
 	var sysUsers, proxyUsers []users.SysUser
+	var changed []string
 	var todo action
 	for _, user := range requiredUsers {
 		if len(sysUsersSecretObj.Data[user.name]) == 0 {
@@ -292,6 +334,7 @@ func (r *ReconcilePerconaXtraDBCluster) manageSysUsers(cr *api.PerconaXtraDBClus
 		}
 
 		todo |= user.action
+		changed = append(changed, user.name)
 
 		pass := string(sysUsersSecretObj.Data[user.name])
 
@@ -350,6 +393,10 @@ func (r *ReconcilePerconaXtraDBCluster) manageSysUsers(cr *api.PerconaXtraDBClus
 		}
 	}
 
+	if len(changed) > 0 {
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventUsersPasswordChanged, "Password changed for system users: %s", strings.Join(changed, ", "))
+	}
+
 	return restartPXC, restartProxy, nil
 }
 
@@ -357,6 +404,10 @@ func (r *ReconcilePerconaXtraDBCluster) syncPXCUsersWithProxySQL(cr *api.Percona
 	if cr.Status.Status != api.AppStateReady || cr.Status.ProxySQL.Status != api.AppStateReady {
 		return nil
 	}
+	// the users are synced by reconcileProxySQL
+	if cr.ProxySQLManaged() {
+		return nil
+	}
 	// sync users if ProxySql enabled
 	if cr.Spec.ProxySQL == nil || !cr.Spec.ProxySQL.Enabled || cr.Status.ObservedGeneration != cr.Generation || cr.Status.PXC.Ready < 1 {
 		return nil
@@ -418,6 +469,10 @@ func (r *ReconcilePerconaXtraDBCluster) manageOperatorAdminUser(cr *api.PerconaX
 	if existInSys {
 		return nil
 	}
+	if cr.Spec.VaultSecretsProvider() != nil {
+		// operator can't write the generated password back to vault
+		return errors.New("operator user password should be set in vault")
+	}
 
 	pass, err := generatePass()
 	if err != nil {
@@ -9,7 +9,7 @@ import (
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	v1 "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
-	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/queries"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 	corev1 "k8s.io/api/core/v1"
@@ -27,7 +27,7 @@ func (r *ReconcilePerconaXtraDBCluster) deleteEnsureVersion(jobName string) {
 	delete(r.crons.jobs, jobName)
 }
 
-func (r *ReconcilePerconaXtraDBCluster) sheduleEnsurePXCVersion(cr *api.PerconaXtraDBCluster, vs VersionService) error {
+func (r *ReconcilePerconaXtraDBCluster) sheduleEnsurePXCVersion(cr *api.PerconaXtraDBCluster) error {
 	jn := jobName(cr)
 	schedule, ok := r.crons.jobs[jn]
 	if cr.Spec.UpdateStrategy != v1.SmartUpdateStatefulSetStrategyType ||
@@ -91,7 +91,7 @@ func (r *ReconcilePerconaXtraDBCluster) sheduleEnsurePXCVersion(cr *api.PerconaX
 			return
 		}
 
-		err = r.ensurePXCVersion(localCr, vs)
+		err = r.ensurePXCVersion(localCr, r.versionService(localCr))
 		if err != nil {
 			logger.Error(err, "failed to ensure version")
 		}
@@ -143,17 +143,40 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 		LogCollectorVersion: cr.Status.LogCollector.Version,
 		CRUID:               string(cr.GetUID()),
 	})
+	metrics.VersionServiceChecks.WithLabelValues(cr.Name, cr.Namespace, metrics.Result(err)).Inc()
 	if err != nil {
 		return errors.Wrap(err, "failed to check version")
 	}
+	metrics.VersionServiceLastCheck.WithLabelValues(cr.Name, cr.Namespace).SetToCurrentTime()
 
 	logger := r.logger(cr.Name, cr.Namespace)
 
+	if image := failedUpgradeImage(cr.Status.FailedUpgrade, newVersion); len(image) > 0 {
+		logger.Info("skip the upgrade rolled back before, remove status.failedUpgrade to retry it", "image", image)
+		return nil
+	}
+
+	if planRequired(cr) {
+		plan := upgradePlan(cr, newVersion)
+		if plan != nil && cr.Annotations[v1.UpgradePlanApprovalAnnotation] != plan.ID {
+			return r.waitPlanApproval(cr, plan)
+		}
+	}
+
+	var knownGood *v1.KnownGoodState
+	if rollbackEnabled(cr) && cr.Status.PXC.Version != "" {
+		knownGood, err = r.knownGoodState(cr)
+		if err != nil {
+			return errors.Wrap(err, "get known-good state")
+		}
+	}
+
 	if cr.Spec.PXC != nil && cr.Spec.PXC.Image != newVersion.PXCImage {
 		if cr.Status.PXC.Version == "" {
 			logger.Info("set PXC version to " + newVersion.PXCVersion)
 		} else {
 			logger.Info("update PXC version", "old version", cr.Status.PXC.Version, "new version", newVersion.PXCVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading PXC from %s to %s", cr.Status.PXC.Version, newVersion.PXCVersion)
 		}
 		cr.Spec.PXC.Image = newVersion.PXCImage
 	}
@@ -163,6 +186,7 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 			logger.Info("set Backup version to " + newVersion.BackupVersion)
 		} else {
 			logger.Info("update Backup version", "old version", cr.Status.Backup.Version, "new version", newVersion.BackupVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading Backup from %s to %s", cr.Status.Backup.Version, newVersion.BackupVersion)
 		}
 		cr.Spec.Backup.Image = newVersion.BackupImage
 	}
@@ -172,6 +196,7 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 			logger.Info("set PMM version to " + newVersion.PMMVersion)
 		} else {
 			logger.Info("update PMM version", "old version", cr.Status.PMM.Version, "new version", newVersion.PMMVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading PMM from %s to %s", cr.Status.PMM.Version, newVersion.PMMVersion)
 		}
 		cr.Spec.PMM.Image = newVersion.PMMImage
 	}
@@ -181,6 +206,7 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 			logger.Info("set ProxySQL version to " + newVersion.ProxySqlVersion)
 		} else {
 			logger.Info("update ProxySQL version", "old version", cr.Status.ProxySQL.Version, "new version", newVersion.ProxySqlVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading ProxySQL from %s to %s", cr.Status.ProxySQL.Version, newVersion.ProxySqlVersion)
 		}
 		cr.Spec.ProxySQL.Image = newVersion.ProxySqlImage
 	}
@@ -190,6 +216,7 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 			logger.Info("set HAProxy version to " + newVersion.HAProxyVersion)
 		} else {
 			logger.Info("update HAProxy version", "old version", cr.Status.HAProxy.Version, "new version", newVersion.HAProxyVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading HAProxy from %s to %s", cr.Status.HAProxy.Version, newVersion.HAProxyVersion)
 		}
 		cr.Spec.HAProxy.Image = newVersion.HAProxyImage
 	}
@@ -199,6 +226,7 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 			logger.Info("set LogCollector version to " + newVersion.LogCollectorVersion)
 		} else {
 			logger.Info("update LogCollector version", "old version", cr.Status.LogCollector.Version, "new version", newVersion.LogCollectorVersion)
+			r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVersionUpgrade, "Upgrading LogCollector from %s to %s", cr.Status.LogCollector.Version, newVersion.LogCollectorVersion)
 		}
 		cr.Spec.LogCollector.Image = newVersion.LogCollectorImage
 	}
@@ -222,6 +250,10 @@ func (r *ReconcilePerconaXtraDBCluster) ensurePXCVersion(cr *api.PerconaXtraDBCl
 	cr.Status.PXC.Version = newVersion.PXCVersion
 	cr.Status.PXC.Image = newVersion.PXCImage
 	cr.Status.LogCollector.Version = newVersion.LogCollectorVersion
+	if knownGood != nil {
+		cr.Status.LastKnownGood = knownGood
+	}
+	cr.Status.UpgradePlan = nil
 
 	err = r.client.Status().Update(context.Background(), cr)
 	if err != nil {
@@ -265,16 +297,10 @@ func (r *ReconcilePerconaXtraDBCluster) fetchVersionFromPXC(cr *api.PerconaXtraD
 		return errors.Wrap(err, "get pod list")
 	}
 
-	user := "root"
-	port := int32(3306)
-	if cr.CompareVersionWith("1.6.0") >= 0 {
-		port = int32(33062)
-	}
-
 	logger := r.logger(cr.Name, cr.Namespace)
 
 	for _, pod := range list.Items {
-		database, err := queries.New(r.client, cr.Namespace, cr.Spec.SecretsName, user, pod.Name+"."+cr.Name+"-pxc."+cr.Namespace, port)
+		database, err := r.pxcDB(cr, pod.Name)
 		if err != nil {
 			logger.Error(err, "failed to create db instance")
 			continue
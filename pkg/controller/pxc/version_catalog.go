@@ -0,0 +1,217 @@
+package pxc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	v "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+	"github.com/percona/percona-xtradb-cluster-operator/versionserviceclient/models"
+)
+
+// versionCatalogKey is the key of the catalog config map. It holds the
+// response of the version service endpoint /versions/v1/pxc-operator,
+// i.e. the version matrices of all operator versions.
+const versionCatalogKey = "versions.json"
+
+// VersionCatalogClient resolves the versions against the catalog kept in
+// the config map, for the clusters without access to the version service
+type VersionCatalogClient struct {
+	OpVersion string
+	client    client.Client
+}
+
+func (r *ReconcilePerconaXtraDBCluster) versionService(cr *api.PerconaXtraDBCluster) VersionService {
+	if len(cr.Spec.UpgradeOptions.VersionCatalog) > 0 {
+		return VersionCatalogClient{OpVersion: cr.Version().String(), client: r.client}
+	}
+	return VersionServiceClient{OpVersion: cr.Version().String()}
+}
+
+// GetExactVersion resolves the versions the same way the version service does.
+// The endpoint is ignored.
+func (vc VersionCatalogClient) GetExactVersion(cr *api.PerconaXtraDBCluster, endpoint string, vm versionMeta) (DepVersion, error) {
+	cm := &corev1.ConfigMap{}
+	err := vc.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.UpgradeOptions.VersionCatalog}, cm)
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "get version catalog")
+	}
+
+	data, ok := cm.Data[versionCatalogKey]
+	if !ok {
+		return DepVersion{}, errors.Errorf("config map %s has no %s key", cm.Name, versionCatalogKey)
+	}
+
+	catalog := models.VersionOperatorResponse{}
+	err = json.Unmarshal([]byte(data), &catalog)
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "parse version catalog")
+	}
+
+	var matrix *models.VersionVersionMatrix
+	for _, ov := range catalog.Versions {
+		if ov != nil && ov.Operator == vc.OpVersion && (ov.Product == "" || ov.Product == productName) {
+			matrix = ov.Matrix
+			break
+		}
+	}
+	if matrix == nil {
+		return DepVersion{}, errors.Errorf("operator version %s isn't in the version catalog", vc.OpVersion)
+	}
+
+	return resolveVersions(matrix, vm, cr.CompareVersionWith("1.7.0") >= 0)
+}
+
+// resolveVersions picks the versions from the matrix by the apply option:
+// recommended, latest, <major>-recommended, <major>-latest or the exact PXC
+// version. The major version of the running PXC is kept unless it's set
+// explicitly. The other products get the latest versions for latest and
+// the recommended ones otherwise.
+func resolveVersions(matrix *models.VersionVersionMatrix, vm versionMeta, withLogCollector bool) (DepVersion, error) {
+	apply := strings.ToLower(strings.TrimSpace(vm.Apply))
+	deps := "recommended"
+	if apply == "latest" || strings.HasSuffix(apply, "-latest") {
+		deps = "latest"
+	}
+
+	dv := DepVersion{}
+	var err error
+
+	dv.PXCVersion, dv.PXCImage, err = selectVersion(matrix.Pxc, apply, vm.PXCVersion)
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "pxc")
+	}
+	dv.BackupVersion, dv.BackupImage, err = selectVersion(backupVersions(matrix.Backup, majorVersion(dv.PXCVersion)), deps, "")
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "backup")
+	}
+	dv.PMMVersion, dv.PMMImage, err = selectVersion(matrix.Pmm, deps, "")
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "pmm")
+	}
+	dv.ProxySqlVersion, dv.ProxySqlImage, err = selectVersion(matrix.Proxysql, deps, "")
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "proxysql")
+	}
+	dv.HAProxyVersion, dv.HAProxyImage, err = selectVersion(matrix.Haproxy, deps, "")
+	if err != nil {
+		return DepVersion{}, errors.Wrap(err, "haproxy")
+	}
+	if withLogCollector {
+		dv.LogCollectorVersion, dv.LogCollectorImage, err = selectVersion(matrix.LogCollector, deps, "")
+		if err != nil {
+			return DepVersion{}, errors.Wrap(err, "log collector")
+		}
+	}
+
+	return dv, nil
+}
+
+// selectVersion returns the version and the image picked by the apply option.
+// If current is set, recommended and latest stay on its major version.
+func selectVersion(versions map[string]models.VersionVersion, apply, current string) (string, string, error) {
+	mode := apply
+	prefix := ""
+	if i := strings.LastIndex(apply, "-"); i > 0 {
+		if suffix := apply[i+1:]; suffix == "recommended" || suffix == "latest" {
+			prefix, mode = apply[:i], suffix
+		}
+	}
+
+	if mode != "recommended" && mode != "latest" {
+		ver, ok := versions[apply]
+		if !ok {
+			return "", "", errors.Errorf("version %s isn't in the catalog", apply)
+		}
+		return apply, catalogImage(ver), nil
+	}
+
+	if len(prefix) == 0 && len(current) > 0 {
+		prefix = majorVersion(current)
+	}
+
+	best := ""
+	for name, ver := range versions {
+		if ver.Status == models.VersionStatusDisabled {
+			continue
+		}
+		if mode == "recommended" && ver.Status != models.VersionStatusRecommended {
+			continue
+		}
+		if len(prefix) > 0 && name != prefix && !strings.HasPrefix(name, prefix+".") {
+			continue
+		}
+		if len(best) == 0 || newerVersion(name, best) {
+			best = name
+		}
+	}
+	if len(best) == 0 {
+		return "", "", errors.Errorf("no %s version in the catalog", apply)
+	}
+
+	return best, catalogImage(versions[best]), nil
+}
+
+// backupVersions returns the backup versions for the major version of PXC.
+// XtraBackup 2.4 works with PXC 5.7 and XtraBackup 8.0 with PXC 8.0.
+func backupVersions(versions map[string]models.VersionVersion, pxcMajor string) map[string]models.VersionVersion {
+	filtered := make(map[string]models.VersionVersion, len(versions))
+	for name, ver := range versions {
+		major := config.ImageMajorVersion(ver.ImagePath)
+		if len(major) == 0 {
+			major = majorVersion(name)
+			if major == "2.4" {
+				major = "5.7"
+			}
+		}
+		if major == pxcMajor {
+			filtered[name] = ver
+		}
+	}
+
+	return filtered
+}
+
+// majorVersion returns the major version of PXC, e.g. 8.0 for 8.0.21-12.1
+func majorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+func newerVersion(a, b string) bool {
+	va, errA := v.NewVersion(a)
+	vb, errB := v.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.GreaterThan(vb)
+}
+
+// catalogImage pins the image to its digest if the catalog has it, so the
+// image mirrored to the private registry can't be replaced
+func catalogImage(ver models.VersionVersion) string {
+	if len(ver.ImageHash) == 0 {
+		return ver.ImagePath
+	}
+
+	image := ver.ImagePath
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	digest := ver.ImageHash
+	if !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+
+	return image + "@" + digest
+}
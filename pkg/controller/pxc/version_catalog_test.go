@@ -0,0 +1,121 @@
+package pxc
+
+import (
+	"testing"
+
+	"github.com/percona/percona-xtradb-cluster-operator/versionserviceclient/models"
+)
+
+func TestResolveVersions(t *testing.T) {
+	version := func(image string, status models.VersionStatus) models.VersionVersion {
+		return models.VersionVersion{ImagePath: image, Status: status}
+	}
+	matrix := &models.VersionVersionMatrix{
+		Pxc: map[string]models.VersionVersion{
+			"5.7.31-31.45": version("percona/percona-xtradb-cluster:5.7.31-31.45", models.VersionStatusRecommended),
+			"5.7.32-31.47": version("percona/percona-xtradb-cluster:5.7.32-31.47", models.VersionStatusAvailable),
+			"8.0.20-11.1":  version("percona/percona-xtradb-cluster:8.0.20-11.1", models.VersionStatusRecommended),
+			"8.0.21-12.1":  version("percona/percona-xtradb-cluster:8.0.21-12.1", models.VersionStatusRecommended),
+			"8.0.22-13.1":  version("percona/percona-xtradb-cluster:8.0.22-13.1", models.VersionStatusAvailable),
+			"8.0.23-14.1":  version("percona/percona-xtradb-cluster:8.0.23-14.1", models.VersionStatusDisabled),
+		},
+		Backup: map[string]models.VersionVersion{
+			"2.4.20": version("percona/percona-xtrabackup:2.4.20", models.VersionStatusRecommended),
+			"2.4.21": version("percona/percona-xtradb-cluster-operator:1.8.0-pxc5.7-backup", models.VersionStatusAvailable),
+			"8.0.14": {
+				ImagePath: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup",
+				ImageHash: "1f3a",
+				Status:    models.VersionStatusRecommended,
+			},
+		},
+		Pmm: map[string]models.VersionVersion{
+			"2.12.0": version("percona/pmm-client:2.12.0", models.VersionStatusRecommended),
+			"2.14.0": version("percona/pmm-client:2.14.0", models.VersionStatusAvailable),
+		},
+		Proxysql: map[string]models.VersionVersion{
+			"2.0.15": version("percona/percona-xtradb-cluster-operator:1.8.0-proxysql", models.VersionStatusRecommended),
+		},
+		Haproxy: map[string]models.VersionVersion{
+			"2.3.2": version("percona/percona-xtradb-cluster-operator:1.8.0-haproxy", models.VersionStatusRecommended),
+		},
+	}
+
+	cases := []struct {
+		name    string
+		vm      versionMeta
+		pxc     string
+		pmm     string
+		backup  string
+		invalid bool
+	}{
+		{
+			name:   "recommended",
+			vm:     versionMeta{Apply: "recommended"},
+			pxc:    "8.0.21-12.1",
+			pmm:    "2.12.0",
+			backup: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup@sha256:1f3a",
+		},
+		{
+			name:   "latest",
+			vm:     versionMeta{Apply: "Latest"},
+			pxc:    "8.0.22-13.1",
+			pmm:    "2.14.0",
+			backup: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup@sha256:1f3a",
+		},
+		{
+			name:   "major version is kept",
+			vm:     versionMeta{Apply: "latest", PXCVersion: "5.7.31-31.45"},
+			pxc:    "5.7.32-31.47",
+			pmm:    "2.14.0",
+			backup: "percona/percona-xtradb-cluster-operator:1.8.0-pxc5.7-backup",
+		},
+		{
+			name:   "backup of the major version",
+			vm:     versionMeta{Apply: "5.7-recommended"},
+			pxc:    "5.7.31-31.45",
+			pmm:    "2.12.0",
+			backup: "percona/percona-xtrabackup:2.4.20",
+		},
+		{
+			name:   "explicit major version",
+			vm:     versionMeta{Apply: "8.0-recommended", PXCVersion: "5.7.31-31.45"},
+			pxc:    "8.0.21-12.1",
+			pmm:    "2.12.0",
+			backup: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup@sha256:1f3a",
+		},
+		{
+			name:   "exact version",
+			vm:     versionMeta{Apply: "8.0.20-11.1"},
+			pxc:    "8.0.20-11.1",
+			pmm:    "2.12.0",
+			backup: "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup@sha256:1f3a",
+		},
+		{
+			name:    "unknown version",
+			vm:      versionMeta{Apply: "8.0.99-1.1"},
+			invalid: true,
+		},
+	}
+
+	for _, c := range cases {
+		dv, err := resolveVersions(matrix, c.vm, false)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%s: expected error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if dv.PXCVersion != c.pxc || dv.PXCImage != matrix.Pxc[c.pxc].ImagePath {
+			t.Errorf("%s: got pxc %s (%s), expected %s", c.name, dv.PXCVersion, dv.PXCImage, c.pxc)
+		}
+		if dv.PMMVersion != c.pmm {
+			t.Errorf("%s: got pmm %s, expected %s", c.name, dv.PMMVersion, c.pmm)
+		}
+		if dv.BackupImage != c.backup {
+			t.Errorf("%s: got backup image %s, expected %s", c.name, dv.BackupImage, c.backup)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package pxc
+
+import (
+	"context"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// resizeAnnotation is set on the PXC statefulset while its pods are
+// restarted with the new resources
+const resizeAnnotation = "percona.com/resize-in-progress"
+
+// reconcileResize starts the vertical scaling if the resources of the pxc
+// container were changed. The auto-tuned dynamic variables are applied online
+// before any restart. It returns true while the pods have to be restarted in
+// the SmartUpdate order (replicas first, the writer last), the statefulset
+// controller doesn't restart them meanwhile even on RollingUpdate.
+// resizeAnnotation is kept on the statefulset while the scaling is in progress.
+func (r *ReconcilePerconaXtraDBCluster) reconcileResize(cr *api.PerconaXtraDBCluster, currentSet *appsv1.StatefulSet, appC corev1.Container) bool {
+	// pods are restarted by the user
+	if cr.Spec.UpdateStrategy == appsv1.OnDeleteStatefulSetStrategyType {
+		return false
+	}
+
+	var current *corev1.Container
+	for i := range currentSet.Spec.Template.Spec.Containers {
+		if currentSet.Spec.Template.Spec.Containers[i].Name == appC.Name {
+			current = &currentSet.Spec.Template.Spec.Containers[i]
+		}
+	}
+
+	changed := current != nil && resourcesChanged(current.Resources, appC.Resources)
+
+	if currentSet.Annotations[resizeAnnotation] == "true" {
+		finished := !changed &&
+			currentSet.Status.ObservedGeneration >= currentSet.Generation &&
+			currentSet.Status.UpdatedReplicas >= currentSet.Status.Replicas
+		if !finished {
+			currentSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+			return true
+		}
+
+		delete(currentSet.Annotations, resizeAnnotation)
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVerticalScalingFinished, "All pods of statefulset %s are running with the new resources", currentSet.Name)
+		return false
+	}
+
+	if !changed {
+		return false
+	}
+
+	if cr.CompareVersionWith("1.3.0") >= 0 {
+		r.applyAutoTuneOnline(cr)
+	}
+
+	if currentSet.Annotations == nil {
+		currentSet.Annotations = make(map[string]string)
+	}
+	currentSet.Annotations[resizeAnnotation] = "true"
+	currentSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVerticalScalingStarted, "Resources of statefulset %s were changed, restarting pods with the new resources", currentSet.Name)
+	return true
+}
+
+// applyAutoTuneOnline sets the new auto-tuned values on the running pods.
+// The buffer pool is only shrunk online, it can't grow above the memory
+// of the running container until the pod is restarted with the new limits.
+func (r *ReconcilePerconaXtraDBCluster) applyAutoTuneOnline(cr *api.PerconaXtraDBCluster) {
+	logger := r.logger(cr.Name, cr.Namespace)
+
+	if len(config.AutoTuneMemory(cr)) == 0 {
+		return
+	}
+	params, err := config.AutoTuneDynamicParams(cr)
+	if err != nil {
+		logger.Error(err, "get auto-tune params")
+		return
+	}
+
+	list := corev1.PodList{}
+	err = r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(statefulset.NewNode(cr).Labels()),
+		},
+	)
+	if err != nil {
+		logger.Error(err, "get pxc pods list")
+		return
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, pod := range list.Items {
+		if !isContainersReady(pod) {
+			continue
+		}
+
+		database, err := r.pxcDB(cr, pod.Name)
+		if err != nil {
+			logger.Error(err, "connect to pod", "pod", pod.Name)
+			continue
+		}
+
+		for _, name := range names {
+			value := params[name]
+			if name == "innodb_buffer_pool_size" {
+				current, err := database.IntVariable(name)
+				if err != nil {
+					logger.Error(err, "get variable", "pod", pod.Name, "variable", name)
+					continue
+				}
+				if value >= current {
+					continue
+				}
+			}
+
+			err = database.SetGlobal(name, value)
+			if err != nil {
+				logger.Error(err, "set variable", "pod", pod.Name, "variable", name)
+				continue
+			}
+			logger.Info("variable applied online", "pod", pod.Name, "variable", name, "value", value)
+		}
+		database.Close()
+	}
+}
+
+func resourcesChanged(old, new corev1.ResourceRequirements) bool {
+	return resourceListChanged(old.Limits, new.Limits) || resourceListChanged(old.Requests, new.Requests)
+}
+
+func resourceListChanged(old, new corev1.ResourceList) bool {
+	if len(old) != len(new) {
+		return true
+	}
+	for name, q := range new {
+		oq, ok := old[name]
+		if !ok || oq.Cmp(q) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package pxc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestReconcileResize(t *testing.T) {
+	container := func(mem string) corev1.Container {
+		return corev1.Container{
+			Name: "pxc",
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(mem)},
+			},
+		}
+	}
+	sfs := func(mem string, annotations map[string]string) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-pxc", Annotations: annotations, Generation: 2},
+			Spec: appsv1.StatefulSetSpec{
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{container(mem)}},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3},
+		}
+	}
+	inProgress := map[string]string{resizeAnnotation: "true"}
+
+	cases := []struct {
+		name       string
+		strategy   appsv1.StatefulSetUpdateStrategyType
+		current    *appsv1.StatefulSet
+		newMem     string
+		inProgress bool
+	}{
+		{"rolling update starts resize", appsv1.RollingUpdateStatefulSetStrategyType, sfs("1G", nil), "2G", true},
+		{"smart update starts resize", api.SmartUpdateStatefulSetStrategyType, sfs("1G", nil), "2G", true},
+		{"on delete is left to the user", appsv1.OnDeleteStatefulSetStrategyType, sfs("1G", nil), "2G", false},
+		{"resources aren't changed", appsv1.RollingUpdateStatefulSetStrategyType, sfs("1G", nil), "1G", false},
+		{"resize is continued", appsv1.RollingUpdateStatefulSetStrategyType, sfs("1G", inProgress), "2G", true},
+		{"resize is finished", appsv1.RollingUpdateStatefulSetStrategyType, sfs("2G", inProgress), "2G", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &ReconcilePerconaXtraDBCluster{recorder: record.NewFakeRecorder(10)}
+			cr := &api.PerconaXtraDBCluster{
+				Spec: api.PerconaXtraDBClusterSpec{
+					CRVersion:      "1.8.0",
+					UpdateStrategy: c.strategy,
+					PXC:            &api.PXCSpec{PodSpec: &api.PodSpec{}},
+				},
+			}
+
+			resize := r.reconcileResize(cr, c.current, container(c.newMem))
+
+			if resize != c.inProgress {
+				t.Errorf("expected resize %v, got %v", c.inProgress, resize)
+			}
+			if got := c.current.Annotations[resizeAnnotation] == "true"; got != c.inProgress {
+				t.Errorf("expected resize in progress %v, got %v", c.inProgress, got)
+			}
+			// the pods are restarted by SmartUpdate during the resize
+			strategy := appsv1.RollingUpdateStatefulSetStrategyType
+			if c.inProgress {
+				strategy = appsv1.OnDeleteStatefulSetStrategyType
+			}
+			if c.current.Spec.UpdateStrategy.Type != strategy {
+				t.Errorf("expected update strategy %s, got %s", strategy, c.current.Spec.UpdateStrategy.Type)
+			}
+		})
+	}
+}
+
+func TestPrimaryCandidates(t *testing.T) {
+	pod := func(name string, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.ContainersReady, Status: ready}},
+			},
+		}
+	}
+
+	pods := []corev1.Pod{
+		pod("cluster1-pxc-10", corev1.ConditionTrue),
+		pod("cluster1-pxc-0", corev1.ConditionFalse),
+		pod("cluster1-pxc-2", corev1.ConditionTrue),
+	}
+
+	got := primaryCandidates(pods)
+	if len(got) != 2 || got[0].Name != "cluster1-pxc-2" || got[1].Name != "cluster1-pxc-10" {
+		t.Errorf("unexpected candidates: %v", got)
+	}
+}
+
+func TestWaitPodReady(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1-pxc-1", Namespace: "ns"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.ContainersReady, Status: corev1.ConditionFalse}},
+		},
+	}
+	r := &ReconcilePerconaXtraDBCluster{client: fake.NewFakeClientWithScheme(scheme, pod)}
+	cr := &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns"},
+		Spec:       api.PerconaXtraDBClusterSpec{HAProxy: &api.PodSpec{Enabled: true}},
+	}
+
+	defer func(i time.Duration) { haproxyCheckInterval = i }(haproxyCheckInterval)
+	haproxyCheckInterval = 0
+
+	if err := r.waitPodReady(cr, pod, 1, logf.Log); err == nil {
+		t.Error("expected the wait of the unready pod to fail")
+	}
+
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	if err := r.client.Status().Update(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.waitPodReady(cr, pod, 1, logf.Log); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package pxc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
+)
+
+// reconcileVolumeExpansion expands the PVCs of PXC and ProxySQL if the
+// requested storage is bigger than the one of the StatefulSet. The claim
+// templates of the StatefulSet are immutable, so it's deleted with the pods
+// left running once all PVCs are resized, and deploy creates it again.
+func (r *ReconcilePerconaXtraDBCluster) reconcileVolumeExpansion(cr *api.PerconaXtraDBCluster) error {
+	if cr.CompareVersionWith("1.8.0") < 0 {
+		return nil
+	}
+
+	err := r.autoscaleStorage(cr)
+	if err != nil {
+		r.logger(cr.Name, cr.Namespace).Error(err, "storage autoscaling")
+	}
+	err = r.applyStorageAutoscaling(cr)
+	if err != nil {
+		return errors.Wrap(err, "apply storage autoscaling")
+	}
+
+	apps := []struct {
+		sfs  api.StatefulApp
+		spec *api.PodSpec
+	}{
+		{statefulset.NewNode(cr), cr.Spec.PXC.PodSpec},
+	}
+	if cr.Spec.ProxySQL != nil && cr.Spec.ProxySQL.Enabled {
+		apps = append(apps, struct {
+			sfs  api.StatefulApp
+			spec *api.PodSpec
+		}{statefulset.NewProxy(cr), cr.Spec.ProxySQL})
+	}
+
+	var statuses []api.VolumeExpansionStatus
+	for _, a := range apps {
+		component := a.sfs.Labels()["app.kubernetes.io/component"]
+		var prev *api.VolumeExpansionStatus
+		for i := range cr.Status.VolumeExpansion {
+			if cr.Status.VolumeExpansion[i].Component == component {
+				prev = &cr.Status.VolumeExpansion[i]
+			}
+		}
+
+		st, err := r.expandVolumes(cr, a.sfs, a.spec, prev)
+		if err != nil {
+			return errors.Wrapf(err, "expand %s volumes", component)
+		}
+		if st != nil {
+			statuses = append(statuses, *st)
+		}
+	}
+	cr.Status.VolumeExpansion = statuses
+
+	return nil
+}
+
+// expandVolumes returns the progress of the expansion or the previous one
+// if the StatefulSet already has the requested size
+func (r *ReconcilePerconaXtraDBCluster) expandVolumes(cr *api.PerconaXtraDBCluster, sfs api.StatefulApp, spec *api.PodSpec,
+	prev *api.VolumeExpansionStatus) (*api.VolumeExpansionStatus, error) {
+	if spec.VolumeSpec == nil || spec.VolumeSpec.PersistentVolumeClaim == nil {
+		return nil, nil
+	}
+	desired, ok := spec.VolumeSpec.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return prev, nil
+	}
+
+	currentSet := appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: sfs.StatefulSet().Name, Namespace: cr.Namespace}, &currentSet)
+	if k8serrors.IsNotFound(err) {
+		return prev, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get statefulset")
+	}
+	// the StatefulSet is being deleted after the expansion
+	if currentSet.DeletionTimestamp != nil || len(currentSet.Spec.VolumeClaimTemplates) == 0 {
+		return prev, nil
+	}
+	template := currentSet.Spec.VolumeClaimTemplates[0]
+	current := template.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	st := &api.VolumeExpansionStatus{
+		Component: sfs.Labels()["app.kubernetes.io/component"],
+		From:      current.String(),
+		To:        desired.String(),
+		State:     api.VolumeExpansionInProgress,
+	}
+
+	switch desired.Cmp(current) {
+	case 0:
+		return prev, nil
+	case -1:
+		st.State = api.VolumeExpansionFailed
+		st.Message = "volumes can't be shrunk"
+		return st, nil
+	}
+
+	if prev == nil || prev.To != st.To {
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVolumeExpansionStarted, "Expanding %s volumes from %s to %s", st.Component, st.From, st.To)
+	}
+
+	list := corev1.PersistentVolumeClaimList{}
+	err = r.client.List(context.TODO(),
+		&list,
+		&client.ListOptions{
+			Namespace:     cr.Namespace,
+			LabelSelector: labels.SelectorFromSet(sfs.Labels()),
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "get PVC list")
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	prefix := template.Name + "-" + currentSet.Name + "-"
+	resized := true
+	var pending []*corev1.PersistentVolumeClaim
+	for i := range list.Items {
+		pvc := &list.Items[i]
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		pvcStatus := r.expandPVC(pvc, desired)
+		switch pvcStatus.State {
+		case api.PVCResizeFailed:
+			st.State = api.VolumeExpansionFailed
+			st.Message = fmt.Sprintf("PVC %s: %s", pvc.Name, pvcStatus.Message)
+		case api.PVCFileSystemResizePending:
+			pending = append(pending, pvc)
+		}
+		resized = resized && pvcStatus.State == api.PVCResized
+		st.PVCs = append(st.PVCs, pvcStatus)
+	}
+
+	if len(pending) > 0 && st.State != api.VolumeExpansionFailed {
+		msg, err := r.restartResizePending(cr, &currentSet, template.Name, pending)
+		if err != nil {
+			return nil, errors.Wrap(err, "restart pod for file system resize")
+		}
+		if len(msg) > 0 {
+			st.State = api.VolumeExpansionFailed
+			st.Message = msg
+			if prev == nil || prev.State != api.VolumeExpansionFailed || prev.Message != msg {
+				r.recorder.Eventf(cr, corev1.EventTypeWarning, EventVolumeExpansionFailed, "Volumes of %s aren't expanded: %s", st.Component, msg)
+			}
+		}
+	}
+
+	if !resized || st.State == api.VolumeExpansionFailed {
+		return st, nil
+	}
+
+	err = r.client.Delete(context.TODO(), &currentSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "delete statefulset with orphaned pods")
+	}
+	st.State = api.VolumeExpansionCompleted
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVolumeExpansionCompleted, "Volumes of %s expanded to %s", st.Component, st.To)
+
+	return st, nil
+}
+
+// restartResizePending restarts the pod of the PVC waiting for the file
+// system resize. Some storage drivers resize the file system only when the
+// volume is mounted, so the pods are restarted one by one while the others
+// are ready. It returns the reason if the file system isn't resized even
+// after the restart.
+func (r *ReconcilePerconaXtraDBCluster) restartResizePending(cr *api.PerconaXtraDBCluster, currentSet *appsv1.StatefulSet,
+	claimName string, pending []*corev1.PersistentVolumeClaim) (string, error) {
+	// the pod restarted before isn't ready yet
+	if currentSet.Status.ReadyReplicas < currentSet.Status.Replicas {
+		return "", nil
+	}
+
+	for _, pvc := range pending {
+		podName := strings.TrimPrefix(pvc.Name, claimName+"-")
+		pod := corev1.Pod{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: podName, Namespace: pvc.Namespace}, &pod)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "get pod %s", podName)
+		}
+
+		if pod.Status.StartTime != nil && pod.Status.StartTime.After(resizePendingSince(pvc).Time) {
+			return fmt.Sprintf("file system of PVC %s isn't resized after the restart of pod %s", pvc.Name, podName), nil
+		}
+
+		r.logger(cr.Name, cr.Namespace).Info("restart pod to resize the file system", "pod name", podName, "pvc", pvc.Name)
+		r.recorder.Eventf(cr, corev1.EventTypeNormal, EventVolumeExpansionPodRestart,
+			"Restarting pod %s to resize the file system of PVC %s", podName, pvc.Name)
+		err = r.client.Delete(context.TODO(), &pod)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "delete pod %s", podName)
+		}
+		return "", nil
+	}
+
+	return "", nil
+}
+
+// resizePendingSince returns the time the PVC started to wait for the file system resize
+func resizePendingSince(pvc *corev1.PersistentVolumeClaim) metav1.Time {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
+			return c.LastTransitionTime
+		}
+	}
+	return metav1.Time{}
+}
+
+// expandPVC requests the size for the PVC and returns the progress of its resize
+func (r *ReconcilePerconaXtraDBCluster) expandPVC(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) api.PVCExpansionStatus {
+	st := api.PVCExpansionStatus{
+		Name:  pvc.Name,
+		State: api.PVCResizing,
+	}
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		st.Capacity = capacity.String()
+	}
+
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if requested.Cmp(size) < 0 {
+		expandable, err := r.storageClassExpandable(pvc)
+		if err != nil {
+			st.State = api.PVCResizeFailed
+			st.Message = err.Error()
+			return st
+		}
+		if !expandable {
+			st.State = api.PVCResizeFailed
+			st.Message = "storage class doesn't allow volume expansion"
+			return st
+		}
+
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		err = r.client.Update(context.TODO(), pvc)
+		if err != nil {
+			st.State = api.PVCResizeFailed
+			st.Message = errors.Wrap(err, "update PVC").Error()
+		}
+		return st
+	}
+
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && c.Status == corev1.ConditionTrue {
+			st.State = api.PVCFileSystemResizePending
+			return st
+		}
+	}
+
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	if capacity.Cmp(size) >= 0 {
+		st.State = api.PVCResized
+	}
+
+	return st
+}
+
+// storageClassExpandable checks the storage class of the PVC allows the expansion.
+// The storage classes are read bypassing the cache. Without access to them
+// the PVC is expanded anyway and the API server rejects it if it can't be.
+func (r *ReconcilePerconaXtraDBCluster) storageClassExpandable(pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return true, nil
+	}
+
+	sc := storagev1.StorageClass{}
+	err := r.apiReader.Get(context.TODO(), types.NamespacedName{Name: *pvc.Spec.StorageClassName}, &sc)
+	if k8serrors.IsForbidden(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "get storage class %s", *pvc.Spec.StorageClassName)
+	}
+
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
@@ -0,0 +1,123 @@
+package pxc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestExpandPVCState(t *testing.T) {
+	pvc := func(capacity string, conditions ...corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaim {
+		p := &corev1.PersistentVolumeClaim{
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+			},
+		}
+		for _, c := range conditions {
+			p.Status.Conditions = append(p.Status.Conditions, corev1.PersistentVolumeClaimCondition{Type: c, Status: corev1.ConditionTrue})
+		}
+		return p
+	}
+
+	cases := []struct {
+		name  string
+		pvc   *corev1.PersistentVolumeClaim
+		state api.PVCExpansionState
+	}{
+		{"volume resizing", pvc("10Gi", corev1.PersistentVolumeClaimResizing), api.PVCResizing},
+		{"filesystem resize pending", pvc("10Gi", corev1.PersistentVolumeClaimFileSystemResizePending), api.PVCFileSystemResizePending},
+		{"resized", pvc("20Gi"), api.PVCResized},
+	}
+
+	r := &ReconcilePerconaXtraDBCluster{}
+	for _, c := range cases {
+		st := r.expandPVC(c.pvc, resource.MustParse("20Gi"))
+		if st.State != c.state {
+			t.Errorf("%s: got state %s, expected %s", c.name, st.State, c.state)
+		}
+	}
+}
+
+func TestRestartResizePending(t *testing.T) {
+	pendingSince := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "datadir-cluster1-pxc-1", Namespace: "ns"},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Conditions: []corev1.PersistentVolumeClaimCondition{{
+				Type:               corev1.PersistentVolumeClaimFileSystemResizePending,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: pendingSince,
+			}},
+		},
+	}
+	pod := func(started time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-pxc-1", Namespace: "ns"},
+			Status:     corev1.PodStatus{StartTime: &metav1.Time{Time: started}},
+		}
+	}
+	sfs := func(ready int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: ready}}
+	}
+	cr := &api.PerconaXtraDBCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns"}}
+
+	tests := map[string]struct {
+		pod     *corev1.Pod
+		sfs     *appsv1.StatefulSet
+		deleted bool
+		failed  bool
+	}{
+		"pod started before the resize": {
+			pod:     pod(pendingSince.Add(-time.Hour)),
+			sfs:     sfs(3),
+			deleted: true,
+		},
+		"another pod isn't ready": {
+			pod: pod(pendingSince.Add(-time.Hour)),
+			sfs: sfs(2),
+		},
+		"pod restarted after the resize": {
+			pod:    pod(pendingSince.Add(time.Second)),
+			sfs:    sfs(3),
+			failed: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &ReconcilePerconaXtraDBCluster{
+				client:   fake.NewFakeClient(tt.pod),
+				recorder: record.NewFakeRecorder(10),
+			}
+
+			msg, err := r.restartResizePending(cr, tt.sfs, "datadir", []*corev1.PersistentVolumeClaim{pvc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if failed := len(msg) > 0; failed != tt.failed {
+				t.Errorf("expected failed=%v, got %q", tt.failed, msg)
+			}
+
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: tt.pod.Name, Namespace: "ns"}, &corev1.Pod{})
+			if deleted := k8serrors.IsNotFound(err); deleted != tt.deleted {
+				t.Errorf("expected deleted=%v, got %v", tt.deleted, deleted)
+			}
+		})
+	}
+}
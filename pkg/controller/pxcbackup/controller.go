@@ -8,13 +8,16 @@ import (
 	"time"
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/backup"
 	"github.com/percona/percona-xtradb-cluster-operator/version"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -48,6 +51,7 @@ func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
 		client:        mgr.GetClient(),
 		scheme:        mgr.GetScheme(),
 		serverVersion: sv,
+		recorder:      mgr.GetEventRecorderFor("perconaxtradbclusterbackup-controller"),
 	}, nil
 }
 
@@ -74,8 +78,9 @@ var _ reconcile.Reconciler = &ReconcilePerconaXtraDBClusterBackup{}
 type ReconcilePerconaXtraDBClusterBackup struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
 
 	serverVersion *version.ServerVersion
 }
@@ -85,7 +90,7 @@ type ReconcilePerconaXtraDBClusterBackup struct {
 // Note:
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
-func (r *ReconcilePerconaXtraDBClusterBackup) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+func (r *ReconcilePerconaXtraDBClusterBackup) Reconcile(request reconcile.Request) (_ reconcile.Result, rerr error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	// reqLogger.Info("Reconciling PerconaXtraDBClusterBackup")
 
@@ -112,6 +117,10 @@ func (r *ReconcilePerconaXtraDBClusterBackup) Reconcile(request reconcile.Reques
 		return reconcile.Result{}, nil
 	}
 
+	defer func(start time.Time) {
+		metrics.ObserveReconcile("pxc-backup", instance.Spec.PXCCluster, instance.Namespace, start, rerr)
+	}(time.Now())
+
 	cluster, err := r.getClusterConfig(instance)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("invalid backup cluster: %v", err)
@@ -260,6 +269,7 @@ func (r *ReconcilePerconaXtraDBClusterBackup) updateJobStatus(bcp *api.PerconaXt
 		return nil
 	}
 
+	prevState := bcp.Status.State
 	bcp.Status = status
 
 	err = r.client.Status().Update(context.TODO(), bcp)
@@ -272,6 +282,25 @@ func (r *ReconcilePerconaXtraDBClusterBackup) updateJobStatus(bcp *api.PerconaXt
 		}
 	}
 
+	// the events are emitted only once the state is changed
+	if status.State == prevState {
+		return nil
+	}
+	if prevState == api.BackupNew {
+		r.recorder.Eventf(bcp, corev1.EventTypeNormal, api.EventBackupStarted, "Backup job %s is created for cluster %s", job.Name, bcp.Spec.PXCCluster)
+	}
+
+	switch status.State {
+	case api.BackupSucceeded:
+		r.recorder.Eventf(bcp, corev1.EventTypeNormal, api.EventBackupSucceeded, "Backup is uploaded to %s", destination)
+		metrics.BackupDuration.WithLabelValues(bcp.Spec.PXCCluster, bcp.Namespace, storageName, string(status.State)).
+			Observe(status.CompletedAt.Sub(bcp.CreationTimestamp.Time).Seconds())
+	case api.BackupFailed:
+		r.recorder.Eventf(bcp, corev1.EventTypeWarning, api.EventBackupFailed, "Backup job %s failed", job.Name)
+		metrics.BackupDuration.WithLabelValues(bcp.Spec.PXCCluster, bcp.Namespace, storageName, string(status.State)).
+			Observe(time.Since(bcp.CreationTimestamp.Time).Seconds())
+	}
+
 	return nil
 }
 
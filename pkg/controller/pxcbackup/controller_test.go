@@ -0,0 +1,82 @@
+package pxcbackup
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/apis"
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestUpdateJobStatusEvents(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	now := metav1.Now()
+	bcp := &api.PerconaXtraDBClusterBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup1", Namespace: "ns", CreationTimestamp: now},
+		Spec:       api.PXCBackupSpec{PXCCluster: "cluster1", StorageName: "fs-pvc"},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "xb-backup1", Namespace: "ns"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcilePerconaXtraDBClusterBackup{
+		client:   fake.NewFakeClientWithScheme(scheme, bcp, job),
+		scheme:   scheme,
+		recorder: recorder,
+	}
+
+	steps := []struct {
+		job    batchv1.JobStatus
+		events []string
+	}{
+		{batchv1.JobStatus{Active: 1}, []string{"Normal BackupStarted"}},
+		{batchv1.JobStatus{Active: 1}, nil},
+		{batchv1.JobStatus{Succeeded: 1, CompletionTime: &now}, []string{"Normal BackupSucceeded"}},
+		{batchv1.JobStatus{Succeeded: 1, CompletionTime: &now}, nil},
+	}
+
+	for i, s := range steps {
+		j := &batchv1.Job{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, j); err != nil {
+			t.Fatal(err)
+		}
+		j.Status = s.job
+		if err := r.client.Status().Update(context.TODO(), j); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.updateJobStatus(bcp, &batchv1.Job{ObjectMeta: job.ObjectMeta}, "pvc/xb-backup1", "fs-pvc", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		if len(events) != len(s.events) {
+			t.Fatalf("step %d: expected events %v, got %v", i, s.events, events)
+		}
+		for k := range events {
+			if !strings.HasPrefix(events[k], s.events[k]+" ") {
+				t.Errorf("step %d: expected event %q, got %q", i, s.events[k], events[k])
+			}
+		}
+	}
+}
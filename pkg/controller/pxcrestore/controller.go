@@ -13,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -22,6 +23,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/metrics"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/statefulset"
 	"github.com/percona/percona-xtradb-cluster-operator/version"
 )
@@ -49,6 +51,7 @@ func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
 		client:        mgr.GetClient(),
 		scheme:        mgr.GetScheme(),
 		serverVersion: sv,
+		recorder:      mgr.GetEventRecorderFor("perconaxtradbclusterrestore-controller"),
 	}, nil
 }
 
@@ -75,8 +78,9 @@ var _ reconcile.Reconciler = &ReconcilePerconaXtraDBClusterRestore{}
 type ReconcilePerconaXtraDBClusterRestore struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
 
 	serverVersion *version.ServerVersion
 }
@@ -123,14 +127,21 @@ func (r *ReconcilePerconaXtraDBClusterRestore) Reconcile(request reconcile.Reque
 
 	returnMsg := fmt.Sprintf(backupRestoredMsg, cr.Name, cr.Spec.PXCCluster, cr.Name)
 
-	defer func() {
+	defer func(start time.Time) {
 		status := api.BcpRestoreStates(api.RestoreSucceeded)
 		if err != nil {
 			status = api.RestoreFailed
 			returnMsg = err.Error()
 		}
 		r.setStatus(cr, status, returnMsg)
-	}()
+		if status == api.RestoreFailed {
+			r.recorder.Event(cr, corev1.EventTypeWarning, api.EventRestoreFailed, returnMsg)
+		} else {
+			r.recorder.Event(cr, corev1.EventTypeNormal, api.EventRestoreSucceeded, returnMsg)
+		}
+		metrics.RestoreDuration.WithLabelValues(cr.Spec.PXCCluster, cr.Namespace, string(status)).Observe(time.Since(start).Seconds())
+		metrics.ObserveReconcile("pxc-restore", cr.Spec.PXCCluster, cr.Namespace, start, err)
+	}(time.Now())
 
 	for _, j := range rJobsList.Items {
 		if j.Spec.PXCCluster == cr.Spec.PXCCluster &&
@@ -162,6 +173,7 @@ func (r *ReconcilePerconaXtraDBClusterRestore) Reconcile(request reconcile.Reque
 		return reconcile.Result{}, fmt.Errorf("wrong PXC options: %v", err)
 	}
 
+	r.recorder.Eventf(cr, corev1.EventTypeNormal, api.EventRestoreStarted, "Restoring backup %s to cluster %s", cr.Spec.BackupName, cr.Spec.PXCCluster)
 	lgr.Info("stopping cluster", "cluster", cr.Spec.PXCCluster)
 	err = r.setStatus(cr, api.RestoreStopCluster, "")
 	if err != nil {
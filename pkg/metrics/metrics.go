@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// metrics are served by the manager on its metrics endpoint (:8080/metrics)
+// together with the controller-runtime ones
+const namespace = "pxc_operator"
+
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+var (
+	ReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the reconcile loop per object",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+		},
+		[]string{"controller", "cluster", "namespace"},
+	)
+	ReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of the reconcile loops finished with an error",
+		},
+		[]string{"controller", "cluster", "namespace"},
+	)
+
+	SmartUpdateInProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "smart_update_in_progress",
+			Help:      "Whether the SmartUpdate is being applied to the cluster",
+		},
+		[]string{"cluster", "namespace"},
+	)
+	SmartUpdatePodsRestarted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "smart_update_pods_restarted_total",
+			Help:      "Number of the PXC pods restarted by the SmartUpdate",
+		},
+		[]string{"cluster", "namespace"},
+	)
+	SmartUpdateDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "smart_update_duration_seconds",
+			Help:      "Duration of the SmartUpdate runs",
+			Buckets:   []float64{30, 60, 300, 600, 1800, 3600, 7200, 14400},
+		},
+		[]string{"cluster", "namespace", "result"},
+	)
+
+	FullCrashRecoveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "full_crash_recoveries_total",
+			Help:      "Number of the full cluster crash recovery attempts",
+		},
+		[]string{"cluster", "namespace", "result"},
+	)
+
+	BackupDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "backup_duration_seconds",
+			Help:      "Duration of the finished backups",
+			Buckets:   []float64{60, 300, 600, 1800, 3600, 7200, 14400, 28800, 86400},
+		},
+		[]string{"cluster", "namespace", "storage", "state"},
+	)
+
+	RestoreDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "restore_duration_seconds",
+			Help:      "Duration of the finished restores",
+			Buckets:   []float64{60, 300, 600, 1800, 3600, 7200, 14400, 28800, 86400},
+		},
+		[]string{"cluster", "namespace", "state"},
+	)
+
+	VersionServiceChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "version_service_checks_total",
+			Help:      "Number of the version service requests",
+		},
+		[]string{"cluster", "namespace", "result"},
+	)
+	VersionServiceLastCheck = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "version_service_last_success_timestamp_seconds",
+			Help:      "Time of the last successful version service request",
+		},
+		[]string{"cluster", "namespace"},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ReconcileDuration,
+		ReconcileErrors,
+		SmartUpdateInProgress,
+		SmartUpdatePodsRestarted,
+		SmartUpdateDuration,
+		FullCrashRecoveries,
+		BackupDuration,
+		RestoreDuration,
+		VersionServiceChecks,
+		VersionServiceLastCheck,
+	)
+}
+
+// ObserveReconcile records the duration and the result of the reconcile loop started at start
+func ObserveReconcile(controller, cluster, ns string, start time.Time, err error) {
+	ReconcileDuration.WithLabelValues(controller, cluster, ns).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ReconcileErrors.WithLabelValues(controller, cluster, ns).Inc()
+	}
+}
+
+// Result returns the result label value for err
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// clusterVec is a metric vector with the cluster and namespace labels
+type clusterVec interface {
+	prometheus.Collector
+	Delete(prometheus.Labels) bool
+}
+
+// DeleteCluster removes the series of the deleted cluster from every vector.
+// The values of the other labels (e.g. result or storage) aren't known
+// in advance, so they are taken from the collected series.
+func DeleteCluster(cluster, ns string) {
+	vecs := []clusterVec{
+		ReconcileDuration,
+		ReconcileErrors,
+		SmartUpdateInProgress,
+		SmartUpdatePodsRestarted,
+		SmartUpdateDuration,
+		FullCrashRecoveries,
+		BackupDuration,
+		RestoreDuration,
+		VersionServiceChecks,
+		VersionServiceLastCheck,
+	}
+	for _, vec := range vecs {
+		for _, labels := range clusterSeries(vec, cluster, ns) {
+			vec.Delete(labels)
+		}
+	}
+}
+
+func clusterSeries(vec clusterVec, cluster, ns string) []prometheus.Labels {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		vec.Collect(ch)
+		close(ch)
+	}()
+
+	var series []prometheus.Labels
+	for m := range ch {
+		pb := dto.Metric{}
+		if err := m.Write(&pb); err != nil {
+			continue
+		}
+		labels := make(prometheus.Labels, len(pb.Label))
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["cluster"] == cluster && labels["namespace"] == ns {
+			series = append(series, labels)
+		}
+	}
+
+	return series
+}
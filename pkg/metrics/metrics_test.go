@@ -0,0 +1,28 @@
+package metrics
+
+import "testing"
+
+func TestDeleteCluster(t *testing.T) {
+	ReconcileErrors.WithLabelValues("pxc", "c1", "ns").Inc()
+	ReconcileErrors.WithLabelValues("pxc-backup", "c1", "ns").Inc()
+	ReconcileErrors.WithLabelValues("pxc", "c2", "ns").Inc()
+	BackupDuration.WithLabelValues("c1", "ns", "s3-us-west", "Succeeded").Observe(1)
+	VersionServiceChecks.WithLabelValues("c1", "ns", ResultError).Inc()
+	SmartUpdateInProgress.WithLabelValues("c1", "ns").Set(1)
+
+	DeleteCluster("c1", "ns")
+
+	for name, c := range map[string]clusterVec{
+		"reconcile errors": ReconcileErrors,
+		"backup duration":  BackupDuration,
+		"vs checks":        VersionServiceChecks,
+		"smart update":     SmartUpdateInProgress,
+	} {
+		if n := len(clusterSeries(c, "c1", "ns")); n != 0 {
+			t.Errorf("%s: %d series of the deleted cluster left", name, n)
+		}
+	}
+	if n := len(clusterSeries(ReconcileErrors, "c2", "ns")); n != 1 {
+		t.Errorf("expected the other cluster's series to be kept, got %d series", n)
+	}
+}
@@ -2,31 +2,365 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	res "k8s.io/apimachinery/pkg/api/resource"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+const (
+	mib = int64(1) << 20
+	gib = int64(1) << 30
 )
 
+// gcacheParam is the wsrep provider option computed by the auto-tuning.
+// It's written into AutoTuneProviderOptionsFile.
+const gcacheParam = "gcache.size"
+
+// AutoTuneProviderOptionsFile is the auto-config file with the auto-tuned
+// wsrep provider options. mysqld doesn't read it, pxc-configure-pxc.sh merges
+// the options into wsrep_provider_options of the node config, so the options
+// of the image aren't overridden.
+const AutoTuneProviderOptionsFile = "wsrep-provider-options"
+
+var errNotEnoughMemory = errors.New("Not enough memory set in requests. Must be >= 12Mi.")
+
+// AutoTuneInput is what the auto-tuning is based on
+type AutoTuneInput struct {
+	// Memory in bytes, 0 if it isn't set
+	Memory int64
+	// CPU in millicores, 0 if it isn't set
+	CPU int64
+	// Storage is the data volume size in bytes, 0 if it isn't known
+	Storage int64
+	IOPS    int64
+	SSD     bool
+	Profile api.AutoTuneProfile
+}
+
+// Tuner computes the values of some mysqld variables and adds them to params.
+// Tuners are applied in order, so a tuner can use the values computed before it.
+type Tuner func(in AutoTuneInput, params map[string]int64) error
+
+// Tuners is the list of the tuners applied by AutoTune
+var Tuners = []Tuner{
+	tuneMemory,
+	tuneTableCache,
+	tuneRedoLog,
+	tuneThreads,
+	tuneIO,
+	tuneGcache,
+}
+
+// dynamicParams are the auto-tuned variables that can be changed with SET GLOBAL
+var dynamicParams = map[string]struct{}{
+	"innodb_buffer_pool_size": {},
+	"max_connections":         {},
+	"table_open_cache":        {},
+	"wsrep_slave_threads":     {},
+	"thread_pool_size":        {},
+}
+
+// getAutoTuneParams returns the memory params of the crVersion < 1.8.0 clusters.
+// They are computed by tuneMemory with the default profile.
 func getAutoTuneParams(memory string) (string, error) {
-	autotuneParams := ""
 	q, err := res.ParseQuantity(memory)
 	if err != nil {
 		return "", err
 	}
 
-	poolSize := q.Value() / int64(100) * int64(75)
-	poolSizeVal := strconv.FormatInt(poolSize, 10)
-	paramValue := "\n" + "innodb_buffer_pool_size" + " = " + poolSizeVal
-	autotuneParams += paramValue
+	if q.Value() == 0 {
+		return "", errNotEnoughMemory
+	}
+	params := make(map[string]int64)
+	err = tuneMemory(AutoTuneInput{Memory: q.Value(), Profile: api.AutoTuneProfileOLTP}, params)
+	if err != nil {
+		return "", err
+	}
+
+	autotuneParams := "\n" + "innodb_buffer_pool_size" + " = " + strconv.FormatInt(params["innodb_buffer_pool_size"], 10)
+	autotuneParams += "\n" + "max_connections" + " = " + strconv.FormatInt(params["max_connections"], 10)
+
+	return autotuneParams, nil
+}
+
+// NewAutoTuneInput collects the resources and the hints of the PXC pods
+func NewAutoTuneInput(cr *api.PerconaXtraDBCluster) (AutoTuneInput, error) {
+	in := AutoTuneInput{
+		Profile: api.AutoTuneProfileOLTP,
+	}
+
+	if memory := AutoTuneMemory(cr); len(memory) > 0 {
+		q, err := res.ParseQuantity(memory)
+		if err != nil {
+			return in, fmt.Errorf("parse memory: %v", err)
+		}
+		in.Memory = q.Value()
+	}
+
+	if cpu := autoTuneCPU(cr); len(cpu) > 0 {
+		q, err := res.ParseQuantity(cpu)
+		if err != nil {
+			return in, fmt.Errorf("parse cpu: %v", err)
+		}
+		in.CPU = q.MilliValue()
+	}
+
+	storageClass := ""
+	if v := cr.Spec.PXC.VolumeSpec; v != nil && v.PersistentVolumeClaim != nil {
+		if q, ok := v.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]; ok {
+			in.Storage = q.Value()
+		}
+		if v.PersistentVolumeClaim.StorageClassName != nil {
+			storageClass = *v.PersistentVolumeClaim.StorageClassName
+		}
+	}
+	in.SSD = isSSDStorageClass(storageClass)
+
+	if at := cr.Spec.PXC.AutoTune; at != nil {
+		if len(at.Profile) > 0 {
+			in.Profile = at.Profile
+		}
+		in.IOPS = at.IOPS
+		switch strings.ToLower(at.StorageType) {
+		case "ssd":
+			in.SSD = true
+		case "hdd":
+			in.SSD = false
+		}
+	}
+
+	return in, nil
+}
+
+// AutoTune computes the mysqld variables for the PXC pods.
+// The variables set in PXC.Configuration take precedence and are skipped.
+func AutoTune(cr *api.PerconaXtraDBCluster) (map[string]string, error) {
+	in, err := NewAutoTuneInput(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]int64)
+	for _, tune := range Tuners {
+		err := tune(in, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	userKeys, err := configurationKeys(cr.Spec.PXC.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(params))
+	for name, value := range params {
+		key := name
+		if name == gcacheParam {
+			key = "wsrep_provider_options"
+		}
+		if _, ok := userKeys[key]; ok {
+			continue
+		}
+		result[name] = strconv.FormatInt(value, 10)
+	}
+
+	return result, nil
+}
+
+// AutoTuneDynamicParams returns the auto-tuned variables
+// that can be changed at runtime with SET GLOBAL
+func AutoTuneDynamicParams(cr *api.PerconaXtraDBCluster) (map[string]int64, error) {
+	params, err := AutoTune(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamic := make(map[string]int64)
+	for name, value := range params {
+		if _, ok := dynamicParams[name]; !ok {
+			continue
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		dynamic[name] = v
+	}
+
+	return dynamic, nil
+}
+
+// autoTuneConfig renders the auto-tuned params as the [mysqld] section.
+// The provider options are rendered by autoTuneProviderOptions.
+func autoTuneConfig(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conf := ""
+	for _, name := range names {
+		if name == gcacheParam {
+			continue
+		}
+		conf += "\n" + name + " = " + params[name]
+	}
+
+	return conf
+}
+
+// autoTuneProviderOptions renders the auto-tuned wsrep provider options
+// in the wsrep_provider_options format, e.g. "gcache.size=134217728"
+func autoTuneProviderOptions(params map[string]string) string {
+	value, ok := params[gcacheParam]
+	if !ok {
+		return ""
+	}
+	return gcacheParam + "=" + value
+}
+
+func tuneMemory(in AutoTuneInput, params map[string]int64) error {
+	if in.Memory == 0 {
+		return nil
+	}
 
 	divider := int64(12582880)
-	if q.Value() < divider {
-		return "", errors.New("Not enough memory set in requests. Must be >= 12Mi.")
+	if in.Memory < divider {
+		return errNotEnoughMemory
 	}
-	maxConnSize := q.Value() / divider
-	maxConnSizeVal := strconv.FormatInt(maxConnSize, 10)
-	paramValue = "\n" + "max_connections" + " = " + maxConnSizeVal
-	autotuneParams += paramValue
 
-	return autotuneParams, nil
+	poolSize := in.Memory / int64(100) * int64(75)
+	maxConnSize := in.Memory / divider
+	if in.Profile == api.AutoTuneProfileDev {
+		poolSize = in.Memory / int64(100) * int64(50)
+		maxConnSize = min64(maxConnSize, 100)
+	}
+	params["innodb_buffer_pool_size"] = poolSize
+	params["max_connections"] = maxConnSize
+
+	return nil
+}
+
+func tuneTableCache(in AutoTuneInput, params map[string]int64) error {
+	maxConn, ok := params["max_connections"]
+	if !ok {
+		return nil
+	}
+
+	params["table_open_cache"] = clamp(maxConn*4, 2000, 20000)
+	return nil
+}
+
+func tuneRedoLog(in AutoTuneInput, params map[string]int64) error {
+	poolSize, ok := params["innodb_buffer_pool_size"]
+	if !ok {
+		return nil
+	}
+
+	// two log files hold 1/4 of the buffer pool, analytics writes in bigger batches
+	logSize := poolSize / 8
+	if in.Profile == api.AutoTuneProfileAnalytics {
+		logSize = poolSize / 4
+	}
+	params["innodb_log_file_size"] = clamp(logSize, 48*mib, 2*gib) / mib * mib
+
+	return nil
+}
+
+func tuneThreads(in AutoTuneInput, params map[string]int64) error {
+	if in.CPU == 0 {
+		return nil
+	}
+
+	cores := (in.CPU + 999) / 1000
+	switch in.Profile {
+	case api.AutoTuneProfileDev:
+		params["wsrep_slave_threads"] = 1
+	case api.AutoTuneProfileAnalytics:
+		params["wsrep_slave_threads"] = cores
+	default:
+		params["wsrep_slave_threads"] = cores * 2
+	}
+	params["thread_pool_size"] = cores
+
+	return nil
+}
+
+func tuneIO(in AutoTuneInput, params map[string]int64) error {
+	capacity, capacityMax := int64(200), int64(400)
+	switch {
+	case in.IOPS > 0:
+		capacity, capacityMax = max64(in.IOPS/2, 100), max64(in.IOPS, 200)
+	case in.Profile == api.AutoTuneProfileDev:
+		capacity, capacityMax = 100, 200
+	case in.SSD:
+		capacity, capacityMax = 1000, 2000
+	}
+	params["innodb_io_capacity"] = capacity
+	params["innodb_io_capacity_max"] = capacityMax
+
+	return nil
+}
+
+func tuneGcache(in AutoTuneInput, params map[string]int64) error {
+	if in.Storage == 0 {
+		return nil
+	}
+
+	params[gcacheParam] = clamp(in.Storage/50, 128*mib, 8*gib) / mib * mib
+	return nil
+}
+
+func autoTuneCPU(cr *api.PerconaXtraDBCluster) string {
+	var cpu string
+
+	if cr.Spec.PXC.Resources != nil {
+		if cr.Spec.PXC.Resources.Requests != nil && len(cr.Spec.PXC.Resources.Requests.CPU) > 0 {
+			cpu = cr.Spec.PXC.Resources.Requests.CPU
+		}
+		// Use limits cpu in priority if it set
+		if cr.Spec.PXC.Resources.Limits != nil && len(cr.Spec.PXC.Resources.Limits.CPU) > 0 {
+			cpu = cr.Spec.PXC.Resources.Limits.CPU
+		}
+	}
+
+	return cpu
+}
+
+var ssdStorageClassHints = []string{"ssd", "premium", "gp2", "gp3", "io1", "io2", "nvme"}
+
+func isSSDStorageClass(name string) bool {
+	name = strings.ToLower(name)
+	for _, hint := range ssdStorageClassHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+func clamp(v, lo, hi int64) int64 {
+	return min64(max64(v, lo), hi)
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
 }
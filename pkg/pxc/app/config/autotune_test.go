@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func newAutoTuneCR(memory, cpu string, autoTune *api.AutoTuneSpec, conf string) *api.PerconaXtraDBCluster {
+	return &api.PerconaXtraDBCluster{
+		Spec: api.PerconaXtraDBClusterSpec{
+			CRVersion: "1.8.0",
+			PXC: &api.PXCSpec{
+				PodSpec: &api.PodSpec{
+					Configuration: conf,
+					Resources: &api.PodResources{
+						Limits: &api.ResourcesList{
+							Memory: memory,
+							CPU:    cpu,
+						},
+					},
+				},
+				AutoTune: autoTune,
+			},
+		},
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	tests := map[string]struct {
+		cr       *api.PerconaXtraDBCluster
+		expected map[string]string
+		skipped  []string
+	}{
+		"oltp": {
+			cr: newAutoTuneCR("4Gi", "2", nil, ""),
+			expected: map[string]string{
+				"innodb_buffer_pool_size": "3221225400",
+				"max_connections":         "341",
+				"table_open_cache":        "2000",
+				"innodb_log_file_size":    "401604608",
+				"wsrep_slave_threads":     "4",
+				"thread_pool_size":        "2",
+				"innodb_io_capacity":      "200",
+				"innodb_io_capacity_max":  "400",
+			},
+		},
+		"dev profile": {
+			cr: newAutoTuneCR("4Gi", "2", &api.AutoTuneSpec{Profile: api.AutoTuneProfileDev}, ""),
+			expected: map[string]string{
+				"innodb_buffer_pool_size": "2147483600",
+				"max_connections":         "100",
+				"wsrep_slave_threads":     "1",
+				"innodb_io_capacity":      "100",
+			},
+		},
+		"iops hint": {
+			cr: newAutoTuneCR("4Gi", "", &api.AutoTuneSpec{IOPS: 3000, StorageType: "ssd"}, ""),
+			expected: map[string]string{
+				"innodb_io_capacity":     "1500",
+				"innodb_io_capacity_max": "3000",
+			},
+			skipped: []string{"wsrep_slave_threads", "thread_pool_size"},
+		},
+		"user configuration has precedence": {
+			cr: newAutoTuneCR("4Gi", "", nil, "[mysqld]\nmax-connections=1000\nwsrep_provider_options=\"gcache.size=1G\""),
+			expected: map[string]string{
+				"innodb_buffer_pool_size": "3221225400",
+			},
+			skipped: []string{"max_connections", gcacheParam},
+		},
+	}
+
+	for name, test := range tests {
+		params, err := AutoTune(test.cr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for k, v := range test.expected {
+			if params[k] != v {
+				t.Errorf("%s: %s = %q, expected %q", name, k, params[k], v)
+			}
+		}
+		for _, k := range test.skipped {
+			if v, ok := params[k]; ok {
+				t.Errorf("%s: %s = %q, expected to be skipped", name, k, v)
+			}
+		}
+	}
+}
+
+func TestAutoTuneConfigGcache(t *testing.T) {
+	params := map[string]string{
+		"max_connections": "100",
+		gcacheParam:       "134217728",
+	}
+
+	// wsrep_provider_options of the image are kept, gcache.size is merged into them
+	expected := "\nmax_connections = 100"
+	if conf := autoTuneConfig(params); conf != expected {
+		t.Errorf("got %q, expected %q", conf, expected)
+	}
+	if opts := autoTuneProviderOptions(params); opts != "gcache.size=134217728" {
+		t.Errorf("got provider options %q", opts)
+	}
+	if opts := autoTuneProviderOptions(map[string]string{"max_connections": "100"}); opts != "" {
+		t.Errorf("got provider options %q without gcache.size", opts)
+	}
+}
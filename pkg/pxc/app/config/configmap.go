@@ -22,7 +22,9 @@ func NewConfigMap(cr *api.PerconaXtraDBCluster, cmName, filename, content string
 	}
 }
 
-func NewAutoTuneConfigMap(cr *api.PerconaXtraDBCluster, cmName string) (*corev1.ConfigMap, error) {
+// AutoTuneMemory returns the memory the auto-tuning is based on.
+// Limits are used in priority over requests.
+func AutoTuneMemory(cr *api.PerconaXtraDBCluster) string {
 	var memory string
 
 	if cr.Spec.PXC.Resources != nil {
@@ -38,11 +40,27 @@ func NewAutoTuneConfigMap(cr *api.PerconaXtraDBCluster, cmName string) (*corev1.
 			}
 		}
 	}
-	autotuneParams, err := getAutoTuneParams(memory)
-	if err != nil {
-		return nil, err
+
+	return memory
+}
+
+func NewAutoTuneConfigMap(cr *api.PerconaXtraDBCluster, cmName string) (*corev1.ConfigMap, error) {
+	var autotuneParams, providerOptions string
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		params, err := AutoTune(cr)
+		if err != nil {
+			return nil, err
+		}
+		autotuneParams = autoTuneConfig(params)
+		providerOptions = autoTuneProviderOptions(params)
+	} else {
+		var err error
+		autotuneParams, err = getAutoTuneParams(AutoTuneMemory(cr))
+		if err != nil {
+			return nil, err
+		}
 	}
-	return &corev1.ConfigMap{
+	cm := &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
 			Kind:       "ConfigMap",
@@ -54,5 +72,10 @@ func NewAutoTuneConfigMap(cr *api.PerconaXtraDBCluster, cmName string) (*corev1.
 		Data: map[string]string{
 			"auto-config.cnf": "[mysqld]" + autotuneParams,
 		},
-	}, nil
+	}
+	if len(providerOptions) > 0 {
+		cm.Data[AutoTuneProviderOptionsFile] = providerOptions
+	}
+
+	return cm, nil
 }
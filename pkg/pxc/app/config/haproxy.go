@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+)
+
+// HAProxyAutoConfigFile is the file with the frontends and the backends
+// included by HAProxy from the haproxy-auto volume
+const HAProxyAutoConfigFile = "haproxy.cfg"
+
+// HAProxyZonesMapFile maps the nodes of the HAProxy pods to their zones
+const HAProxyZonesMapFile = "zones.map"
+
+// defaultHAProxyWeight is the weight of the PXC pods without explicit weight
+const defaultHAProxyWeight = 100
+
+// HAProxyNode is the PXC pod served by HAProxy
+type HAProxyNode struct {
+	Name string
+	Host string
+	Zone string
+}
+
+// HAProxyNodes returns the PXC pods of the cluster by the ordinal
+func HAProxyNodes(cr *api.PerconaXtraDBCluster) []HAProxyNode {
+	nodes := make([]HAProxyNode, 0, cr.Spec.PXC.Size)
+	for i := 0; i < int(cr.Spec.PXC.Size); i++ {
+		name := fmt.Sprintf("%s-pxc-%d", cr.Name, i)
+		nodes = append(nodes, HAProxyNode{
+			Name: name,
+			Host: name + "." + cr.Name + "-pxc." + cr.Namespace,
+		})
+	}
+	return nodes
+}
+
+// HAProxyConfig renders the frontends and the backends of HAProxy by the
+// backends spec. The servers are resolved by the pod names, so the config
+// is changed only if the cluster is resized, the spec or the zones of the
+// PXC pods are changed.
+func HAProxyConfig(cr *api.PerconaXtraDBCluster, nodes []HAProxyNode, proxyProtocol bool) string {
+	spec := cr.Spec.HAProxy.Backends
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, "resolvers kubernetes")
+	fmt.Fprintln(b, "    parse-resolv-conf")
+	fmt.Fprintln(b, "    hold valid 10s")
+
+	writers := writerOrder(spec, nodes)
+	writeBackend(b, spec, "galera-nodes", writers, 3306, proxyProtocol, true)
+	writeBackend(b, spec, "galera-replica-nodes", nodes, 3306, proxyProtocol, false)
+	writeBackend(b, spec, "galera-mysqlx-nodes", writers, 33060, false, true)
+	if cr.CompareVersionWith("1.6.0") >= 0 {
+		writeBackend(b, spec, "galera-admin-nodes", writers, 33062, false, true)
+	}
+
+	// the reads of the HAProxy pod go to the backend of its zone,
+	// the replicas of the other zones are the backups there
+	var replicaRules []string
+	if cr.Spec.HAProxy.ZoneAwareReads {
+		for _, zone := range nodeZones(nodes) {
+			writeZoneBackend(b, spec, "galera-replica-nodes-"+zone, zone, nodes, proxyProtocol)
+		}
+		zoneOfPod := "env(NODE_NAME),map(/etc/haproxy/pxc/" + HAProxyZonesMapFile + ")"
+		replicaRules = append(replicaRules,
+			"use_backend galera-replica-nodes-%["+zoneOfPod+"] if { "+zoneOfPod+" -m found }")
+	}
+
+	writeFrontend(b, spec.MaxConnections, "galera-in", "galera-nodes", nil, "*:3306", "*:3309 accept-proxy")
+	writeFrontend(b, spec.MaxConnections, "galera-replica-in", "galera-replica-nodes", replicaRules, "*:3307")
+	writeFrontend(b, spec.MaxConnections, "galera-mysqlx-in", "galera-mysqlx-nodes", nil, "*:33060")
+	if cr.CompareVersionWith("1.6.0") >= 0 {
+		writeFrontend(b, spec.MaxConnections, "galera-admin-in", "galera-admin-nodes", nil, "*:33062")
+	}
+
+	for _, l := range spec.Listeners {
+		listenerNodes := nodes
+		if len(l.Nodes) > 0 {
+			listenerNodes = make([]HAProxyNode, 0, len(l.Nodes))
+			for _, n := range nodes {
+				if containsString(l.Nodes, n.Name) {
+					listenerNodes = append(listenerNodes, n)
+				}
+			}
+		}
+		maxConn := spec.MaxConnections
+		if l.MaxConnections > 0 {
+			maxConn = l.MaxConnections
+		}
+		writeBackend(b, spec, l.Name+"-nodes", listenerNodes, 3306, proxyProtocol, false)
+		writeFrontend(b, maxConn, l.Name+"-in", l.Name+"-nodes", nil, fmt.Sprintf("*:%d", l.Port))
+	}
+
+	if cr.HAProxyMetricsEnabled() {
+		writeMetricsFrontend(b)
+	}
+
+	return b.String()
+}
+
+// HAProxyZonesMap renders the map of the nodes to their zones
+func HAProxyZonesMap(topology []api.PodTopology) string {
+	zones := make(map[string]string, len(topology))
+	for _, t := range topology {
+		if len(t.Zone) > 0 {
+			zones[t.Node] = t.Zone
+		}
+	}
+	nodes := make([]string, 0, len(zones))
+	for n := range zones {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+
+	b := &strings.Builder{}
+	for _, n := range nodes {
+		fmt.Fprintf(b, "%s %s\n", n, zones[n])
+	}
+	return b.String()
+}
+
+// nodeZones returns the sorted zones of the nodes
+func nodeZones(nodes []HAProxyNode) []string {
+	var zones []string
+	for _, n := range nodes {
+		if len(n.Zone) > 0 && !containsString(zones, n.Zone) {
+			zones = append(zones, n.Zone)
+		}
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// writerOrder returns the nodes in the order they become the writer
+func writerOrder(spec *api.HAProxyBackendsSpec, nodes []HAProxyNode) []HAProxyNode {
+	ordered := append([]HAProxyNode(nil), nodes...)
+	if spec.WriterPolicy == api.HAProxyWriterPreferredZone {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Zone == spec.PreferredZone && ordered[j].Zone != spec.PreferredZone
+		})
+	}
+	return ordered
+}
+
+// writeBackend renders the backend of the nodes. The single writer backend
+// has one active server, the rest are the backups taken in the order.
+// The sticky writer stays on the server it switched to until it fails.
+func writeBackend(b *strings.Builder, spec *api.HAProxyBackendsSpec, name string, nodes []HAProxyNode, port int, proxyProtocol, writer bool) {
+	writeBackendHeader(b, name)
+	sticky := writer && spec.WriterPolicy == api.HAProxyWriterSticky
+	switch {
+	case sticky:
+		fmt.Fprintln(b, "    balance first")
+		fmt.Fprintln(b, "    stick-table type integer size 1")
+		fmt.Fprintln(b, "    stick on int(1)")
+	default:
+		fmt.Fprintln(b, "    balance roundrobin")
+	}
+
+	for i, n := range nodes {
+		fmt.Fprintln(b, serverLine(spec, n, port, proxyProtocol, writer, writer && !sticky && i > 0))
+	}
+}
+
+// writeZoneBackend renders the replicas backend of the zone. The replicas
+// of the other zones are used only if all the replicas of the zone are down.
+func writeZoneBackend(b *strings.Builder, spec *api.HAProxyBackendsSpec, name, zone string, nodes []HAProxyNode, proxyProtocol bool) {
+	writeBackendHeader(b, name)
+	fmt.Fprintln(b, "    balance roundrobin")
+	fmt.Fprintln(b, "    option allbackups")
+	for _, n := range nodes {
+		fmt.Fprintln(b, serverLine(spec, n, 3306, proxyProtocol, false, n.Zone != zone))
+	}
+}
+
+func writeBackendHeader(b *strings.Builder, name string) {
+	fmt.Fprintf(b, "\nbackend %s\n", name)
+	fmt.Fprintln(b, "    mode tcp")
+	fmt.Fprintln(b, "    option srvtcpka")
+	fmt.Fprintln(b, "    option external-check")
+	fmt.Fprintln(b, "    external-check command /usr/local/bin/check_pxc.sh")
+}
+
+func serverLine(spec *api.HAProxyBackendsSpec, n HAProxyNode, port int, proxyProtocol, writer, backup bool) string {
+	server := fmt.Sprintf("    server %s %s:%d resolvers kubernetes init-addr none check inter 10000 rise 1 fall 2", n.Name, n.Host, port)
+	if writer {
+		server += " on-marked-down shutdown-sessions"
+	} else {
+		w, ok := spec.Weights[n.Name]
+		if !ok {
+			w = defaultHAProxyWeight
+		}
+		server += fmt.Sprintf(" weight %d", w)
+	}
+	if spec.ServerMaxConnections > 0 {
+		server += fmt.Sprintf(" maxconn %d", spec.ServerMaxConnections)
+	}
+	if proxyProtocol {
+		server += " send-proxy-v2 check-send-proxy"
+	}
+	if backup {
+		server += " backup"
+	}
+	return server
+}
+
+func writeFrontend(b *strings.Builder, maxConn int, name, backend string, rules []string, binds ...string) {
+	fmt.Fprintf(b, "\nfrontend %s\n", name)
+	for _, bind := range binds {
+		fmt.Fprintf(b, "    bind %s\n", bind)
+	}
+	fmt.Fprintln(b, "    mode tcp")
+	fmt.Fprintln(b, "    option clitcpka")
+	if maxConn > 0 {
+		fmt.Fprintf(b, "    maxconn %d\n", maxConn)
+	}
+	for _, r := range rules {
+		fmt.Fprintf(b, "    %s\n", r)
+	}
+	fmt.Fprintf(b, "    default_backend %s\n", backend)
+}
+
+// writeMetricsFrontend renders the frontend of the built-in prometheus exporter
+func writeMetricsFrontend(b *strings.Builder) {
+	fmt.Fprintf(b, "\nfrontend %s\n", app.MetricsPortName)
+	fmt.Fprintf(b, "    bind *:%d\n", app.HAProxyMetricsPort)
+	fmt.Fprintln(b, "    mode http")
+	fmt.Fprintln(b, "    http-request use-service prometheus-exporter if { path /metrics }")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func newHAProxyCR(backends *api.HAProxyBackendsSpec) *api.PerconaXtraDBCluster {
+	return &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "pxc"},
+		Spec: api.PerconaXtraDBClusterSpec{
+			CRVersion: "1.8.0",
+			PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Size: 3}},
+			HAProxy:   &api.PodSpec{Enabled: true, Backends: backends},
+		},
+	}
+}
+
+// backend returns the server lines of the rendered backend
+func backend(conf, name string) []string {
+	var servers []string
+	in := false
+	for _, line := range strings.Split(conf, "\n") {
+		switch {
+		case strings.HasPrefix(line, "backend "):
+			in = line == "backend "+name
+		case in && strings.HasPrefix(strings.TrimSpace(line), "server "):
+			servers = append(servers, strings.TrimSpace(line))
+		}
+	}
+	return servers
+}
+
+func TestHAProxyConfig(t *testing.T) {
+	cr := newHAProxyCR(&api.HAProxyBackendsSpec{
+		WriterPolicy:  api.HAProxyWriterPreferredZone,
+		PreferredZone: "b",
+		Weights:       map[string]int{"cluster1-pxc-2": 50},
+		Listeners: []api.HAProxyListener{
+			{Name: "reporting", Port: 3310, Nodes: []string{"cluster1-pxc-2"}, MaxConnections: 200},
+		},
+		ServerMaxConnections: 1000,
+	})
+	nodes := HAProxyNodes(cr)
+	nodes[0].Zone, nodes[1].Zone, nodes[2].Zone = "a", "b", "a"
+
+	conf := HAProxyConfig(cr, nodes, false)
+
+	writers := backend(conf, "galera-nodes")
+	if len(writers) != 3 {
+		t.Fatalf("expected 3 writer servers, got %d:\n%s", len(writers), conf)
+	}
+	if !strings.HasPrefix(writers[0], "server cluster1-pxc-1 ") || strings.Contains(writers[0], " backup") {
+		t.Errorf("expected cluster1-pxc-1 in the preferred zone to be the active writer, got %q", writers[0])
+	}
+	for _, s := range writers[1:] {
+		if !strings.HasSuffix(s, " backup") {
+			t.Errorf("expected backup writer, got %q", s)
+		}
+	}
+
+	replicas := backend(conf, "galera-replica-nodes")
+	if len(replicas) != 3 || !strings.Contains(replicas[0], " weight 100 maxconn 1000") || !strings.Contains(replicas[2], " weight 50 ") {
+		t.Errorf("unexpected replica servers: %q", replicas)
+	}
+
+	reporting := backend(conf, "reporting-nodes")
+	if len(reporting) != 1 || !strings.HasPrefix(reporting[0], "server cluster1-pxc-2 ") {
+		t.Errorf("expected reporting listener pinned to cluster1-pxc-2, got %q", reporting)
+	}
+	if !strings.Contains(conf, "frontend reporting-in\n    bind *:3310\n    mode tcp\n    option clitcpka\n    maxconn 200\n") {
+		t.Errorf("reporting frontend isn't rendered:\n%s", conf)
+	}
+}
+
+func TestHAProxyZoneAwareReads(t *testing.T) {
+	cr := newHAProxyCR(&api.HAProxyBackendsSpec{})
+	cr.Spec.HAProxy.ZoneAwareReads = true
+	nodes := HAProxyNodes(cr)
+	nodes[0].Zone, nodes[1].Zone, nodes[2].Zone = "a", "b", "a"
+
+	conf := HAProxyConfig(cr, nodes, false)
+
+	zoneA := backend(conf, "galera-replica-nodes-a")
+	if len(zoneA) != 3 || strings.HasSuffix(zoneA[0], " backup") || !strings.HasSuffix(zoneA[1], " backup") ||
+		strings.HasSuffix(zoneA[2], " backup") {
+		t.Errorf("expected the replicas of zone b to be the backups, got %q", zoneA)
+	}
+	if len(backend(conf, "galera-replica-nodes-b")) != 3 {
+		t.Errorf("backend of zone b isn't rendered:\n%s", conf)
+	}
+	if !strings.Contains(conf, "use_backend galera-replica-nodes-%[env(NODE_NAME),map(/etc/haproxy/pxc/zones.map)]") {
+		t.Errorf("replicas frontend doesn't choose the backend by the zone:\n%s", conf)
+	}
+
+	zones := HAProxyZonesMap([]api.PodTopology{
+		{Pod: "cluster1-haproxy-0", Node: "node-2", Zone: "b"},
+		{Pod: "cluster1-pxc-0", Node: "node-1", Zone: "a"},
+		{Pod: "cluster1-pxc-1", Node: "node-2", Zone: "b"},
+		{Pod: "cluster1-pxc-2", Node: "node-3"},
+	})
+	if zones != "node-1 a\nnode-2 b\n" {
+		t.Errorf("unexpected zones map %q", zones)
+	}
+}
+
+func TestHAProxyMetricsFrontend(t *testing.T) {
+	cr := newHAProxyCR(&api.HAProxyBackendsSpec{})
+
+	if conf := HAProxyConfig(cr, HAProxyNodes(cr), false); strings.Contains(conf, "prometheus-exporter") {
+		t.Errorf("metrics frontend is rendered with disabled metrics:\n%s", conf)
+	}
+
+	cr.Spec.Metrics = &api.MetricsSpec{Enabled: true}
+	conf := HAProxyConfig(cr, HAProxyNodes(cr), false)
+	expected := "frontend metrics\n    bind *:8404\n    mode http\n" +
+		"    http-request use-service prometheus-exporter if { path /metrics }\n"
+	if !strings.Contains(conf, expected) {
+		t.Errorf("metrics frontend isn't rendered:\n%s", conf)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/pkg/errors"
+)
+
+// configurationKeys returns the options set in the [mysqld] section of the
+// user configuration. Dashes are normalized to underscores as mysqld does.
+func configurationKeys(conf string) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	if len(strings.TrimSpace(conf)) == 0 {
+		return keys, nil
+	}
+
+	file, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(conf))
+	if err != nil {
+		return nil, errors.Wrap(err, "load configuration")
+	}
+
+	s, err := file.GetSection("mysqld")
+	if err != nil {
+		// there is no [mysqld] section
+		return keys, nil
+	}
+
+	for _, name := range s.KeyStrings() {
+		keys[normalizeOption(name)] = struct{}{}
+	}
+
+	return keys, nil
+}
+
+func normalizeOption(name string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(name), "-", "_", -1))
+}
+
+// ConfigurationDiff is the difference between two PXC configurations
+type ConfigurationDiff struct {
+	// Dynamic holds the new values of the changed [mysqld] variables
+	// that can be applied with SET GLOBAL
+	Dynamic map[string]interface{}
+	// Static holds the changed options that are applied only on restart.
+	// Options of the sections other than [mysqld] are prefixed with the section name.
+	Static []string
+}
+
+// Empty returns true if the configurations are equal
+func (d ConfigurationDiff) Empty() bool {
+	return len(d.Dynamic) == 0 && len(d.Static) == 0
+}
+
+// DiffConfiguration compares the old and the new PXC configurations.
+// Removed options are static as the compiled-in default can't be restored
+// online if the option is also set by the auto-tuning.
+func DiffConfiguration(old, new string) (ConfigurationDiff, error) {
+	diff := ConfigurationDiff{
+		Dynamic: make(map[string]interface{}),
+	}
+
+	oldOpts, err := configurationOptions(old)
+	if err != nil {
+		return diff, errors.Wrap(err, "old configuration")
+	}
+	newOpts, err := configurationOptions(new)
+	if err != nil {
+		return diff, errors.Wrap(err, "new configuration")
+	}
+
+	sections := make(map[string]struct{})
+	for section := range oldOpts {
+		sections[section] = struct{}{}
+	}
+	for section := range newOpts {
+		sections[section] = struct{}{}
+	}
+
+	for section := range sections {
+		names := make(map[string]struct{})
+		for name := range oldOpts[section] {
+			names[name] = struct{}{}
+		}
+		for name := range newOpts[section] {
+			names[name] = struct{}{}
+		}
+
+		for name := range names {
+			oldValue, inOld := oldOpts[section][name]
+			newValue, inNew := newOpts[section][name]
+			if inOld && inNew && oldValue == newValue {
+				continue
+			}
+
+			if section != "mysqld" {
+				diff.Static = append(diff.Static, section+"."+name)
+				continue
+			}
+
+			variable := strings.TrimPrefix(name, "loose_")
+			v, ok := mysqldVariables[variable]
+			if !inNew || !ok || !v.dynamic {
+				diff.Static = append(diff.Static, name)
+				continue
+			}
+			value, err := v.setValue(newValue)
+			if err != nil {
+				diff.Static = append(diff.Static, name)
+				continue
+			}
+			diff.Dynamic[variable] = value
+		}
+	}
+	sort.Strings(diff.Static)
+
+	return diff, nil
+}
+
+// configurationOptions returns the options of every section.
+// Option names of the [mysqld] section are normalized.
+func configurationOptions(conf string) (map[string]map[string]string, error) {
+	opts := make(map[string]map[string]string)
+	if len(strings.TrimSpace(conf)) == 0 {
+		return opts, nil
+	}
+
+	file, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(conf))
+	if err != nil {
+		return nil, errors.Wrap(err, "load configuration")
+	}
+
+	for _, s := range file.Sections() {
+		section := make(map[string]string)
+		for _, key := range s.Keys() {
+			name := key.Name()
+			if s.Name() == "mysqld" {
+				name = normalizeOption(name)
+			}
+			section[name] = key.Value()
+		}
+		if len(section) > 0 {
+			opts[s.Name()] = section
+		}
+	}
+
+	return opts, nil
+}
+
+// setValue converts the option value to the value accepted by SET GLOBAL
+func (v mysqldVariable) setValue(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+
+	switch v.kind {
+	case kindBool:
+		if len(value) == 0 || containsFold([]string{"on", "true", "1"}, value) {
+			return "ON", nil
+		}
+		return "OFF", nil
+	case kindInt:
+		// SET doesn't accept the K, M, G suffixes
+		return parseSize(value)
+	case kindFloat:
+		return strconv.ParseFloat(value, 64)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		old, new string
+		dynamic  map[string]interface{}
+		static   []string
+	}{
+		"equal": {
+			old:     "[mysqld]\nmax_connections=100",
+			new:     "[mysqld]\nmax-connections=100",
+			dynamic: map[string]interface{}{},
+		},
+		"dynamic": {
+			old:     "[mysqld]\nmax_connections=100\nwsrep_log_conflicts",
+			new:     "[mysqld]\nmax_connections=200\nwsrep_log_conflicts=OFF\nmax_allowed_packet=64M",
+			dynamic: map[string]interface{}{"max_connections": int64(200), "wsrep_log_conflicts": "OFF", "max_allowed_packet": int64(67108864)},
+		},
+		"static": {
+			old:     "[mysqld]\nmax_connections=100\ninnodb_log_file_size=1G",
+			new:     "[mysqld]\nmax_connections=200\ninnodb_log_file_size=2G",
+			dynamic: map[string]interface{}{"max_connections": int64(200)},
+			static:  []string{"innodb_log_file_size"},
+		},
+		"removed": {
+			old:     "[mysqld]\nmax_connections=100",
+			new:     "[mysqld]",
+			dynamic: map[string]interface{}{},
+			static:  []string{"max_connections"},
+		},
+		"other section": {
+			old:     "[mysqld]\nmax_connections=100\n[sst]\nxbstream-opts=--decompress",
+			new:     "[mysqld]\nmax_connections=100",
+			dynamic: map[string]interface{}{},
+			static:  []string{"sst.xbstream-opts"},
+		},
+	}
+
+	for name, test := range tests {
+		diff, err := DiffConfiguration(test.old, test.new)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(diff.Dynamic, test.dynamic) {
+			t.Errorf("%s: got dynamic %v, expected %v", name, diff.Dynamic, test.dynamic)
+		}
+		if !reflect.DeepEqual(diff.Static, test.static) {
+			t.Errorf("%s: got static %v, expected %v", name, diff.Static, test.static)
+		}
+	}
+}
@@ -0,0 +1,660 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/pkg/errors"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+type variableKind int
+
+const (
+	kindString variableKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindEnum
+)
+
+// mysqldVariable describes the option accepted by mysqld
+type mysqldVariable struct {
+	kind variableKind
+	min  int64
+	// max is ignored if it's 0
+	max    int64
+	values []string
+	// only is the major version the option exists in, e.g. "5.7"
+	only string
+	// deprecated is the major version the option is deprecated in
+	deprecated string
+	// hint is added to the deprecation or removal message
+	hint string
+	// dynamic variables can be changed with SET GLOBAL without a restart
+	dynamic bool
+}
+
+// unsafeVariables are the options managed by the operator or the entrypoint.
+// Overriding them breaks the cluster, so they are accepted only with
+// allowUnsafeConfigurations. If values are set, only these values are unsafe.
+var unsafeVariables = map[string][]string{
+	"wsrep_provider":              nil,
+	"wsrep_cluster_address":       nil,
+	"wsrep_cluster_name":          nil,
+	"wsrep_node_address":          nil,
+	"wsrep_node_incoming_address": nil,
+	"wsrep_sst_receive_address":   nil,
+	"wsrep_sst_method":            nil,
+	"wsrep_sst_auth":              nil,
+	"wsrep_on":                    {"off", "0", "false"},
+	"pxc_strict_mode":             {"disabled"},
+	"datadir":                     nil,
+	"socket":                      nil,
+	"port":                        nil,
+	"admin_address":               nil,
+	"admin_port":                  nil,
+	"skip_grant_tables":           nil,
+	"skip_networking":             nil,
+}
+
+// pluginPrefixes are the prefixes of the options added by the plugins.
+// The plugins are loaded by the user, so their options aren't in the catalog.
+var pluginPrefixes = []string{
+	"audit_log_",
+	"keyring_",
+	"validate_password",
+	"rpl_semi_sync_",
+	"connection_control_",
+	"query_response_time_",
+	"performance_schema_",
+	"mysqlx_",
+	"rocksdb_",
+	"tokudb_",
+}
+
+// mysqldVariables is the catalog of the known mysqld options
+var mysqldVariables = map[string]mysqldVariable{
+	// wsrep
+	"wsrep_provider":                  {kind: kindString},
+	"wsrep_provider_options":          {kind: kindString},
+	"wsrep_cluster_address":           {kind: kindString},
+	"wsrep_cluster_name":              {kind: kindString},
+	"wsrep_node_address":              {kind: kindString},
+	"wsrep_node_incoming_address":     {kind: kindString},
+	"wsrep_node_name":                 {kind: kindString},
+	"wsrep_sst_receive_address":       {kind: kindString},
+	"wsrep_sst_method":                {kind: kindString},
+	"wsrep_sst_donor":                 {kind: kindString, dynamic: true},
+	"wsrep_sst_donor_rejects_queries": {kind: kindBool, dynamic: true},
+	"wsrep_sst_auth":                  {kind: kindString, only: "5.7"},
+	"wsrep_on":                        {kind: kindBool},
+	"wsrep_debug":                     {kind: kindString, dynamic: true},
+	"wsrep_log_conflicts":             {kind: kindBool, dynamic: true},
+	"wsrep_slave_threads":             {kind: kindInt, min: 1, max: 512, dynamic: true},
+	"wsrep_applier_threads":           {kind: kindInt, min: 1, max: 512, dynamic: true},
+	"wsrep_slave_fk_checks":           {kind: kindBool, dynamic: true},
+	"wsrep_slave_uk_checks":           {kind: kindBool, dynamic: true},
+	"wsrep_sync_wait":                 {kind: kindInt, min: 0, max: 15, dynamic: true},
+	"wsrep_causal_reads":              {kind: kindBool, only: "5.7", deprecated: "5.7", hint: "use wsrep_sync_wait", dynamic: true},
+	"wsrep_auto_increment_control":    {kind: kindBool, dynamic: true},
+	"wsrep_certify_nonpk":             {kind: kindBool, dynamic: true},
+	"wsrep_max_ws_rows":               {kind: kindInt, min: 0, max: 1048576, dynamic: true},
+	"wsrep_max_ws_size":               {kind: kindInt, min: 1024, max: 2147483647, dynamic: true},
+	"wsrep_retry_autocommit":          {kind: kindInt, min: 0, max: 10000, dynamic: true},
+	"wsrep_osu_method":                {kind: kindEnum, values: []string{"TOI", "RSU", "NBO"}, dynamic: true},
+	"wsrep_forced_binlog_format":      {kind: kindEnum, values: []string{"ROW", "STATEMENT", "MIXED", "NONE"}, dynamic: true},
+	"wsrep_recover":                   {kind: kindBool},
+	"wsrep_reject_queries":            {kind: kindEnum, values: []string{"NONE", "ALL", "ALL_KILL"}, dynamic: true},
+	"wsrep_restart_slave":             {kind: kindBool, dynamic: true},
+	"wsrep_notify_cmd":                {kind: kindString},
+	"wsrep_dirty_reads":               {kind: kindBool, dynamic: true},
+	"wsrep_desync":                    {kind: kindBool, dynamic: true},
+	"wsrep_ignore_apply_errors":       {kind: kindInt, min: 0, max: 7, dynamic: true},
+	"wsrep_min_log_verbosity":         {kind: kindInt, min: 1, max: 3, only: "8.0", dynamic: true},
+	"wsrep_drupal_282555_workaround":  {kind: kindBool, only: "5.7", dynamic: true},
+	"wsrep_preordered":                {kind: kindBool, only: "5.7"},
+	"wsrep_replicate_myisam":          {kind: kindBool, only: "5.7", hint: "use pxc_strict_mode", dynamic: true},
+	"wsrep_load_data_splitting":       {kind: kindBool, dynamic: true},
+	"wsrep_data_home_dir":             {kind: kindString},
+	"wsrep_dbug_option":               {kind: kindString},
+	"wsrep_start_position":            {kind: kindString},
+	"pxc_strict_mode":                 {kind: kindEnum, values: []string{"ENFORCING", "PERMISSIVE", "MASTER", "DISABLED"}, dynamic: true},
+	"pxc_maint_mode":                  {kind: kindEnum, values: []string{"DISABLED", "SHUTDOWN", "MAINTENANCE"}, dynamic: true},
+	"pxc_maint_transition_period":     {kind: kindInt, min: 0, dynamic: true},
+	"pxc_encrypt_cluster_traffic":     {kind: kindBool},
+
+	// server
+	"datadir":                         {kind: kindString},
+	"socket":                          {kind: kindString},
+	"port":                            {kind: kindInt, min: 0, max: 65535},
+	"bind_address":                    {kind: kindString},
+	"admin_address":                   {kind: kindString, only: "8.0"},
+	"admin_port":                      {kind: kindInt, min: 0, max: 65535, only: "8.0"},
+	"server_id":                       {kind: kindInt, min: 0, max: 4294967295},
+	"user":                            {kind: kindString},
+	"pid_file":                        {kind: kindString},
+	"tmpdir":                          {kind: kindString},
+	"secure_file_priv":                {kind: kindString},
+	"character_set_server":            {kind: kindString, dynamic: true},
+	"collation_server":                {kind: kindString, dynamic: true},
+	"init_connect":                    {kind: kindString, dynamic: true},
+	"init_file":                       {kind: kindString},
+	"sql_mode":                        {kind: kindString, dynamic: true},
+	"default_time_zone":               {kind: kindString},
+	"default_storage_engine":          {kind: kindString, dynamic: true},
+	"default_tmp_storage_engine":      {kind: kindString, dynamic: true},
+	"default_authentication_plugin":   {kind: kindEnum, values: []string{"mysql_native_password", "sha256_password", "caching_sha2_password"}},
+	"explicit_defaults_for_timestamp": {kind: kindBool},
+	"lower_case_table_names":          {kind: kindInt, min: 0, max: 2},
+	"skip_name_resolve":               {kind: kindBool},
+	"skip_grant_tables":               {kind: kindBool},
+	"skip_networking":                 {kind: kindBool},
+	"skip_external_locking":           {kind: kindBool},
+	"symbolic_links":                  {kind: kindBool, deprecated: "8.0"},
+	"local_infile":                    {kind: kindBool, dynamic: true},
+	"event_scheduler":                 {kind: kindEnum, values: []string{"ON", "OFF", "DISABLED", "1", "0"}, dynamic: true},
+	"transaction_isolation":           {kind: kindEnum, values: []string{"READ-UNCOMMITTED", "READ-COMMITTED", "REPEATABLE-READ", "SERIALIZABLE"}, dynamic: true},
+	"tx_isolation":                    {kind: kindEnum, values: []string{"READ-UNCOMMITTED", "READ-COMMITTED", "REPEATABLE-READ", "SERIALIZABLE"}, only: "5.7", deprecated: "5.7", hint: "use transaction_isolation", dynamic: true},
+	"transaction_read_only":           {kind: kindBool, dynamic: true},
+	"tx_read_only":                    {kind: kindBool, only: "5.7", deprecated: "5.7", hint: "use transaction_read_only", dynamic: true},
+	"read_only":                       {kind: kindBool, dynamic: true},
+	"super_read_only":                 {kind: kindBool, dynamic: true},
+	"autocommit":                      {kind: kindBool, dynamic: true},
+	"max_connections":                 {kind: kindInt, min: 1, max: 100000, dynamic: true},
+	"max_user_connections":            {kind: kindInt, min: 0, max: 4294967295, dynamic: true},
+	"max_connect_errors":              {kind: kindInt, min: 1, dynamic: true},
+	"max_allowed_packet":              {kind: kindInt, min: 1024, max: 1073741824, dynamic: true},
+	"max_heap_table_size":             {kind: kindInt, min: 16384, dynamic: true},
+	"tmp_table_size":                  {kind: kindInt, min: 1024, dynamic: true},
+	"max_prepared_stmt_count":         {kind: kindInt, min: 0, max: 4194304, dynamic: true},
+	"max_execution_time":              {kind: kindInt, min: 0, dynamic: true},
+	"back_log":                        {kind: kindInt, min: 1, max: 65535},
+	"thread_cache_size":               {kind: kindInt, min: 0, max: 16384, dynamic: true},
+	"thread_stack":                    {kind: kindInt, min: 131072},
+	"thread_handling":                 {kind: kindEnum, values: []string{"one-thread-per-connection", "no-threads", "pool-of-threads"}},
+	"thread_pool_size":                {kind: kindInt, min: 1, dynamic: true},
+	"thread_pool_max_threads":         {kind: kindInt, min: 1, max: 100000, dynamic: true},
+	"thread_pool_oversubscribe":       {kind: kindInt, min: 1, max: 1000, dynamic: true},
+	"thread_pool_stall_limit":         {kind: kindInt, min: 1, dynamic: true},
+	"extra_port":                      {kind: kindInt, min: 0, max: 65535, only: "5.7"},
+	"extra_max_connections":           {kind: kindInt, min: 1, max: 100000, only: "5.7", dynamic: true},
+	"table_open_cache":                {kind: kindInt, min: 1, max: 524288, dynamic: true},
+	"table_open_cache_instances":      {kind: kindInt, min: 1, max: 64},
+	"table_definition_cache":          {kind: kindInt, min: 400, max: 524288, dynamic: true},
+	"open_files_limit":                {kind: kindInt, min: 0},
+	"sort_buffer_size":                {kind: kindInt, min: 32768, dynamic: true},
+	"join_buffer_size":                {kind: kindInt, min: 128, dynamic: true},
+	"read_buffer_size":                {kind: kindInt, min: 8192, max: 2147479552, dynamic: true},
+	"read_rnd_buffer_size":            {kind: kindInt, min: 1, max: 2147483647, dynamic: true},
+	"key_buffer_size":                 {kind: kindInt, min: 8, dynamic: true},
+	"bulk_insert_buffer_size":         {kind: kindInt, min: 0, dynamic: true},
+	"net_buffer_length":               {kind: kindInt, min: 1024, max: 1048576, dynamic: true},
+	"net_read_timeout":                {kind: kindInt, min: 1, dynamic: true},
+	"net_write_timeout":               {kind: kindInt, min: 1, dynamic: true},
+	"connect_timeout":                 {kind: kindInt, min: 2, max: 31536000, dynamic: true},
+	"wait_timeout":                    {kind: kindInt, min: 1, max: 31536000, dynamic: true},
+	"interactive_timeout":             {kind: kindInt, min: 1, dynamic: true},
+	"lock_wait_timeout":               {kind: kindInt, min: 1, max: 31536000, dynamic: true},
+	"group_concat_max_len":            {kind: kindInt, min: 4, dynamic: true},
+	"ft_min_word_len":                 {kind: kindInt, min: 1},
+	"performance_schema":              {kind: kindBool},
+	"userstat":                        {kind: kindBool, dynamic: true},
+	"query_cache_size":                {kind: kindInt, min: 0, only: "5.7", deprecated: "5.7", dynamic: true},
+	"query_cache_type":                {kind: kindEnum, values: []string{"OFF", "ON", "DEMAND", "0", "1", "2"}, only: "5.7", deprecated: "5.7", dynamic: true},
+	"query_cache_limit":               {kind: kindInt, min: 0, only: "5.7", deprecated: "5.7", dynamic: true},
+	"secure_auth":                     {kind: kindBool, only: "5.7", deprecated: "5.7", dynamic: true},
+	"old_passwords":                   {kind: kindInt, min: 0, max: 2, only: "5.7", deprecated: "5.7", dynamic: true},
+	"sync_frm":                        {kind: kindBool, only: "5.7", deprecated: "5.7"},
+	"max_tmp_tables":                  {kind: kindInt, min: 1, only: "5.7"},
+	"multi_range_count":               {kind: kindInt, min: 1, only: "5.7"},
+	"date_format":                     {kind: kindString, only: "5.7"},
+	"datetime_format":                 {kind: kindString, only: "5.7"},
+	"time_format":                     {kind: kindString, only: "5.7"},
+	"show_compatibility_56":           {kind: kindBool, only: "5.7", deprecated: "5.7", dynamic: true},
+	"default_password_lifetime":       {kind: kindInt, min: 0, max: 65535, dynamic: true},
+	"ssl_ca":                          {kind: kindString},
+	"ssl_cert":                        {kind: kindString},
+	"ssl_key":                         {kind: kindString},
+	"ssl_cipher":                      {kind: kindString},
+	"tls_version":                     {kind: kindString},
+	"require_secure_transport":        {kind: kindBool, dynamic: true},
+	"plugin_load":                     {kind: kindString},
+	"plugin_load_add":                 {kind: kindString},
+	"early_plugin_load":               {kind: kindString},
+
+	// logs
+	"log_error":                       {kind: kindString},
+	"log_error_verbosity":             {kind: kindInt, min: 1, max: 3, dynamic: true},
+	"log_error_suppression_list":      {kind: kindString, only: "8.0", dynamic: true},
+	"log_warnings":                    {kind: kindInt, min: 0, max: 2, only: "5.7", deprecated: "5.7", hint: "use log_error_verbosity", dynamic: true},
+	"log_timestamps":                  {kind: kindEnum, values: []string{"UTC", "SYSTEM"}, dynamic: true},
+	"general_log":                     {kind: kindBool, dynamic: true},
+	"general_log_file":                {kind: kindString, dynamic: true},
+	"slow_query_log":                  {kind: kindBool, dynamic: true},
+	"slow_query_log_file":             {kind: kindString, dynamic: true},
+	"long_query_time":                 {kind: kindFloat, min: 0, dynamic: true},
+	"log_queries_not_using_indexes":   {kind: kindBool, dynamic: true},
+	"log_slow_verbosity":              {kind: kindString, dynamic: true},
+	"log_slow_rate_limit":             {kind: kindInt, min: 1, max: 1000, dynamic: true},
+	"log_output":                      {kind: kindString, dynamic: true},
+	"log_bin":                         {kind: kindString},
+	"log_bin_index":                   {kind: kindString},
+	"skip_log_bin":                    {kind: kindBool},
+	"log_bin_trust_function_creators": {kind: kindBool, dynamic: true},
+	"log_slave_updates":               {kind: kindBool},
+	"binlog_format":                   {kind: kindEnum, values: []string{"ROW", "STATEMENT", "MIXED"}, dynamic: true},
+	"binlog_row_image":                {kind: kindEnum, values: []string{"FULL", "MINIMAL", "NOBLOB"}, dynamic: true},
+	"binlog_cache_size":               {kind: kindInt, min: 4096, dynamic: true},
+	"binlog_expire_logs_seconds":      {kind: kindInt, min: 0, max: 4294967295, only: "8.0", dynamic: true},
+	"expire_logs_days":                {kind: kindInt, min: 0, max: 99, deprecated: "8.0", hint: "use binlog_expire_logs_seconds", dynamic: true},
+	"max_binlog_size":                 {kind: kindInt, min: 4096, max: 1073741824, dynamic: true},
+	"max_binlog_cache_size":           {kind: kindInt, min: 4096, dynamic: true},
+	"binlog_space_limit":              {kind: kindInt, min: 0},
+	"sync_binlog":                     {kind: kindInt, min: 0, max: 4294967295, dynamic: true},
+	"binlog_checksum":                 {kind: kindEnum, values: []string{"NONE", "CRC32"}, dynamic: true},
+	"binlog_encryption":               {kind: kindBool, only: "8.0", dynamic: true},
+	"encrypt_binlog":                  {kind: kindBool, only: "5.7"},
+	"master_verify_checksum":          {kind: kindBool, dynamic: true},
+	"gtid_mode":                       {kind: kindEnum, values: []string{"OFF", "OFF_PERMISSIVE", "ON_PERMISSIVE", "ON"}},
+	"enforce_gtid_consistency":        {kind: kindEnum, values: []string{"OFF", "ON", "WARN", "0", "1", "2"}},
+	"master_info_repository":          {kind: kindEnum, values: []string{"FILE", "TABLE"}, deprecated: "8.0"},
+	"relay_log_info_repository":       {kind: kindEnum, values: []string{"FILE", "TABLE"}, deprecated: "8.0"},
+	"relay_log":                       {kind: kindString},
+	"relay_log_recovery":              {kind: kindBool},
+	"slave_parallel_workers":          {kind: kindInt, min: 0, max: 1024, dynamic: true},
+	"slave_parallel_type":             {kind: kindEnum, values: []string{"DATABASE", "LOGICAL_CLOCK"}, dynamic: true},
+	"slave_preserve_commit_order":     {kind: kindBool, dynamic: true},
+	"slave_net_timeout":               {kind: kindInt, min: 1, dynamic: true},
+	"skip_slave_start":                {kind: kindBool},
+	"replicate_do_db":                 {kind: kindString},
+	"replicate_ignore_db":             {kind: kindString},
+	"replicate_wild_do_table":         {kind: kindString},
+	"replicate_wild_ignore_table":     {kind: kindString},
+
+	// innodb
+	"innodb_buffer_pool_size":             {kind: kindInt, min: 5242880, dynamic: true},
+	"innodb_buffer_pool_instances":        {kind: kindInt, min: 1, max: 64},
+	"innodb_buffer_pool_chunk_size":       {kind: kindInt, min: 1048576},
+	"innodb_buffer_pool_dump_at_shutdown": {kind: kindBool, dynamic: true},
+	"innodb_buffer_pool_load_at_startup":  {kind: kindBool},
+	"innodb_dedicated_server":             {kind: kindBool, only: "8.0"},
+	"innodb_log_file_size":                {kind: kindInt, min: 4194304},
+	"innodb_log_files_in_group":           {kind: kindInt, min: 2, max: 100},
+	"innodb_log_buffer_size":              {kind: kindInt, min: 262144},
+	"innodb_log_group_home_dir":           {kind: kindString},
+	"innodb_flush_log_at_trx_commit":      {kind: kindInt, min: 0, max: 2, dynamic: true},
+	"innodb_flush_log_at_timeout":         {kind: kindInt, min: 1, max: 2700, dynamic: true},
+	"innodb_flush_method":                 {kind: kindEnum, values: []string{"fsync", "O_DSYNC", "littlesync", "nosync", "O_DIRECT", "O_DIRECT_NO_FSYNC", "ALL_O_DIRECT"}},
+	"innodb_flush_neighbors":              {kind: kindInt, min: 0, max: 2, dynamic: true},
+	"innodb_io_capacity":                  {kind: kindInt, min: 100, dynamic: true},
+	"innodb_io_capacity_max":              {kind: kindInt, min: 100, dynamic: true},
+	"innodb_read_io_threads":              {kind: kindInt, min: 1, max: 64},
+	"innodb_write_io_threads":             {kind: kindInt, min: 1, max: 64},
+	"innodb_purge_threads":                {kind: kindInt, min: 1, max: 32},
+	"innodb_page_cleaners":                {kind: kindInt, min: 1, max: 64},
+	"innodb_thread_concurrency":           {kind: kindInt, min: 0, max: 1000, dynamic: true},
+	"innodb_lock_wait_timeout":            {kind: kindInt, min: 1, max: 1073741824, dynamic: true},
+	"innodb_rollback_on_timeout":          {kind: kindBool},
+	"innodb_file_per_table":               {kind: kindBool, dynamic: true},
+	"innodb_data_file_path":               {kind: kindString},
+	"innodb_data_home_dir":                {kind: kindString},
+	"innodb_temp_data_file_path":          {kind: kindString},
+	"innodb_undo_directory":               {kind: kindString},
+	"innodb_undo_tablespaces":             {kind: kindInt, min: 0, max: 127},
+	"innodb_undo_log_truncate":            {kind: kindBool, dynamic: true},
+	"innodb_max_undo_log_size":            {kind: kindInt, min: 10485760, dynamic: true},
+	"innodb_autoinc_lock_mode":            {kind: kindInt, min: 0, max: 2},
+	"innodb_doublewrite":                  {kind: kindBool},
+	"innodb_checksum_algorithm":           {kind: kindEnum, values: []string{"crc32", "strict_crc32", "innodb", "strict_innodb", "none", "strict_none"}, dynamic: true},
+	"innodb_stats_on_metadata":            {kind: kindBool, dynamic: true},
+	"innodb_stats_persistent":             {kind: kindBool, dynamic: true},
+	"innodb_adaptive_hash_index":          {kind: kindBool, dynamic: true},
+	"innodb_change_buffering":             {kind: kindEnum, values: []string{"none", "inserts", "deletes", "changes", "purges", "all"}, dynamic: true},
+	"innodb_old_blocks_time":              {kind: kindInt, min: 0, dynamic: true},
+	"innodb_open_files":                   {kind: kindInt, min: 10},
+	"innodb_sort_buffer_size":             {kind: kindInt, min: 65536, max: 67108864},
+	"innodb_online_alter_log_max_size":    {kind: kindInt, min: 65536, dynamic: true},
+	"innodb_print_all_deadlocks":          {kind: kindBool, dynamic: true},
+	"innodb_strict_mode":                  {kind: kindBool, dynamic: true},
+	"innodb_default_row_format":           {kind: kindEnum, values: []string{"REDUNDANT", "COMPACT", "DYNAMIC"}, dynamic: true},
+	"innodb_encrypt_tables":               {kind: kindEnum, values: []string{"ON", "OFF", "FORCE", "KEYRING_ON", "ONLINE_TO_KEYRING", "ONLINE_FROM_KEYRING_TO_UNENCRYPTED"}, dynamic: true},
+	"innodb_autoextend_increment":         {kind: kindInt, min: 1, max: 1000, dynamic: true},
+	"innodb_deadlock_detect":              {kind: kindBool, dynamic: true},
+	"innodb_numa_interleave":              {kind: kindBool},
+	"innodb_monitor_enable":               {kind: kindString, dynamic: true},
+	"innodb_file_format":                  {kind: kindString, only: "5.7", deprecated: "5.7", dynamic: true},
+	"innodb_file_format_check":            {kind: kindBool, only: "5.7", deprecated: "5.7"},
+	"innodb_file_format_max":              {kind: kindString, only: "5.7", deprecated: "5.7", dynamic: true},
+	"innodb_large_prefix":                 {kind: kindBool, only: "5.7", deprecated: "5.7", dynamic: true},
+	"innodb_support_xa":                   {kind: kindBool, only: "5.7", deprecated: "5.7", dynamic: true},
+	"innodb_undo_logs":                    {kind: kindInt, min: 0, max: 128, only: "5.7", deprecated: "5.7", hint: "use innodb_rollback_segments", dynamic: true},
+	"innodb_rollback_segments":            {kind: kindInt, min: 1, max: 128, dynamic: true},
+	"innodb_locks_unsafe_for_binlog":      {kind: kindBool, only: "5.7", deprecated: "5.7"},
+	"ignore_builtin_innodb":               {kind: kindBool, only: "5.7", deprecated: "5.7"},
+}
+
+// PXCMajorVersion returns the major version (5.7 or 8.0) of the PXC used by
+// the cluster. It's empty if the version can't be detected from the status
+// or the image tag.
+func PXCMajorVersion(cr *api.PerconaXtraDBCluster) string {
+	if v := versionMajor(cr.Status.PXC.Version); len(v) > 0 || cr.Spec.PXC == nil {
+		return v
+	}
+
+	return ImageMajorVersion(cr.Spec.PXC.Image)
+}
+
+// ImageMajorVersion returns the major version of PXC detected from the image tag
+func ImageMajorVersion(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return versionMajor(image[i+1:])
+	}
+
+	return ""
+}
+
+func versionMajor(v string) string {
+	for _, major := range []string{"5.7", "8.0"} {
+		if strings.HasPrefix(v, major) || strings.Contains(v, "pxc"+major) {
+			return major
+		}
+	}
+
+	return ""
+}
+
+// ValidateConfiguration checks the [mysqld] section of PXC.Configuration
+// against the catalog of the options known for the PXC version. The returned
+// error lists the known options with invalid values and the options that
+// would prevent mysqld from starting or would break the cluster. Unknown and
+// deprecated options are returned as warnings.
+func ValidateConfiguration(cr *api.PerconaXtraDBCluster) (warnings []string, err error) {
+	return ValidateConfigurationFor(cr, PXCMajorVersion(cr))
+}
+
+// ValidateConfigurationFor checks PXC.Configuration against the given major
+// version of PXC, e.g. the target version of the major upgrade
+func ValidateConfigurationFor(cr *api.PerconaXtraDBCluster, major string) (warnings []string, err error) {
+	if cr.Spec.PXC == nil || len(strings.TrimSpace(cr.Spec.PXC.Configuration)) == 0 {
+		return nil, nil
+	}
+
+	file, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(cr.Spec.PXC.Configuration))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse configuration")
+	}
+
+	var problems []string
+
+	if s, err := file.GetSection(ini.DEFAULT_SECTION); err == nil {
+		for _, name := range s.KeyStrings() {
+			if strings.HasPrefix(name, "!") {
+				continue
+			}
+			problems = append(problems, "option "+name+" is set outside of any section")
+		}
+	}
+
+	s, err := file.GetSection("mysqld")
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, key := range s.Keys() {
+		w, p := validateOption(key.Name(), key.Value(), major, cr.Spec.AllowUnsafeConfig)
+		if len(w) > 0 {
+			warnings = append(warnings, w)
+		}
+		if len(p) > 0 {
+			problems = append(problems, p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return warnings, errors.Errorf("invalid pxc configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return warnings, nil
+}
+
+// validateOption returns the warning and the problem found in the option
+func validateOption(name, value, major string, allowUnsafe bool) (warning, problem string) {
+	if strings.HasPrefix(name, "!") {
+		return "", ""
+	}
+
+	option := normalizeOption(name)
+	// mysqld ignores the unknown options with the loose prefix
+	loose := false
+	if strings.HasPrefix(option, "loose_") {
+		option = strings.TrimPrefix(option, "loose_")
+		loose = true
+	}
+
+	v, ok := mysqldVariables[option]
+	if !ok {
+		// --skip-foo and --disable-foo mean foo=OFF, --enable-foo means foo=ON
+		for _, prefix := range []string{"skip_", "disable_", "enable_"} {
+			if bv, found := mysqldVariables[strings.TrimPrefix(option, prefix)]; strings.HasPrefix(option, prefix) && found && bv.kind == kindBool {
+				return "", ""
+			}
+		}
+	}
+
+	if unsafeValues, found := unsafeVariables[option]; found && !allowUnsafe {
+		if len(unsafeValues) == 0 || containsFold(unsafeValues, value) {
+			return "", "option " + name + " is managed by the operator, set allowUnsafeConfigurations to override it"
+		}
+	}
+
+	// the catalog isn't complete, so unknown options are passed to mysqld as is
+	// unless they look like a typo of a known one
+	if !ok {
+		if loose || allowUnsafe || hasPluginPrefix(option) {
+			return "", ""
+		}
+		if known := closestOption(option); len(known) > 0 {
+			return "", "unknown option " + name + ", did you mean " + known +
+				"? Use the loose_ prefix or set allowUnsafeConfigurations to pass it to mysqld as is"
+		}
+		return "unknown option " + name + ", check it for typos", ""
+	}
+
+	if len(v.only) > 0 && len(major) > 0 && v.only != major {
+		if loose {
+			return "option " + name + " is ignored by PXC " + major, ""
+		}
+		return "", withHint("option "+name+" was removed in PXC "+major, v.hint)
+	}
+
+	if len(v.deprecated) > 0 && v.deprecated == major {
+		warning = withHint("option "+name+" is deprecated in PXC "+major, v.hint)
+	}
+
+	if msg := v.validate(value); len(msg) > 0 {
+		return warning, "option " + name + ": " + msg
+	}
+
+	if option == "sql_mode" && major == "8.0" {
+		if modes := IncompatibleSQLModes(value); len(modes) > 0 {
+			return warning, "option " + name + ": modes " + strings.Join(modes, ", ") + " were removed in PXC 8.0"
+		}
+	}
+
+	return warning, ""
+}
+
+// sqlModesRemovedIn80 are the sql_mode values mysqld 8.0 doesn't start with
+var sqlModesRemovedIn80 = []string{
+	"DB2", "MAXDB", "MSSQL", "MYSQL323", "MYSQL40", "NO_AUTO_CREATE_USER",
+	"NO_FIELD_OPTIONS", "NO_KEY_OPTIONS", "NO_TABLE_OPTIONS", "ORACLE", "POSTGRESQL",
+}
+
+// IncompatibleSQLModes returns the modes of the sql_mode value removed in PXC 8.0
+func IncompatibleSQLModes(sqlMode string) []string {
+	var modes []string
+	for _, mode := range strings.Split(strings.Trim(sqlMode, `"' `), ",") {
+		mode = strings.ToUpper(strings.TrimSpace(mode))
+		for _, removed := range sqlModesRemovedIn80 {
+			if mode == removed {
+				modes = append(modes, mode)
+			}
+		}
+	}
+	return modes
+}
+
+func (v mysqldVariable) validate(value string) string {
+	value = strings.TrimSpace(value)
+	// boolean keys without a value
+	if len(value) == 0 {
+		if v.kind == kindBool || v.kind == kindString {
+			return ""
+		}
+		return "value is required"
+	}
+
+	switch v.kind {
+	case kindBool:
+		if !containsFold([]string{"on", "off", "true", "false", "1", "0"}, value) {
+			return "invalid boolean value " + value
+		}
+	case kindEnum:
+		if !containsFold(v.values, value) {
+			return "invalid value " + value + ", must be one of " + strings.Join(v.values, ", ")
+		}
+	case kindInt:
+		n, err := parseSize(value)
+		if err != nil {
+			return "invalid integer value " + value
+		}
+		if n < v.min {
+			return "value " + value + " is less than " + strconv.FormatInt(v.min, 10)
+		}
+		if v.max > 0 && n > v.max {
+			return "value " + value + " is greater than " + strconv.FormatInt(v.max, 10)
+		}
+	case kindFloat:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "invalid numeric value " + value
+		}
+		if n < float64(v.min) {
+			return "value " + value + " is less than " + strconv.FormatInt(v.min, 10)
+		}
+		if v.max > 0 && n > float64(v.max) {
+			return "value " + value + " is greater than " + strconv.FormatInt(v.max, 10)
+		}
+	}
+
+	return ""
+}
+
+// parseSize parses the integer value with the optional K, M, G or T suffix
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		multiplier = 1 << 10
+	case 'm', 'M':
+		multiplier = 1 << 20
+	case 'g', 'G':
+		multiplier = 1 << 30
+	case 't', 'T':
+		multiplier = 1 << 40
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return n * multiplier, nil
+}
+
+// closestOption returns the catalog option the unknown option is a likely
+// typo of: within 1 edit for the short names and 2 edits for the longer ones.
+// It returns an empty string if there is no such option.
+func closestOption(option string) string {
+	maxDistance := 2
+	if len(option) <= 8 {
+		maxDistance = 1
+	}
+
+	closest := ""
+	for name := range mysqldVariables {
+		d := editDistance(option, name)
+		if d > maxDistance {
+			continue
+		}
+		if len(closest) == 0 || d < editDistance(option, closest) || d == editDistance(option, closest) && name < closest {
+			closest = name
+		}
+	}
+
+	return closest
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(v int, values ...int) int {
+	for _, x := range values {
+		if x < v {
+			v = x
+		}
+	}
+	return v
+}
+
+func hasPluginPrefix(option string) bool {
+	for _, prefix := range pluginPrefixes {
+		if strings.HasPrefix(option, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func withHint(msg, hint string) string {
+	if len(hint) > 0 {
+		return msg + ", " + hint
+	}
+	return msg
+}
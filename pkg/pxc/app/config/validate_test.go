@@ -0,0 +1,134 @@
+package config
+
+import (
+	"testing"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestValidateConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		image       string
+		allowUnsafe bool
+		valid       bool
+		warnings    int
+	}{
+		"valid": {
+			conf:  "[mysqld]\nlog-bin=binlog\nlog-slave-updates\nmax_allowed_packet=789M\nwsrep_provider_options=\"gcache.size=1G\"\n[sst]\nxbstream-opts=--decompress",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+			valid: true,
+		},
+		"typo": {
+			conf:  "[mysqld]\ninnodb_bufer_pool_size=1G",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"typo with allowUnsafeConfigurations": {
+			conf:        "[mysqld]\ninnodb_bufer_pool_size=1G",
+			image:       "percona/percona-xtradb-cluster:8.0.21-12.1",
+			allowUnsafe: true,
+			valid:       true,
+		},
+		"unknown option": {
+			conf:     "[mysqld]\noptimizer_prune_level=0",
+			image:    "percona/percona-xtradb-cluster:8.0.21-12.1",
+			valid:    true,
+			warnings: 1,
+		},
+		"options missing in the catalog": {
+			conf:  "[mysqld]\nskip-log-bin\ninnodb_flush_log_at_timeout=2\nmax_binlog_cache_size=1G\nbinlog_space_limit=10G\nlog_error_suppression_list=MY-013360\nlong_query_time=0.5",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+			valid: true,
+		},
+		"invalid long_query_time": {
+			conf:  "[mysqld]\nlong_query_time=abc",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"loose typo": {
+			conf:  "[mysqld]\nloose_innodb_bufer_pool_size=1G",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+			valid: true,
+		},
+		"invalid value": {
+			conf:  "[mysqld]\ninnodb_flush_log_at_trx_commit=5",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"invalid boolean": {
+			conf:  "[mysqld]\nwsrep_log_conflicts=maybe",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"removed option": {
+			conf:  "[mysqld]\nquery_cache_size=0",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"deprecated option": {
+			conf:     "[mysqld]\nquery_cache_size=0",
+			image:    "percona/percona-xtradb-cluster:5.7.31-31.45",
+			valid:    true,
+			warnings: 1,
+		},
+		"wsrep_provider": {
+			conf:  "[mysqld]\nwsrep_provider=/usr/lib64/libgalera.so",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"wsrep_provider with allowUnsafeConfigurations": {
+			conf:        "[mysqld]\nwsrep_provider=/usr/lib64/libgalera.so",
+			image:       "percona/percona-xtradb-cluster:8.0.21-12.1",
+			allowUnsafe: true,
+			valid:       true,
+		},
+		"sql_mode removed in 8.0": {
+			conf:  "[mysqld]\nsql_mode=\"STRICT_TRANS_TABLES,NO_AUTO_CREATE_USER\"",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+		"sql_mode on 5.7": {
+			conf:  "[mysqld]\nsql_mode=\"STRICT_TRANS_TABLES,NO_AUTO_CREATE_USER\"",
+			image: "percona/percona-xtradb-cluster:5.7.31-31.45",
+			valid: true,
+		},
+		"option outside of section": {
+			conf:  "max_connections=100\n[mysqld]\nwsrep_debug=1",
+			image: "percona/percona-xtradb-cluster:8.0.21-12.1",
+		},
+	}
+
+	for name, test := range tests {
+		cr := &api.PerconaXtraDBCluster{
+			Spec: api.PerconaXtraDBClusterSpec{
+				AllowUnsafeConfig: test.allowUnsafe,
+				PXC: &api.PXCSpec{
+					PodSpec: &api.PodSpec{
+						Image:         test.image,
+						Configuration: test.conf,
+					},
+				},
+			},
+		}
+
+		warnings, err := ValidateConfiguration(cr)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if len(warnings) != test.warnings {
+			t.Errorf("%s: got warnings %v, expected %d", name, warnings, test.warnings)
+		}
+	}
+}
+
+func TestClosestOption(t *testing.T) {
+	tests := map[string]string{
+		"innodb_bufer_pool_size": "innodb_buffer_pool_size",
+		"max_conections":         "max_connections",
+		"optimizer_prune_level":  "",
+		"foo":                    "",
+	}
+
+	for option, expected := range tests {
+		if got := closestOption(option); got != expected {
+			t.Errorf("%s: got %q, expected %q", option, got, expected)
+		}
+	}
+}
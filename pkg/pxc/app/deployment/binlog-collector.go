@@ -62,7 +62,7 @@ func GetBinlogCollectorDeployment(cr *api.PerconaXtraDBCluster) (appsv1.Deployme
 		{
 			Name: "PXC_PASS",
 			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: app.SecretKeySelector(cr.Spec.SecretsName, pxcUser),
+				SecretKeyRef: app.SecretKeySelector(cr.Spec.UsersSecretName(cr.Name), pxcUser),
 			},
 		},
 		{
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+const (
+	MetricsPortName = "metrics"
+
+	MysqldExporterPort   = 9104
+	ProxySQLExporterPort = 42004
+	// HAProxyMetricsPort is the port of the built-in HAProxy prometheus exporter
+	HAProxyMetricsPort = 8404
+)
+
+// MetricsExporter returns a prometheus exporter container which connects
+// to the database with the given dsn. The dsn can reference the env variables
+// of the container, e.g. the user password taken from the secret.
+func MetricsExporter(spec *api.MetricsSpec, image string, port int32, dsn string, envs []corev1.EnvVar) (corev1.Container, error) {
+	res, err := CreateResources(spec.Resources)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+
+	container := corev1.Container{
+		Name:            "metrics-exporter",
+		Image:           image,
+		ImagePullPolicy: spec.ImagePullPolicy,
+		Env: append(envs, corev1.EnvVar{
+			Name:  "DATA_SOURCE_NAME",
+			Value: dsn,
+		}),
+		Args: append([]string{"--web.listen-address=:" + strconv.Itoa(int(port))}, spec.Args...),
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: port,
+				Name:          MetricsPortName,
+			},
+		},
+		LivenessProbe: &corev1.Probe{
+			InitialDelaySeconds: 30,
+			TimeoutSeconds:      5,
+			PeriodSeconds:       10,
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Port: intstr.FromInt(int(port)),
+					Path: "/metrics",
+				},
+			},
+		},
+		Resources:       res,
+		SecurityContext: spec.ContainerSecurityContext,
+	}
+
+	return container, nil
+}
@@ -14,8 +14,33 @@ import (
 const (
 	haproxyName           = "haproxy"
 	haproxyDataVolumeName = "haproxydata"
+	// haproxyManagedVolumeName is the config map with the backends
+	// rendered by the operator
+	haproxyManagedVolumeName = "haproxy-managed"
 )
 
+// haproxyReloadScript copies the backends rendered by the operator to the
+// haproxy-auto volume and reloads HAProxy over the master socket, the same
+// way pxc-monit does it. The config map volume is updated by kubelet, so
+// the files are compared on each iteration.
+const haproxyReloadScript = `src=/etc/haproxy-managed
+dst=/etc/haproxy/pxc
+last=""
+while true; do
+    sum=$(cat "$src"/* 2>/dev/null | md5sum)
+    if [ "$sum" != "$last" ] && ls "$src"/* >/dev/null 2>&1; then
+        for f in "$src"/*; do
+            cp "$f" "$dst/.$(basename "$f").tmp" && mv "$dst/.$(basename "$f").tmp" "$dst/$(basename "$f")"
+        done
+        if [ -S "$dst/haproxy-main.sock" ]; then
+            echo reload | socat stdio "$dst/haproxy-main.sock"
+        fi
+        last="$sum"
+    fi
+    sleep 5
+done
+`
+
 type HAProxy struct {
 	sfs     *appsv1.StatefulSet
 	labels  map[string]string
@@ -138,6 +163,34 @@ func (c *HAProxy) AppContainer(spec *api.PodSpec, secrets string, cr *api.Percon
 		}, "/usr/local/bin/readiness-check.sh")
 	}
 
+	if cr.HAProxyMetricsEnabled() {
+		appc.Ports = append(
+			appc.Ports,
+			corev1.ContainerPort{
+				ContainerPort: app.HAProxyMetricsPort,
+				Name:          app.MetricsPortName,
+			},
+		)
+	}
+
+	if cr.HAProxyBackendsManaged() {
+		for _, l := range spec.Backends.Listeners {
+			appc.Ports = append(appc.Ports, corev1.ContainerPort{
+				ContainerPort: l.Port,
+				Name:          l.Name,
+			})
+		}
+		// the replicas backend of the zone is chosen by the node
+		if spec.ZoneAwareReads {
+			appc.Env = append(appc.Env, corev1.EnvVar{
+				Name: "NODE_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+				},
+			})
+		}
+	}
+
 	hasKey, err := cr.ConfigHasKey("mysqld", "proxy_protocol_networks")
 	if err != nil {
 		return appc, errors.Wrap(err, "check if congfig has proxy_protocol_networks key")
@@ -158,12 +211,36 @@ func (c *HAProxy) AppContainer(spec *api.PodSpec, secrets string, cr *api.Percon
 	return appc, nil
 }
 
+// SidecarContainers returns the peer-list container that renders the backends
+// on the PXC pods change. If the operator renders the backends, the
+// haproxy-reload container applies them instead.
 func (c *HAProxy) SidecarContainers(spec *api.PodSpec, secrets string, cr *api.PerconaXtraDBCluster) ([]corev1.Container, error) {
 	res, err := app.CreateResources(spec.SidecarResources)
 	if err != nil {
 		return nil, fmt.Errorf("create sidecar resources error: %v", err)
 	}
 
+	if cr.HAProxyBackendsManaged() {
+		return []corev1.Container{{
+			Name:            "haproxy-reload",
+			Image:           spec.Image,
+			ImagePullPolicy: spec.ImagePullPolicy,
+			Command:         []string{"/bin/bash", "-c", haproxyReloadScript},
+			Resources:       res,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      haproxyManagedVolumeName,
+					MountPath: "/etc/haproxy-managed",
+				},
+				{
+					Name:      "haproxy-auto",
+					MountPath: "/etc/haproxy/pxc",
+				},
+			},
+			SecurityContext: spec.ContainerSecurityContext,
+		}}, nil
+	}
+
 	container := corev1.Container{
 		Name:            "pxc-monit",
 		Image:           spec.Image,
@@ -228,6 +305,12 @@ func (c *HAProxy) PMMContainer(spec *api.PMMSpec, secrets string, cr *api.Percon
 	return nil, nil
 }
 
+// MetricsContainer returns nil as HAProxy metrics are exposed by the
+// built-in exporter of the haproxy container (see HAProxyMetricsEnabled)
+func (c *HAProxy) MetricsContainer(spec *api.MetricsSpec, secrets string, cr *api.PerconaXtraDBCluster) (*corev1.Container, error) {
+	return nil, nil
+}
+
 func (c *HAProxy) Volumes(podSpec *api.PodSpec, cr *api.PerconaXtraDBCluster) (*api.Volume, error) {
 	vol := app.Volumes(podSpec, haproxyDataVolumeName)
 	vol.Volumes = append(
@@ -235,6 +318,10 @@ func (c *HAProxy) Volumes(podSpec *api.PodSpec, cr *api.PerconaXtraDBCluster) (*
 		app.GetConfigVolumes("haproxy-custom", c.labels["app.kubernetes.io/instance"]+"-haproxy"),
 		app.GetTmpVolume("haproxy-auto"),
 	)
+	// the backends are copied to haproxy-auto by the haproxy-reload container
+	if cr.HAProxyBackendsManaged() {
+		vol.Volumes = append(vol.Volumes, app.GetConfigVolumes(haproxyManagedVolumeName, c.labels["app.kubernetes.io/instance"]+"-haproxy-auto"))
+	}
 	if cr.CompareVersionWith("1.7.0") >= 0 {
 		vol.Volumes = append(vol.Volumes, app.GetSecretVolumes("mysql-users-secret-file", "internal-"+cr.Name, false))
 	}
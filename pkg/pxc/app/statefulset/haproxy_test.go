@@ -0,0 +1,69 @@
+package statefulset
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestHAProxyManagedBackendsReload(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "pxc"},
+		Spec: api.PerconaXtraDBClusterSpec{
+			CRVersion: "1.8.0",
+			PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Size: 3}},
+			HAProxy:   &api.PodSpec{Enabled: true, Backends: &api.HAProxyBackendsSpec{}},
+		},
+	}
+	haproxy := NewHAProxy(cr)
+
+	sidecars, err := haproxy.SidecarContainers(cr.Spec.HAProxy, "internal-cluster1", cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecars) != 1 || sidecars[0].Name != "haproxy-reload" {
+		t.Fatalf("expected the haproxy-reload sidecar, got %+v", sidecars)
+	}
+	mounts := map[string]string{}
+	for _, m := range sidecars[0].VolumeMounts {
+		mounts[m.Name] = m.MountPath
+	}
+	if mounts[haproxyManagedVolumeName] != "/etc/haproxy-managed" || mounts["haproxy-auto"] != "/etc/haproxy/pxc" {
+		t.Errorf("unexpected mounts of haproxy-reload: %v", mounts)
+	}
+
+	vol, err := haproxy.Volumes(cr.Spec.HAProxy, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, v := range vol.Volumes {
+		switch v.Name {
+		case "haproxy-auto":
+			found++
+			// HAProxy creates the master socket there
+			if v.EmptyDir == nil {
+				t.Error("haproxy-auto should be writable")
+			}
+		case haproxyManagedVolumeName:
+			found++
+			if v.ConfigMap == nil || v.ConfigMap.Name != "cluster1-haproxy-auto" {
+				t.Errorf("unexpected %s volume: %+v", haproxyManagedVolumeName, v)
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("expected haproxy-auto and %s volumes, got %+v", haproxyManagedVolumeName, vol.Volumes)
+	}
+
+	cr.Spec.HAProxy.Backends = nil
+	sidecars, err = haproxy.SidecarContainers(cr.Spec.HAProxy, "internal-cluster1", cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecars) != 1 || sidecars[0].Name != "pxc-monit" {
+		t.Errorf("expected pxc-monit without the managed backends, got %+v", sidecars)
+	}
+}
@@ -0,0 +1,100 @@
+package statefulset
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+)
+
+func newMetricsCR() *api.PerconaXtraDBCluster {
+	return &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "pxc"},
+		Spec: api.PerconaXtraDBClusterSpec{
+			CRVersion: "1.8.0",
+			PXC:       &api.PXCSpec{PodSpec: &api.PodSpec{Size: 3}},
+			HAProxy:   &api.PodSpec{Enabled: true},
+			Metrics: &api.MetricsSpec{
+				Enabled:       true,
+				Image:         "prom/mysqld-exporter:v0.12.1",
+				ProxySQLImage: "percona/proxysql_exporter:1.1.2",
+				Args:          []string{"--collect.info_schema.processlist"},
+			},
+		},
+	}
+}
+
+func containerPort(c corev1.Container, name string) int32 {
+	for _, p := range c.Ports {
+		if p.Name == name {
+			return p.ContainerPort
+		}
+	}
+	return 0
+}
+
+func TestMetricsContainer(t *testing.T) {
+	cr := newMetricsCR()
+
+	cases := []struct {
+		app   api.StatefulApp
+		image string
+		port  int32
+		dsn   string
+	}{
+		{NewNode(cr), "prom/mysqld-exporter:v0.12.1", app.MysqldExporterPort, "monitor:$(MONITOR_PASSWORD)@(127.0.0.1:33062)/"},
+		{NewProxy(cr), "percona/proxysql_exporter:1.1.2", app.ProxySQLExporterPort, "proxyadmin:$(PROXY_ADMIN_PASSWORD)@tcp(127.0.0.1:6032)/"},
+	}
+
+	for _, c := range cases {
+		ct, err := c.app.MetricsContainer(cr.Spec.Metrics, "internal-cluster1", cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct.Image != c.image {
+			t.Errorf("expected image %s, got %s", c.image, ct.Image)
+		}
+		if port := containerPort(*ct, app.MetricsPortName); port != c.port {
+			t.Errorf("%s: expected metrics port %d, got %d", c.image, c.port, port)
+		}
+		if ct.LivenessProbe.HTTPGet.Port.IntValue() != int(c.port) {
+			t.Errorf("%s: liveness probe checks port %s", c.image, ct.LivenessProbe.HTTPGet.Port.String())
+		}
+		if ct.Args[len(ct.Args)-1] != "--collect.info_schema.processlist" {
+			t.Errorf("%s: extra args aren't passed: %v", c.image, ct.Args)
+		}
+		dsn := ""
+		for _, e := range ct.Env {
+			if e.Name == "DATA_SOURCE_NAME" {
+				dsn = e.Value
+			}
+		}
+		if dsn != c.dsn {
+			t.Errorf("expected dsn %s, got %s", c.dsn, dsn)
+		}
+	}
+}
+
+func TestHAProxyMetricsPort(t *testing.T) {
+	cr := newMetricsCR()
+
+	c, err := NewHAProxy(cr).AppContainer(cr.Spec.HAProxy, "internal-cluster1", cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := containerPort(c, app.MetricsPortName); port != 0 {
+		t.Errorf("metrics port %d is exposed without the exporter frontend", port)
+	}
+
+	cr.Spec.HAProxy.Backends = &api.HAProxyBackendsSpec{}
+	c, err = NewHAProxy(cr).AppContainer(cr.Spec.HAProxy, "internal-cluster1", cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := containerPort(c, app.MetricsPortName); port != app.HAProxyMetricsPort {
+		t.Errorf("expected metrics port %d, got %d", app.HAProxyMetricsPort, port)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	app "github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/recovery"
 )
 
 const (
@@ -219,6 +220,17 @@ func (c *Node) AppContainer(spec *api.PodSpec, secrets string, cr *api.PerconaXt
 		appc.LivenessProbe.Exec.Command = []string{"/var/lib/mysql/liveness-check.sh"}
 	}
 
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		// the agent rejects the bootstrap requests until the secret is created
+		tokenRef := app.SecretKeySelector(recovery.TokenSecretName(cr.Name), recovery.TokenKey)
+		optional := true
+		tokenRef.Optional = &optional
+		appc.Env = append(appc.Env, corev1.EnvVar{
+			Name:      recovery.TokenEnv,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: tokenRef},
+		})
+	}
+
 	res, err := app.CreateResources(spec.Resources)
 	if err != nil {
 		return appc, fmt.Errorf("create resources error: %v", err)
@@ -412,6 +424,29 @@ func (c *Node) PMMContainer(spec *api.PMMSpec, secrets string, cr *api.PerconaXt
 	return &ct, nil
 }
 
+func (c *Node) MetricsContainer(spec *api.MetricsSpec, secrets string, cr *api.PerconaXtraDBCluster) (*corev1.Container, error) {
+	envs := []corev1.EnvVar{
+		{
+			Name: "MONITOR_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: app.SecretKeySelector(secrets, "monitor"),
+			},
+		},
+	}
+	port := "3306"
+	if cr.CompareVersionWith("1.6.0") >= 0 {
+		port = "33062"
+	}
+	dsn := "monitor:$(MONITOR_PASSWORD)@(127.0.0.1:" + port + ")/"
+
+	ct, err := app.MetricsExporter(spec, spec.Image, app.MysqldExporterPort, dsn, envs)
+	if err != nil {
+		return nil, fmt.Errorf("create metrics exporter: %v", err)
+	}
+
+	return &ct, nil
+}
+
 func (c *Node) Volumes(podSpec *api.PodSpec, cr *api.PerconaXtraDBCluster) (*api.Volume, error) {
 	vol := app.Volumes(podSpec, DataVolumeName)
 	ls := c.Labels()
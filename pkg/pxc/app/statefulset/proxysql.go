@@ -133,6 +133,11 @@ func (c *Proxy) AppContainer(spec *api.PodSpec, secrets string, cr *api.PerconaX
 }
 
 func (c *Proxy) SidecarContainers(spec *api.PodSpec, secrets string, cr *api.PerconaXtraDBCluster) ([]corev1.Container, error) {
+	// the servers and the users are configured by the operator
+	if cr.ProxySQLManaged() {
+		return nil, nil
+	}
+
 	res, err := app.CreateResources(spec.SidecarResources)
 	if err != nil {
 		return nil, fmt.Errorf("create sidecar resources error: %v", err)
@@ -323,6 +328,26 @@ func (c *Proxy) PMMContainer(spec *api.PMMSpec, secrets string, cr *api.PerconaX
 	return &ct, nil
 }
 
+func (c *Proxy) MetricsContainer(spec *api.MetricsSpec, secrets string, cr *api.PerconaXtraDBCluster) (*corev1.Container, error) {
+	// ProxySQL stats are available only via the admin interface
+	envs := []corev1.EnvVar{
+		{
+			Name: "PROXY_ADMIN_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: app.SecretKeySelector(secrets, "proxyadmin"),
+			},
+		},
+	}
+	dsn := "proxyadmin:$(PROXY_ADMIN_PASSWORD)@tcp(127.0.0.1:6032)/"
+
+	ct, err := app.MetricsExporter(spec, spec.ProxySQLImage, app.ProxySQLExporterPort, dsn, envs)
+	if err != nil {
+		return nil, fmt.Errorf("create metrics exporter: %v", err)
+	}
+
+	return &ct, nil
+}
+
 func (c *Proxy) Volumes(podSpec *api.PodSpec, cr *api.PerconaXtraDBCluster) (*api.Volume, error) {
 	vol := app.Volumes(podSpec, proxyDataVolumeName)
 	ls := c.Labels()
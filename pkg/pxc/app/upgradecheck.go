@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+// UpgradeCheckJobName returns the name of the pre-flight job of the major upgrade
+func UpgradeCheckJobName(cr *api.PerconaXtraDBCluster) string {
+	return cr.Name + "-upgrade-check"
+}
+
+// UpgradeCheckJob runs the MySQL Shell upgrade checker against the first PXC
+// pod. The job fails if the checker finds any error, i.e. a blocker of the
+// upgrade to the target version.
+func UpgradeCheckJob(cr *api.PerconaXtraDBCluster, image, target string) *batchv1.Job {
+	secrets := cr.Spec.SecretsName
+	port := 3306
+	if cr.CompareVersionWith("1.6.0") >= 0 {
+		secrets = "internal-" + cr.Name
+		port = 33062
+	}
+	host := fmt.Sprintf("%s-pxc-0.%s-pxc.%s", cr.Name, cr.Name, cr.Namespace)
+
+	script := fmt.Sprintf(`mysqlsh --js --uri="root@%s:%d" --password="$ROOT_PASSWORD" `+
+		`-e 'util.checkForServerUpgrade({targetVersion: "%s", outputFormat: "JSON"})' > /tmp/check.json; `+
+		`cat /tmp/check.json; grep -q '"errorCount": 0' /tmp/check.json`, host, port, target)
+
+	var backoffLimit int32 = 2
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "percona-xtradb-cluster",
+		"app.kubernetes.io/instance":   cr.Name,
+		"app.kubernetes.io/component":  "upgrade-check",
+		"app.kubernetes.io/managed-by": "percona-xtradb-cluster-operator",
+	}
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      UpgradeCheckJobName(cr),
+			Namespace: cr.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: cr.Spec.PXC.ImagePullSecrets,
+					SecurityContext:  cr.Spec.PXC.PodSecurityContext,
+					Containers: []corev1.Container{
+						{
+							Name:            "upgrade-check",
+							Image:           image,
+							ImagePullPolicy: cr.Spec.PXC.ImagePullPolicy,
+							Command:         []string{"sh", "-c", script},
+							SecurityContext: cr.Spec.PXC.ContainerSecurityContext,
+							Env: []corev1.EnvVar{
+								{
+									Name: "ROOT_PASSWORD",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: SecretKeySelector(secrets, "root"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -81,7 +81,7 @@ func (bcp *Backup) JobSpec(spec api.PXCBackupSpec, cluster api.PerconaXtraDBClus
 							{
 								Name: "PXC_PASS",
 								ValueFrom: &corev1.EnvVarSource{
-									SecretKeyRef: app.SecretKeySelector(cluster.SecretsName, "xtrabackup"),
+									SecretKeyRef: app.SecretKeySelector(cluster.UsersSecretName(spec.PXCCluster), "xtrabackup"),
 								},
 							},
 						},
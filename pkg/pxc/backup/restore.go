@@ -311,7 +311,7 @@ func S3RestoreJob(cr *api.PerconaXtraDBClusterRestore, bcp *api.PerconaXtraDBClu
 		{
 			Name: "PXC_PASS",
 			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: app.SecretKeySelector(cluster.SecretsName, pxcUser),
+				SecretKeyRef: app.SecretKeySelector(cluster.UsersSecretName(cr.Spec.PXCCluster), pxcUser),
 			},
 		},
 	}
@@ -0,0 +1,79 @@
+package credentials
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+// Source provides passwords of the system users
+type Source interface {
+	// Users returns passwords of the system users by the user name
+	Users() (map[string][]byte, error)
+}
+
+type secretSource struct {
+	cl client.Client
+	nn types.NamespacedName
+}
+
+// NewSecret returns Source which reads the credentials from the k8s secret.
+// The k8s NotFound error is returned as is if the secret doesn't exist.
+func NewSecret(cl client.Client, namespace, name string) Source {
+	return secretSource{
+		cl: cl,
+		nn: types.NamespacedName{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+func (s secretSource) Users() (map[string][]byte, error) {
+	secretObj := corev1.Secret{}
+	err := s.cl.Get(context.TODO(), s.nn, &secretObj)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretObj.Data, nil
+}
+
+// Cache keeps the vault sources between reconcile loops,
+// so fetched credentials and tokens are reused until they expire
+type Cache struct {
+	store *sync.Map
+}
+
+func NewCache() Cache {
+	return Cache{
+		store: new(sync.Map),
+	}
+}
+
+// Vault returns the cached vault source for the cluster.
+// The source is recreated if the cluster's vault spec was changed.
+func (c Cache) Vault(cl client.Client, namespace, name string, spec api.VaultKVSpec) (*Vault, error) {
+	key := namespace + "/" + name
+	if v, ok := c.store.Load(key); ok && v.(*Vault).spec == spec {
+		return v.(*Vault), nil
+	}
+
+	v, err := NewVault(cl, namespace, spec)
+	if err != nil {
+		return nil, err
+	}
+	c.store.Store(key, v)
+
+	return v, nil
+}
+
+// Delete removes the cluster's source from the cache
+func (c Cache) Delete(namespace, name string) {
+	c.store.Delete(namespace + "/" + name)
+}
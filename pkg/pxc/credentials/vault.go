@@ -0,0 +1,249 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+const saTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Vault reads the system users credentials from the Vault KV secrets engine.
+// The fetched credentials are cached for spec.RefreshInterval seconds,
+// so the passwords rotated in Vault are picked up on the next refresh.
+type Vault struct {
+	cl        client.Client
+	namespace string
+	spec      api.VaultKVSpec
+	httpc     *http.Client
+
+	// saTokenPath can be changed in tests
+	saTokenPath string
+
+	mu        sync.Mutex
+	token     string
+	tokenExp  time.Time
+	data      map[string][]byte
+	fetchedAt time.Time
+}
+
+func NewVault(cl client.Client, namespace string, spec api.VaultKVSpec) (*Vault, error) {
+	v := &Vault{
+		cl:          cl,
+		namespace:   namespace,
+		spec:        spec,
+		httpc:       &http.Client{Timeout: 10 * time.Second},
+		saTokenPath: saTokenPath,
+	}
+
+	if len(spec.CASecretName) > 0 {
+		ca, err := v.secretKey(spec.CASecretName, "ca.crt")
+		if err != nil {
+			return nil, errors.Wrap(err, "get vault CA")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificates found in secret %s", spec.CASecretName)
+		}
+		v.httpc.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return v, nil
+}
+
+// Users returns the cached credentials or fetches them from Vault if the cache is expired
+func (v *Vault) Users() (map[string][]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	refresh := time.Duration(v.spec.RefreshInterval) * time.Second
+	if v.data != nil && time.Since(v.fetchedAt) < refresh {
+		return v.data, nil
+	}
+
+	data, err := v.read()
+	if err == errForbidden {
+		// the token could be revoked, try to get a new one
+		v.token = ""
+		data, err = v.read()
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s/%s from vault", v.spec.Mount, v.spec.Path)
+	}
+
+	v.data = data
+	v.fetchedAt = time.Now()
+
+	return v.data, nil
+}
+
+var errForbidden = errors.New("permission denied")
+
+func (v *Vault) read() (map[string][]byte, error) {
+	token, err := v.getToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "get token")
+	}
+
+	path := v.spec.Mount + "/" + strings.TrimPrefix(v.spec.Path, "/")
+	if v.spec.KVVersion == 2 {
+		path = v.spec.Mount + "/data/" + strings.TrimPrefix(v.spec.Path, "/")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, v.url(path), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	body, err := v.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]string
+	if v.spec.KVVersion == 2 {
+		resp := struct {
+			Data struct {
+				Data map[string]string `json:"data"`
+			} `json:"data"`
+		}{}
+		err = json.Unmarshal(body, &resp)
+		data = resp.Data.Data
+	} else {
+		resp := struct {
+			Data map[string]string `json:"data"`
+		}{}
+		err = json.Unmarshal(body, &resp)
+		data = resp.Data
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal response")
+	}
+
+	users := make(map[string][]byte, len(data))
+	for user, pass := range data {
+		users[user] = []byte(pass)
+	}
+
+	return users, nil
+}
+
+func (v *Vault) getToken() (string, error) {
+	if len(v.token) > 0 && (v.tokenExp.IsZero() || time.Now().Before(v.tokenExp)) {
+		return v.token, nil
+	}
+
+	if len(v.spec.TokenSecretName) > 0 {
+		token, err := v.secretKey(v.spec.TokenSecretName, "token")
+		if err != nil {
+			return "", err
+		}
+		v.token = strings.TrimSpace(string(token))
+		v.tokenExp = time.Time{}
+		return v.token, nil
+	}
+
+	jwt, err := ioutil.ReadFile(v.saTokenPath)
+	if err != nil {
+		return "", errors.Wrap(err, "read service account token")
+	}
+
+	login, err := json.Marshal(map[string]string{
+		"role": v.spec.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "marshal login request")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, v.url("auth/"+v.spec.AuthPath+"/login"), bytes.NewReader(login))
+	if err != nil {
+		return "", errors.Wrap(err, "create login request")
+	}
+
+	body, err := v.do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "kubernetes auth login")
+	}
+
+	resp := struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return "", errors.Wrap(err, "unmarshal login response")
+	}
+	if len(resp.Auth.ClientToken) == 0 {
+		return "", errors.New("empty client token in login response")
+	}
+
+	v.token = resp.Auth.ClientToken
+	v.tokenExp = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		// renew the token a bit earlier than it expires
+		v.tokenExp = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 9 / 10)
+	}
+
+	return v.token, nil
+}
+
+func (v *Vault) do(req *http.Request) ([]byte, error) {
+	resp, err := v.httpc.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "do request")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response body")
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, errForbidden
+	case resp.StatusCode != http.StatusOK:
+		return nil, errors.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+func (v *Vault) url(path string) string {
+	return strings.TrimSuffix(v.spec.Address, "/") + "/v1/" + path
+}
+
+func (v *Vault) secretKey(name, key string) ([]byte, error) {
+	secretObj := corev1.Secret{}
+	err := v.cl.Get(context.TODO(), types.NamespacedName{Namespace: v.namespace, Name: name}, &secretObj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get secret %s", name)
+	}
+
+	val, ok := secretObj.Data[key]
+	if !ok {
+		return nil, errors.Errorf("no %s key in secret %s", key, name)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,82 @@
+package credentials
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+)
+
+func TestVaultUsers(t *testing.T) {
+	tests := map[string]struct {
+		kvVersion int
+		path      string
+		body      string
+	}{
+		"kv v1": {
+			kvVersion: 1,
+			path:      "/v1/secret/pxc/cluster1",
+			body:      `{"data":{"root":"rootpass","operator":"operatorpass"}}`,
+		},
+		"kv v2": {
+			kvVersion: 2,
+			path:      "/v1/secret/data/pxc/cluster1",
+			body:      `{"data":{"data":{"root":"rootpass","operator":"operatorpass"},"metadata":{"version":3}}}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("X-Vault-Token") != "s.token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				if r.URL.Path != test.path {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, test.body)
+			}))
+			defer srv.Close()
+
+			cl := fake.NewFakeClient(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "ns"},
+				Data:       map[string][]byte{"token": []byte("s.token\n")},
+			})
+
+			v, err := NewVault(cl, "ns", api.VaultKVSpec{
+				Address:         srv.URL,
+				Mount:           "secret",
+				Path:            "pxc/cluster1",
+				KVVersion:       test.kvVersion,
+				TokenSecretName: "vault-token",
+				RefreshInterval: 300,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 2; i++ {
+				users, err := v.Users()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(users["root"]) != "rootpass" || string(users["operator"]) != "operatorpass" {
+					t.Errorf("unexpected users: %v", users)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("expected cached credentials to be used, got %d requests", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package queries
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ProxySQLServer is the row of the ProxySQL mysql_servers table
+type ProxySQLServer struct {
+	Hostgroup      int
+	Hostname       string
+	Port           int
+	Weight         int
+	MaxConnections int
+	UseSSL         bool
+}
+
+// GaleraHostgroups is the row of the ProxySQL mysql_galera_hostgroups table
+type GaleraHostgroups struct {
+	Writer                int
+	BackupWriter          int
+	Reader                int
+	Offline               int
+	MaxWriters            int
+	WriterIsAlsoReader    int
+	MaxTransactionsBehind int
+}
+
+// ProxySQLQueryRule is the row of the ProxySQL mysql_query_rules table
+type ProxySQLQueryRule struct {
+	RuleID               int
+	Active               bool
+	Username             string
+	SchemaName           string
+	FlagIn               int
+	MatchDigest          string
+	MatchPattern         string
+	NegateMatchPattern   bool
+	FlagOut              int
+	DestinationHostgroup int
+	Apply                bool
+	Comment              string
+}
+
+// ProxySQLUser is the row of the ProxySQL mysql_users table
+type ProxySQLUser struct {
+	Username         string
+	Password         string
+	DefaultHostgroup int
+}
+
+// PrimaryHostIn returns the host of the writer hostgroup
+func (p *Database) PrimaryHostIn(hostgroup int) (string, error) {
+	var host string
+	err := p.db.QueryRow("SELECT hostname FROM runtime_mysql_servers WHERE hostgroup_id = ?", hostgroup).Scan(&host)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	return host, nil
+}
+
+// NativePasswordUsers returns the PXC users that can be authenticated by ProxySQL.
+// The users in exclude and the internal users of MySQL are skipped. ProxySQL
+// doesn't know the hosts, so if the accounts of the user on different hosts
+// have different passwords, the same one is picked on every call.
+func (p *Database) NativePasswordUsers(exclude []string) ([]ProxySQLUser, error) {
+	query := "SELECT User, MAX(authentication_string) FROM mysql.user " +
+		"WHERE plugin = 'mysql_native_password' AND authentication_string != '' AND User NOT LIKE 'mysql.%'"
+	args := make([]interface{}, 0, len(exclude))
+	if len(exclude) > 0 {
+		query += " AND User NOT IN (?" + strings.Repeat(", ?", len(exclude)-1) + ")"
+		for _, u := range exclude {
+			args = append(args, u)
+		}
+	}
+
+	rows, err := p.db.Query(query+" GROUP BY User ORDER BY User", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []ProxySQLUser
+	for rows.Next() {
+		var u ProxySQLUser
+		err := rows.Scan(&u.Username, &u.Password)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// ProxySQLServers returns the configured mysql_servers
+func (p *Database) ProxySQLServers() ([]ProxySQLServer, error) {
+	rows, err := p.db.Query("SELECT hostgroup_id, hostname, port, weight, max_connections, use_ssl FROM mysql_servers ORDER BY hostgroup_id, hostname")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []ProxySQLServer
+	for rows.Next() {
+		var s ProxySQLServer
+		err := rows.Scan(&s.Hostgroup, &s.Hostname, &s.Port, &s.Weight, &s.MaxConnections, &s.UseSSL)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, s)
+	}
+
+	return servers, rows.Err()
+}
+
+// SetProxySQLServers replaces mysql_servers and applies them
+func (p *Database) SetProxySQLServers(servers []ProxySQLServer) error {
+	current, err := p.ProxySQLServers()
+	if err != nil {
+		return fmt.Errorf("get servers: %v", err)
+	}
+	if equalRows(current, servers) {
+		return nil
+	}
+
+	stmts := []stmt{{query: "DELETE FROM mysql_servers"}}
+	for _, s := range servers {
+		stmts = append(stmts, stmt{
+			query: "INSERT INTO mysql_servers (hostgroup_id, hostname, port, weight, max_connections, use_ssl) VALUES (?, ?, ?, ?, ?, ?)",
+			args:  []interface{}{s.Hostgroup, s.Hostname, s.Port, s.Weight, s.MaxConnections, boolInt(s.UseSSL)},
+		})
+	}
+
+	return p.apply("MYSQL SERVERS", stmts)
+}
+
+// GaleraHostgroups returns the configured mysql_galera_hostgroups
+func (p *Database) GaleraHostgroups() ([]GaleraHostgroups, error) {
+	rows, err := p.db.Query("SELECT writer_hostgroup, backup_writer_hostgroup, reader_hostgroup, offline_hostgroup, " +
+		"max_writers, writer_is_also_reader, max_transactions_behind FROM mysql_galera_hostgroups")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hostgroups []GaleraHostgroups
+	for rows.Next() {
+		var h GaleraHostgroups
+		err := rows.Scan(&h.Writer, &h.BackupWriter, &h.Reader, &h.Offline, &h.MaxWriters, &h.WriterIsAlsoReader, &h.MaxTransactionsBehind)
+		if err != nil {
+			return nil, err
+		}
+		hostgroups = append(hostgroups, h)
+	}
+
+	return hostgroups, rows.Err()
+}
+
+// SetGaleraHostgroups replaces mysql_galera_hostgroups and applies them.
+// The hostgroups are part of the servers configuration in ProxySQL.
+func (p *Database) SetGaleraHostgroups(h GaleraHostgroups) error {
+	current, err := p.GaleraHostgroups()
+	if err != nil {
+		return fmt.Errorf("get galera hostgroups: %v", err)
+	}
+	if equalRows(current, []GaleraHostgroups{h}) {
+		return nil
+	}
+
+	return p.apply("MYSQL SERVERS", []stmt{
+		{query: "DELETE FROM mysql_galera_hostgroups"},
+		{
+			query: "INSERT INTO mysql_galera_hostgroups (writer_hostgroup, backup_writer_hostgroup, reader_hostgroup, " +
+				"offline_hostgroup, active, max_writers, writer_is_also_reader, max_transactions_behind) VALUES (?, ?, ?, ?, 1, ?, ?, ?)",
+			args: []interface{}{h.Writer, h.BackupWriter, h.Reader, h.Offline, h.MaxWriters, h.WriterIsAlsoReader, h.MaxTransactionsBehind},
+		},
+	})
+}
+
+// ProxySQLQueryRules returns the configured mysql_query_rules
+func (p *Database) ProxySQLQueryRules() ([]ProxySQLQueryRule, error) {
+	rows, err := p.db.Query("SELECT rule_id, active, IFNULL(username, ''), IFNULL(schemaname, ''), flagIN, " +
+		"IFNULL(match_digest, ''), IFNULL(match_pattern, ''), negate_match_pattern, IFNULL(flagOUT, 0), " +
+		"IFNULL(destination_hostgroup, 0), apply, IFNULL(comment, '') FROM mysql_query_rules ORDER BY rule_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []ProxySQLQueryRule
+	for rows.Next() {
+		var r ProxySQLQueryRule
+		err := rows.Scan(&r.RuleID, &r.Active, &r.Username, &r.SchemaName, &r.FlagIn, &r.MatchDigest, &r.MatchPattern,
+			&r.NegateMatchPattern, &r.FlagOut, &r.DestinationHostgroup, &r.Apply, &r.Comment)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// SetProxySQLQueryRules replaces mysql_query_rules and applies them
+func (p *Database) SetProxySQLQueryRules(rules []ProxySQLQueryRule) error {
+	current, err := p.ProxySQLQueryRules()
+	if err != nil {
+		return fmt.Errorf("get query rules: %v", err)
+	}
+	if equalRows(current, rules) {
+		return nil
+	}
+
+	stmts := []stmt{{query: "DELETE FROM mysql_query_rules"}}
+	for _, r := range rules {
+		stmts = append(stmts, stmt{
+			query: "INSERT INTO mysql_query_rules (rule_id, active, username, schemaname, flagIN, match_digest, match_pattern, " +
+				"negate_match_pattern, flagOUT, destination_hostgroup, apply, comment) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			args: []interface{}{r.RuleID, boolInt(r.Active), nullString(r.Username), nullString(r.SchemaName), r.FlagIn,
+				nullString(r.MatchDigest), nullString(r.MatchPattern), boolInt(r.NegateMatchPattern), nullInt(r.FlagOut),
+				nullInt(r.DestinationHostgroup), boolInt(r.Apply), nullString(r.Comment)},
+		})
+	}
+
+	return p.apply("MYSQL QUERY RULES", stmts)
+}
+
+// QueryRuleHits returns the number of the queries matched by each runtime query rule
+func (p *Database) QueryRuleHits() (map[int]int64, error) {
+	rows, err := p.db.Query("SELECT rule_id, hits FROM stats_mysql_query_rules")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := make(map[int]int64)
+	for rows.Next() {
+		var id int
+		var n int64
+		err := rows.Scan(&id, &n)
+		if err != nil {
+			return nil, err
+		}
+		hits[id] = n
+	}
+
+	return hits, rows.Err()
+}
+
+// ProxySQLUsers returns the configured mysql_users
+func (p *Database) ProxySQLUsers() ([]ProxySQLUser, error) {
+	rows, err := p.db.Query("SELECT username, password, default_hostgroup FROM mysql_users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []ProxySQLUser
+	for rows.Next() {
+		var u ProxySQLUser
+		err := rows.Scan(&u.Username, &u.Password, &u.DefaultHostgroup)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// SetProxySQLUsers replaces mysql_users and applies them
+func (p *Database) SetProxySQLUsers(users []ProxySQLUser) error {
+	current, err := p.ProxySQLUsers()
+	if err != nil {
+		return fmt.Errorf("get users: %v", err)
+	}
+	if equalRows(current, users) {
+		return nil
+	}
+
+	stmts := []stmt{{query: "DELETE FROM mysql_users"}}
+	for _, u := range users {
+		stmts = append(stmts, stmt{
+			query: "INSERT INTO mysql_users (username, password, default_hostgroup) VALUES (?, ?, ?)",
+			args:  []interface{}{u.Username, u.Password, u.DefaultHostgroup},
+		})
+	}
+
+	return p.apply("MYSQL USERS", stmts)
+}
+
+type stmt struct {
+	query string
+	args  []interface{}
+}
+
+// apply runs the statements against the ProxySQL admin tables and
+// loads the module configuration to runtime and saves it to disk
+func (p *Database) apply(module string, stmts []stmt) error {
+	for _, s := range stmts {
+		_, err := p.db.Exec(s.query, s.args...)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s.query, err)
+		}
+	}
+
+	_, err := p.db.Exec("LOAD " + module + " TO RUNTIME")
+	if err != nil {
+		return fmt.Errorf("load %s to runtime: %v", strings.ToLower(module), err)
+	}
+	_, err = p.db.Exec("SAVE " + module + " TO DISK")
+	if err != nil {
+		return fmt.Errorf("save %s to disk: %v", strings.ToLower(module), err)
+	}
+
+	return nil
+}
+
+func equalRows(current, desired interface{}) bool {
+	cv, dv := reflect.ValueOf(current), reflect.ValueOf(desired)
+	if cv.Len() == 0 && dv.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(current, desired)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func nullInt(i int) interface{} {
+	if i == 0 {
+		return nil
+	}
+	return i
+}
+
+func nullString(s string) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
@@ -1,15 +1,15 @@
 package queries
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/credentials"
 )
 
 // value of writer group is hardcoded in ProxySQL config inside docker image
@@ -22,21 +22,32 @@ type Database struct {
 
 var ErrNotFound = errors.New("not found")
 
-func New(client client.Client, namespace, secretName, user, host string, port int32) (Database, error) {
-	secretObj := corev1.Secret{}
-	err := client.Get(context.TODO(),
-		types.NamespacedName{
-			Namespace: namespace,
-			Name:      secretName,
-		},
-		&secretObj,
-	)
+// Option changes the parameters of the connection
+type Option func(params url.Values)
+
+// WithTimeouts limits the time of the connection dial and of the reads,
+// so an unresponsive pod doesn't block the caller. It's meant for the short
+// status queries, the operator statements can run much longer.
+func WithTimeouts(dial, read time.Duration) Option {
+	return func(params url.Values) {
+		params.Set("timeout", dial.String())
+		params.Set("readTimeout", read.String())
+	}
+}
+
+func New(src credentials.Source, user, host string, port int32, opts ...Option) (Database, error) {
+	users, err := src.Users()
 	if err != nil {
 		return Database{}, err
 	}
 
-	pass := string(secretObj.Data[user])
-	connStr := fmt.Sprintf("%s:%s@tcp(%s:%d)/mysql?interpolateParams=true", user, pass, host, port)
+	params := url.Values{"interpolateParams": {"true"}}
+	for _, opt := range opts {
+		opt(params)
+	}
+
+	pass := string(users[user])
+	connStr := fmt.Sprintf("%s:%s@tcp(%s:%d)/mysql?%s", user, pass, host, port, params.Encode())
 	db, err := sql.Open("mysql", connStr)
 	if err != nil {
 		return Database{}, err
@@ -77,16 +88,7 @@ func (p *Database) Status(host, ip string) ([]string, error) {
 }
 
 func (p *Database) PrimaryHost() (string, error) {
-	var host string
-	err := p.db.QueryRow("SELECT hostname FROM runtime_mysql_servers WHERE hostgroup_id = ?", writerID).Scan(&host)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", ErrNotFound
-		}
-		return "", err
-	}
-
-	return host, nil
+	return p.PrimaryHostIn(writerID)
 }
 
 func (p *Database) Hostname() (string, error) {
@@ -117,6 +119,94 @@ func (p *Database) WsrepLocalStateComment() (string, error) {
 	return value, nil
 }
 
+// WsrepStatus returns all wsrep_% global status variables
+func (p *Database) WsrepStatus() (map[string]string, error) {
+	rows, err := p.db.Query("SHOW GLOBAL STATUS LIKE 'wsrep_%'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	status := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+
+		err := rows.Scan(&name, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		status[name] = value
+	}
+
+	return status, rows.Err()
+}
+
+// IntVariable returns the value of the integer global variable
+func (p *Database) IntVariable(name string) (int64, error) {
+	var value int64
+
+	err := p.db.QueryRow("SELECT @@GLOBAL." + name).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("variable was not found")
+		}
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// StringVariable returns the value of the string global variable
+func (p *Database) StringVariable(name string) (string, error) {
+	var value string
+
+	err := p.db.QueryRow("SELECT @@GLOBAL." + name).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("variable was not found")
+		}
+		return "", err
+	}
+
+	return value, nil
+}
+
+// SetGlobal changes the global variable at runtime
+func (p *Database) SetGlobal(name string, value interface{}) error {
+	_, err := p.db.Exec("SET GLOBAL "+name+" = ?", value)
+	return err
+}
+
+// Check runs the query and returns true if it returns 1 in the first column of the first row
+func (p *Database) Check(query string) (bool, error) {
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return false, err
+	}
+
+	return len(values) > 0 && string(values[0]) == "1", nil
+}
+
 func (p *Database) Version() (string, error) {
 	var version string
 
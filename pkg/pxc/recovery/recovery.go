@@ -0,0 +1,152 @@
+package recovery
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// AgentPort is the port the recovery agent listens on inside the pxc container
+	AgentPort = 33063
+	// StatePath is the HTTP path of the recovery state
+	StatePath = "/recovery"
+	// BootstrapPath is the HTTP path that starts the bootstrap of the node
+	BootstrapPath = "/recovery/bootstrap"
+
+	// TokenEnv is the env variable of the pxc container with the token
+	// required by BootstrapPath
+	TokenEnv = "RECOVERY_AGENT_TOKEN"
+	// TokenKey is the key of the token in the agent secret
+	TokenKey = "token"
+
+	// EmptyUUID is the cluster UUID of the node that doesn't know its position
+	EmptyUUID = "00000000-0000-0000-0000-000000000000"
+)
+
+// TokenSecretName returns the name of the secret with the agent token.
+// The token is used only by the agent, so the users credentials
+// aren't sent to the pods.
+func TokenSecretName(cluster string) string {
+	return cluster + "-recovery-agent"
+}
+
+// State is published by the pxc entrypoint when the node is waiting
+// for the full cluster crash recovery
+type State struct {
+	Node            string `json:"node"`
+	UUID            string `json:"uuid"`
+	Seqno           int64  `json:"seqno"`
+	SafeToBootstrap bool   `json:"safeToBootstrap"`
+
+	// NoAgent is set by the operator if the node waits for the recovery,
+	// but the agent isn't running there, e.g. the pod has the init
+	// container of the previous version. The state is read from the logs then.
+	NoAgent bool `json:"-"`
+}
+
+// ErrAgentNotRunning is returned if the agent doesn't accept connections.
+// It's started only when the node is waiting for the recovery.
+var ErrAgentNotRunning = errors.New("recovery agent isn't running")
+
+// Client connects to the recovery agent of the pod
+type Client struct {
+	http  *http.Client
+	url   string
+	token string
+}
+
+// NewClient returns the client of the agent on the host. If the ca isn't
+// empty, the agent is connected over TLS and its certificate is verified
+// for the serverName.
+func NewClient(host, serverName string, ca []byte, token string) (*Client, error) {
+	c := &Client{
+		http:  &http.Client{Timeout: 5 * time.Second},
+		url:   fmt.Sprintf("http://%s:%d", host, AgentPort),
+		token: token,
+	}
+	if len(ca) == 0 {
+		return c, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates in ca")
+	}
+	c.http.Transport = &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:    pool,
+			ServerName: serverName,
+		},
+	}
+	c.url = fmt.Sprintf("https://%s:%d", host, AgentPort)
+
+	return c, nil
+}
+
+// State returns the recovery state of the node.
+// It returns nil if the node isn't waiting for the recovery.
+func (c *Client) State() (*State, error) {
+	resp, err := c.http.Get(c.url + StatePath)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return nil, ErrAgentNotRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	state := &State{}
+	err = json.NewDecoder(resp.Body).Decode(state)
+	if err != nil {
+		return nil, fmt.Errorf("decode state: %v", err)
+	}
+
+	return state, nil
+}
+
+// Bootstrap asks the node to bootstrap a new cluster from its data
+func (c *Client) Bootstrap() error {
+	req, err := http.NewRequest(http.MethodPost, c.url+BootstrapPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response status: %s: %s", resp.Status, body)
+	}
+
+	return nil
+}
+
+// Authorized returns true if the request has the bearer token
+func Authorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if len(token) == 0 || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
@@ -0,0 +1,95 @@
+package recovery
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxctls"
+)
+
+func TestAuthorized(t *testing.T) {
+	cases := []struct {
+		header string
+		token  string
+		ok     bool
+	}{
+		{"Bearer s3cret", "s3cret", true},
+		{"Bearer wrong", "s3cret", false},
+		{"Basic b3BlcmF0b3I6czNjcmV0", "s3cret", false},
+		{"", "s3cret", false},
+		// the agent without the token rejects every request
+		{"Bearer ", "", false},
+	}
+
+	for _, c := range cases {
+		r, err := http.NewRequest(http.MethodPost, "http://10.0.0.1:33063"+BootstrapPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.header) > 0 {
+			r.Header.Set("Authorization", c.header)
+		}
+		if ok := Authorized(r, c.token); ok != c.ok {
+			t.Errorf("header %q: expected authorized=%v, got %v", c.header, c.ok, ok)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient("10.0.0.1", "cluster1-pxc-0.cluster1-pxc", nil, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(c.url, "http://10.0.0.1:") {
+		t.Errorf("expected plain HTTP without the ca, got %s", c.url)
+	}
+
+	if _, err := NewClient("10.0.0.1", "cluster1-pxc-0.cluster1-pxc", []byte("not a certificate"), "s3cret"); err == nil {
+		t.Error("expected error for the invalid ca")
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	ca, cert, key, err := pxctls.Issue([]string{"*.cluster1-pxc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == BootstrapPath && Authorized(r, "s3cret") {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	for name, tt := range map[string]struct {
+		serverName string
+		token      string
+		ok         bool
+	}{
+		"valid":             {"cluster1-pxc-0.cluster1-pxc", "s3cret", true},
+		"wrong token":       {"cluster1-pxc-0.cluster1-pxc", "wrong", false},
+		"wrong server name": {"cluster2-pxc-0.cluster2-pxc", "s3cret", false},
+	} {
+		c, err := NewClient("127.0.0.1", tt.serverName, ca, tt.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.url = srv.URL
+
+		if err := c.Bootstrap(); (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok=%v, got %v", name, tt.ok, err)
+		}
+	}
+}
@@ -278,9 +278,28 @@ func NewServiceHAProxy(cr *api.PerconaXtraDBCluster) *corev1.Service {
 		)
 	}
 
+	obj.Spec.Ports = append(obj.Spec.Ports, HAProxyListenerPorts(cr)...)
+
 	return obj
 }
 
+// HAProxyListenerPorts returns the service ports of the extra HAProxy listeners
+func HAProxyListenerPorts(cr *api.PerconaXtraDBCluster) []corev1.ServicePort {
+	if !cr.HAProxyBackendsManaged() {
+		return nil
+	}
+
+	ports := make([]corev1.ServicePort, 0, len(cr.Spec.HAProxy.Backends.Listeners))
+	for _, l := range cr.Spec.HAProxy.Backends.Listeners {
+		ports = append(ports, corev1.ServicePort{
+			Port:       l.Port,
+			TargetPort: intstr.FromInt(int(l.Port)),
+			Name:       l.Name,
+		})
+	}
+	return ports
+}
+
 func NewServiceHAProxyReplicas(cr *api.PerconaXtraDBCluster) *corev1.Service {
 	svcType := corev1.ServiceTypeClusterIP
 	if cr.Spec.HAProxy != nil && len(cr.Spec.HAProxy.ReplicasServiceType) > 0 {
@@ -338,3 +357,40 @@ func NewServiceHAProxyReplicas(cr *api.PerconaXtraDBCluster) *corev1.Service {
 
 	return obj
 }
+
+// NewServiceMetrics returns the headless service that exposes
+// the metrics exporter port of every pod of the component
+func NewServiceMetrics(cr *api.PerconaXtraDBCluster, component string, port int32) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cr.Name + "-" + component + "-metrics",
+			Namespace: cr.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "percona-xtradb-cluster",
+				"app.kubernetes.io/instance":   cr.Name,
+				"app.kubernetes.io/component":  component + "-metrics",
+				"app.kubernetes.io/managed-by": "percona-xtradb-cluster-operator",
+				"app.kubernetes.io/part-of":    "percona-xtradb-cluster",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Port:       port,
+					TargetPort: intstr.FromInt(int(port)),
+					Name:       "metrics",
+				},
+			},
+			ClusterIP: "None",
+			Selector: map[string]string{
+				"app.kubernetes.io/name":      "percona-xtradb-cluster",
+				"app.kubernetes.io/instance":  cr.Name,
+				"app.kubernetes.io/component": component,
+			},
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package pxc
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app"
+)
+
+func TestNewServiceMetrics(t *testing.T) {
+	cr := &api.PerconaXtraDBCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "pxc"},
+	}
+
+	svc := NewServiceMetrics(cr, "proxysql", app.ProxySQLExporterPort)
+
+	if svc.Name != "cluster1-proxysql-metrics" || svc.Namespace != "pxc" {
+		t.Errorf("unexpected service %s/%s", svc.Namespace, svc.Name)
+	}
+	if svc.Spec.ClusterIP != "None" {
+		t.Errorf("expected headless service, got cluster IP %q", svc.Spec.ClusterIP)
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Name != app.MetricsPortName ||
+		svc.Spec.Ports[0].Port != app.ProxySQLExporterPort || svc.Spec.Ports[0].TargetPort.IntValue() != app.ProxySQLExporterPort {
+		t.Errorf("unexpected ports %+v", svc.Spec.Ports)
+	}
+	if c := svc.Spec.Selector["app.kubernetes.io/component"]; c != "proxysql" {
+		t.Errorf("expected the proxysql pods to be selected, got %q", c)
+	}
+	// the ServiceMonitor selects the service by its labels, not the pods
+	if c := svc.Labels["app.kubernetes.io/component"]; c != "proxysql-metrics" {
+		t.Errorf("expected component label proxysql-metrics, got %q", c)
+	}
+}
@@ -37,6 +37,9 @@ func StatefulSet(sfs api.StatefulApp, podSpec *api.PodSpec, cr *api.PerconaXtraD
 		secrets = "internal-" + cr.Name
 	}
 	pod.Affinity = PodAffinity(podSpec.Affinity, sfs)
+	if cr.CompareVersionWith("1.8.0") >= 0 {
+		pod.TopologySpreadConstraints = TopologySpreadConstraints(podSpec.TopologySpreadConstraints, sfs)
+	}
 
 	if sfs.Labels()["app.kubernetes.io/component"] == "haproxy" && cr.CompareVersionWith("1.7.0") == -1 {
 		t := true
@@ -67,6 +70,16 @@ func StatefulSet(sfs api.StatefulApp, podSpec *api.PodSpec, cr *api.PerconaXtraD
 		}
 	}
 
+	if cr.Spec.Metrics != nil && cr.Spec.Metrics.Enabled {
+		metricsC, err := sfs.MetricsContainer(cr.Spec.Metrics, secrets, cr)
+		if err != nil {
+			return nil, errors.Wrap(err, "metrics container")
+		}
+		if metricsC != nil {
+			pod.Containers = append(pod.Containers, *metricsC)
+		}
+	}
+
 	if cr.Spec.LogCollector != nil && cr.Spec.LogCollector.Enabled && cr.CompareVersionWith("1.7.0") >= 0 {
 		logCollectorC, err := sfs.LogCollectorContainer(cr.Spec.LogCollector, cr.Spec.LogCollectorSecretName, secrets, cr)
 		if err != nil {
@@ -140,28 +153,67 @@ func PodAffinity(af *api.PodAffinity, app api.App) *corev1.Affinity {
 		return nil
 	}
 
-	switch {
-	case af.Advanced != nil:
+	if af.Advanced != nil {
 		return af.Advanced
-	case af.TopologyKey != nil:
-		if strings.ToLower(*af.TopologyKey) == api.AffinityTopologyKeyOff {
-			return nil
+	}
+
+	selector := &metav1.LabelSelector{
+		MatchLabels: app.Labels(),
+	}
+	anti := &corev1.PodAntiAffinity{}
+	if af.TopologyKey != nil && strings.ToLower(*af.TopologyKey) != api.AffinityTopologyKeyOff {
+		anti.RequiredDuringSchedulingIgnoredDuringExecution = append(anti.RequiredDuringSchedulingIgnoredDuringExecution,
+			corev1.PodAffinityTerm{
+				LabelSelector: selector,
+				TopologyKey:   *af.TopologyKey,
+			},
+		)
+	}
+
+	if zs := af.ZoneSpread; zs != nil {
+		term := corev1.PodAffinityTerm{
+			LabelSelector: selector,
+			TopologyKey:   zs.TopologyKey,
 		}
-		return &corev1.Affinity{
-			PodAntiAffinity: &corev1.PodAntiAffinity{
-				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
-					{
-						LabelSelector: &metav1.LabelSelector{
-							MatchLabels: app.Labels(),
-						},
-						TopologyKey: *af.TopologyKey,
-					},
+		if zs.Mode == api.ZoneSpreadRequired {
+			anti.RequiredDuringSchedulingIgnoredDuringExecution = append(anti.RequiredDuringSchedulingIgnoredDuringExecution, term)
+		} else {
+			anti.PreferredDuringSchedulingIgnoredDuringExecution = append(anti.PreferredDuringSchedulingIgnoredDuringExecution,
+				corev1.WeightedPodAffinityTerm{
+					Weight:          zs.Weight,
+					PodAffinityTerm: term,
 				},
-			},
+			)
 		}
 	}
 
-	return nil
+	if len(anti.RequiredDuringSchedulingIgnoredDuringExecution) == 0 && len(anti.PreferredDuringSchedulingIgnoredDuringExecution) == 0 {
+		return nil
+	}
+
+	return &corev1.Affinity{
+		PodAntiAffinity: anti,
+	}
+}
+
+// TopologySpreadConstraints returns the spread constraints of the pod.
+// The constraints without the label selector spread the pods of the app.
+func TopologySpreadConstraints(constraints []corev1.TopologySpreadConstraint, app api.App) []corev1.TopologySpreadConstraint {
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	tsc := make([]corev1.TopologySpreadConstraint, 0, len(constraints))
+	for _, c := range constraints {
+		c := *c.DeepCopy()
+		if c.LabelSelector == nil {
+			c.LabelSelector = &metav1.LabelSelector{
+				MatchLabels: app.Labels(),
+			}
+		}
+		tsc = append(tsc, c)
+	}
+	return tsc
 }
 
 func MergeTemplateAnnotations(sfs *appsv1.StatefulSet, annotations map[string]string) {
@@ -24,6 +24,7 @@ import (
 
 	v1 "github.com/percona/percona-xtradb-cluster-operator/pkg/apis/pxc/v1"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/k8s"
+	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxc/app/config"
 	"github.com/percona/percona-xtradb-cluster-operator/pkg/pxctls"
 )
 
@@ -270,7 +271,12 @@ func (h *hook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sendResponse(req.Request.UID, req.TypeMeta, w, cr.Validate())
+	err = cr.Validate()
+	if err == nil && cr.CompareVersionWith("1.8.0") >= 0 {
+		_, err = config.ValidateConfiguration(cr)
+	}
+
+	err = sendResponse(req.Request.UID, req.TypeMeta, w, err)
 	if err != nil {
 		log.Log.Error(err, "Can't send validation response")
 	}